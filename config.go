@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Fernando-Dourado/harness-move-project/operation"
+	"github.com/Fernando-Dourado/harness-move-project/services"
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// MoveConfig is the content of the --config file. Values like ${TOKEN} are
+	// replaced by the environment variables before parsing.
+	MoveConfig struct {
+		Source        EndpointConfig             `yaml:"source"`
+		Target        EndpointConfig             `yaml:"target"`
+		CreateOrg     bool                       `yaml:"createOrg"`
+		CreateProject bool                       `yaml:"createProject"`
+		Entities      []services.EntityType      `yaml:"entities"`
+		Filters       services.EntityFilter      `yaml:"filters"`
+		Mapping       services.IdentifierMapping `yaml:"mapping"`
+		MappingFile   string                     `yaml:"mappingFile"`
+		Concurrency   int                        `yaml:"concurrency"`
+		Report        string                     `yaml:"report"`
+		OnConflict    string                     `yaml:"onConflict"`
+		Verify        bool                       `yaml:"verify"`
+		Principals    services.PrincipalMapping  `yaml:"principals"`
+		TokenFile     string                     `yaml:"tokenFile"`
+		ProjectAdmins []string                   `yaml:"projectAdmins"`
+		State         string                     `yaml:"state"`
+		// Targets copies the source to each "org/project", instead of the
+		// target org and project
+		Targets []string `yaml:"targets"`
+	}
+
+	EndpointConfig struct {
+		Url     string `yaml:"url"`
+		Token   string `yaml:"token"`
+		Account string `yaml:"account"`
+		Org     string `yaml:"org"`
+		Project string `yaml:"project"`
+	}
+)
+
+func loadConfig(path string) (*MoveConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &MoveConfig{}
+	dec := yaml.NewDecoder(bytes.NewReader([]byte(os.ExpandEnv(string(b)))))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// newConfig reads the config file, when provided, and overrides its values
+// with the flags set in the command line
+func newConfig(c *cli.Context) (*MoveConfig, error) {
+	cfg := &MoveConfig{}
+	if path := c.GlobalString("config"); len(path) > 0 {
+		var err error
+		if cfg, err = loadConfig(path); err != nil {
+			return nil, err
+		}
+	}
+
+	override := func(name string, value *string) {
+		if c.GlobalIsSet(name) {
+			*value = c.GlobalString(name)
+		}
+	}
+	override("api-token", &cfg.Source.Token)
+	override("account", &cfg.Source.Account)
+	override("vanity-url-source", &cfg.Source.Url)
+	override("source-org", &cfg.Source.Org)
+	override("source-project", &cfg.Source.Project)
+	override("target-token", &cfg.Target.Token)
+	override("target-account", &cfg.Target.Account)
+	override("vanity-url-target", &cfg.Target.Url)
+	override("target-org", &cfg.Target.Org)
+	override("target-project", &cfg.Target.Project)
+	override("mapping-file", &cfg.MappingFile)
+	override("report", &cfg.Report)
+	override("on-conflict", &cfg.OnConflict)
+	override("token-file", &cfg.TokenFile)
+	override("state", &cfg.State)
+
+	if c.GlobalIsSet("create-org") {
+		cfg.CreateOrg = c.GlobalBool("create-org")
+	}
+	if c.GlobalIsSet("create-project") {
+		cfg.CreateProject = c.GlobalBool("create-project")
+	}
+	if c.GlobalIsSet("verify") {
+		cfg.Verify = c.GlobalBool("verify")
+	}
+	if c.GlobalIsSet("concurrency") {
+		cfg.Concurrency = c.GlobalInt("concurrency")
+	}
+	if c.GlobalIsSet("project-admins") {
+		cfg.ProjectAdmins = strings.Split(c.GlobalString("project-admins"), ",")
+	}
+	if c.GlobalIsSet("targets") {
+		cfg.Targets = strings.Split(c.GlobalString("targets"), ",")
+	}
+	if c.GlobalIsSet("entities") {
+		cfg.Entities = nil
+		for _, name := range strings.Split(c.GlobalString("entities"), ",") {
+			t, err := services.ParseEntityType(name)
+			if err != nil {
+				return nil, err
+			}
+			cfg.Entities = append(cfg.Entities, t)
+		}
+	}
+	return cfg, nil
+}
+
+// operationConfig validates the config and loads the mapping file, whose
+// rules win over the inline mapping
+func (cfg *MoveConfig) operationConfig() (operation.OperationConfig, error) {
+	for _, t := range cfg.Entities {
+		if _, err := services.ParseEntityType(string(t)); err != nil {
+			return operation.OperationConfig{}, err
+		}
+	}
+	if err := cfg.Filters.Validate(); err != nil {
+		return operation.OperationConfig{}, err
+	}
+	if err := cfg.Mapping.Validate(); err != nil {
+		return operation.OperationConfig{}, err
+	}
+	for _, admin := range cfg.ProjectAdmins {
+		if _, err := services.ParseProjectAdmin(admin); err != nil {
+			return operation.OperationConfig{}, err
+		}
+	}
+	onConflict, err := services.ParseConflictPolicy(cfg.OnConflict)
+	if err != nil {
+		return operation.OperationConfig{}, err
+	}
+
+	mapping := cfg.Mapping
+	if len(cfg.MappingFile) > 0 {
+		fileMapping, err := services.LoadIdentifierMapping(cfg.MappingFile)
+		if err != nil {
+			return operation.OperationConfig{}, err
+		}
+		if mapping == nil {
+			mapping = services.IdentifierMapping{}
+		}
+		for t, rule := range fileMapping {
+			mapping[t] = rule
+		}
+	}
+
+	return operation.OperationConfig{
+		CreateOrg:     cfg.CreateOrg,
+		CreateProject: cfg.CreateProject,
+		Mapping:       mapping,
+		Entities:      cfg.Entities,
+		Filter:        cfg.Filters,
+		Concurrency:   cfg.Concurrency,
+		ReportFile:    cfg.Report,
+		OnConflict:    onConflict,
+		Verify:        cfg.Verify,
+		Principals:    cfg.Principals,
+		TokenFile:     cfg.TokenFile,
+		ProjectAdmins: cfg.ProjectAdmins,
+		StateFile:     cfg.State,
+	}, nil
+}
+
+func (cfg *MoveConfig) newMove() (*operation.Move, error) {
+	opConfig, err := cfg.operationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	mv := operation.NewMove(
+		operation.CopyConfig{
+			Org:     cfg.Source.Org,
+			Project: cfg.Source.Project,
+			Token:   cfg.Source.Token,
+			Account: cfg.Source.Account,
+			Url:     cfg.Source.Url,
+		},
+		operation.CopyConfig{
+			Org:     cfg.Target.Org,
+			Project: cfg.Target.Project,
+			Token:   cfg.Target.Token,
+			Account: cfg.Target.Account,
+			Url:     cfg.Target.Url,
+		},
+		opConfig,
+	)
+
+	applyArgumentRules(mv)
+
+	if err := validateMove(mv); err != nil {
+		return nil, err
+	}
+	return mv, nil
+}
+
+// newSync updates the existing target entities, unless another conflict
+// policy is set
+func (cfg *MoveConfig) newSync(interval time.Duration, runs int, deleteRemoved bool) (*operation.Sync, error) {
+	if len(cfg.OnConflict) == 0 {
+		cfg.OnConflict = string(services.ConflictUpdate)
+	}
+	if deleteRemoved && len(cfg.State) == 0 {
+		return nil, errors.New("--delete needs the --state file")
+	}
+	if interval <= 0 {
+		return nil, errors.New("the sync interval must be positive")
+	}
+	mv, err := cfg.newMove()
+	if err != nil {
+		return nil, err
+	}
+	mv.Config.Delete = deleteRemoved
+
+	s := operation.NewSync(mv, interval)
+	s.Runs = runs
+	return s, nil
+}
+
+// newBatch uses the accounts and settings of the config for every row of the
+// manifest. The org and project of the config are ignored.
+func (cfg *MoveConfig) newBatch(manifest string, parallel int) (*operation.Batch, error) {
+	rows, err := operation.LoadBatchManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.batchOf(rows, parallel)
+}
+
+// newFanOut copies the source project of the config to every target, one
+// after the other. The source is read once.
+func (cfg *MoveConfig) newFanOut() (*operation.Batch, error) {
+	if len(cfg.Source.Org) == 0 || len(cfg.Source.Project) == 0 {
+		return nil, errors.New("required values not set: source-org, source-project")
+	}
+	rows, err := operation.FanOutRows(cfg.Source.Org+"/"+cfg.Source.Project, cfg.Targets, cfg.CreateProject)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.batchOf(rows, 1)
+}
+
+func (cfg *MoveConfig) batchOf(rows []operation.BatchRow, parallel int) (*operation.Batch, error) {
+	opConfig, err := cfg.operationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// THE RULES FILL THE TARGET ACCOUNT AND URLS FROM THE SOURCE
+	mv := operation.NewMove(
+		operation.CopyConfig{
+			Token:   cfg.Source.Token,
+			Account: cfg.Source.Account,
+			Url:     cfg.Source.Url,
+		},
+		operation.CopyConfig{
+			Token:   cfg.Target.Token,
+			Account: cfg.Target.Account,
+			Url:     cfg.Target.Url,
+		},
+		opConfig,
+	)
+	applyArgumentRules(mv)
+
+	var missing []string
+	if len(mv.Source.Token) == 0 {
+		missing = append(missing, "api-token")
+	}
+	if len(mv.Source.Account) == 0 {
+		missing = append(missing, "account")
+	}
+	if len(missing) > 0 {
+		return nil, errors.New("required values not set: " + strings.Join(missing, ", "))
+	}
+
+	return &operation.Batch{
+		Source:     mv.Source,
+		Target:     mv.Target,
+		Config:     opConfig,
+		Rows:       rows,
+		Parallel:   parallel,
+		ReportFile: cfg.Report,
+	}, nil
+}
+
+// validateMove checks the values required from the flags or the config file
+func validateMove(mv *operation.Move) error {
+	var missing []string
+	if len(mv.Source.Token) == 0 {
+		missing = append(missing, "api-token")
+	}
+	if len(mv.Source.Account) == 0 {
+		missing = append(missing, "account")
+	}
+	if len(mv.Source.Org) == 0 {
+		missing = append(missing, "source-org")
+	}
+	if len(mv.Source.Project) == 0 {
+		missing = append(missing, "source-project")
+	}
+	if len(mv.Target.Org) == 0 {
+		missing = append(missing, "target-org")
+	}
+	if len(missing) > 0 {
+		return errors.New("required values not set: " + strings.Join(missing, ", "))
+	}
+	return nil
+}
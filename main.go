@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/Fernando-Dourado/harness-move-project/operation"
 	"github.com/Fernando-Dourado/harness-move-project/services"
@@ -20,10 +21,15 @@ func main() {
 	app.UsageText = "harness-move-project [options]"
 	app.Action = run
 	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:     "config",
+			Usage:    "YAML file with the move configuration. The flags override its values.",
+			Required: false,
+		},
 		cli.StringFlag{
 			Name:     "api-token",
 			Usage:    "API authentication token for accessing the source system.",
-			Required: true,
+			Required: false,
 		},
 		cli.StringFlag{
 			Name:     "vanity-url-source",
@@ -38,22 +44,22 @@ func main() {
 		cli.StringFlag{
 			Name:     "account",
 			Usage:    "The account identifier associated with the source system.",
-			Required: true,
+			Required: false,
 		},
 		cli.StringFlag{
 			Name:     "source-org",
 			Usage:    "The organization identifier in the source account.",
-			Required: true,
+			Required: false,
 		},
 		cli.StringFlag{
 			Name:     "source-project",
 			Usage:    "The project identifier in the source account.",
-			Required: true,
+			Required: false,
 		},
 		cli.StringFlag{
 			Name:     "target-org",
 			Usage:    "The org identifier in the target account.",
-			Required: true,
+			Required: false,
 		},
 		cli.StringFlag{
 			Name:     "target-project",
@@ -70,39 +76,209 @@ func main() {
 			Usage:    "The account identifier associated with the target system. Not needed if target and source accounts are the same.",
 			Required: false,
 		},
+		cli.BoolFlag{
+			Name:     "create-org",
+			Usage:    "Creates the org in the target account if missing, copying the source org.",
+			Required: false,
+		},
 		cli.BoolFlag{
 			Name:     "create-project",
 			Usage:    "Creates the project in the target account/org if missing.",
 			Required: false,
 		},
+		cli.StringFlag{
+			Name:     "project-admins",
+			Usage:    "Comma separated list of user:<email>, usergroup:<id> or serviceaccount:<id> made admins of the created project.",
+			Required: false,
+		},
+		cli.StringFlag{
+			Name:     "mapping-file",
+			Usage:    "YAML file with the identifier rename rules by entity type.",
+			Required: false,
+		},
+		cli.StringFlag{
+			Name:     "entities",
+			Usage:    "Comma separated list of the entity types to copy. All when not set.",
+			Required: false,
+		},
+		cli.IntFlag{
+			Name:     "concurrency",
+			Usage:    "Number of independent entity types copied at the same time.",
+			Required: false,
+		},
+		cli.StringFlag{
+			Name:     "report",
+			Usage:    "File to write the JSON report of the copied entities.",
+			Required: false,
+		},
+		cli.StringFlag{
+			Name:     "on-conflict",
+			Usage:    "What to do when an entity exists in the target: skip, update or fail. (default: skip)",
+			Required: false,
+		},
+		cli.BoolFlag{
+			Name:     "verify",
+			Usage:    "Checks the target against the source after the move.",
+			Required: false,
+		},
+		cli.StringFlag{
+			Name:     "token-file",
+			Usage:    "Mints new tokens for the copied service account API keys and writes them to this file.",
+			Required: false,
+		},
+		cli.StringFlag{
+			Name:     "targets",
+			Usage:    "Comma separated list of org/project targets receiving the source project, read once. Replaces the target org and project.",
+			Required: false,
+		},
+		cli.StringFlag{
+			Name:     "state",
+			Usage:    "File keeping the content hash of the synced entities. The unchanged ones are skipped by the next runs.",
+			Required: false,
+		},
+	}
+	app.Commands = []cli.Command{
+		{
+			Name:      "verify",
+			Usage:     "Checks the target against the source without copying anything.",
+			UsageText: "harness-move-project [options] verify",
+			Action:    runVerify,
+		},
+		{
+			Name:      "batch",
+			Usage:     "Moves every project pair listed in a CSV or YAML manifest.",
+			UsageText: "harness-move-project [options] batch --manifest <file> [--parallel <n>]",
+			Action:    runBatch,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:     "manifest",
+					Usage:    "CSV or YAML file with the source and target org/project of each row.",
+					Required: true,
+				},
+				cli.IntFlag{
+					Name:     "parallel",
+					Usage:    "Number of rows moved at the same time.",
+					Value:    1,
+					Required: false,
+				},
+			},
+		},
+		{
+			Name:      "export-terraform",
+			Usage:     "Writes the source project as Terraform configuration for the Harness provider, with import blocks for the target project.",
+			UsageText: "harness-move-project [options] export-terraform [--output <dir>]",
+			Action:    runExportTerraform,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:     "output",
+					Usage:    "Directory receiving the .tf files.",
+					Value:    "terraform",
+					Required: false,
+				},
+			},
+		},
+		{
+			Name:      "sync",
+			Usage:     "Keeps the target project current, copying the source changes on an interval.",
+			UsageText: "harness-move-project [options] sync [--interval <duration>] [--runs <n>] [--delete]",
+			Action:    runSync,
+			Flags: []cli.Flag{
+				cli.DurationFlag{
+					Name:     "interval",
+					Usage:    "Time between the start of two runs.",
+					Value:    5 * time.Minute,
+					Required: false,
+				},
+				cli.IntFlag{
+					Name:     "runs",
+					Usage:    "Stops after that many runs. Runs until stopped when not set.",
+					Required: false,
+				},
+				cli.BoolFlag{
+					Name:     "delete",
+					Usage:    "Deletes the synced CD entities whose source entity was deleted. Needs --state.",
+					Required: false,
+				},
+			},
+		},
 	}
 	app.Run(os.Args)
 }
 
 func run(c *cli.Context) {
-	mv := operation.NewMove(
-		operation.CopyConfig{
-			Org:     c.String("source-org"),
-			Project: c.String("source-project"),
-			Token:   c.String("api-token"),
-			Account: c.String("account"),
-			Url:     c.String("vanity-url-source"),
-		},
-		operation.CopyConfig{
-			Org:     c.String("target-org"),
-			Project: c.String("target-project"),
-			Token:   c.String("target-token"),
-			Account: c.String("target-account"),
-			Url:     c.String("vanity-url-target"),
-		},
-		operation.OperationConfig{
-			CreateProject: c.Bool("create-project"),
-		},
-	)
+	cfg, err := newConfig(c)
+	if err == nil && len(cfg.Targets) > 0 {
+		var b *operation.Batch
+		if b, err = cfg.newFanOut(); err == nil {
+			err = b.Exec()
+		}
+	} else if err == nil {
+		var mv *operation.Move
+		if mv, err = cfg.newMove(); err == nil {
+			err = mv.Exec()
+		}
+	}
+	if err != nil {
+		fmt.Println(color.RedString(fmt.Sprint("Failed: ", err.Error())))
+		os.Exit(1)
+	}
+}
+
+func runVerify(c *cli.Context) {
+	cfg, err := newConfig(c)
+	if err == nil {
+		var mv *operation.Move
+		if mv, err = cfg.newMove(); err == nil {
+			err = mv.Verify()
+		}
+	}
+	if err != nil {
+		fmt.Println(color.RedString(fmt.Sprint("Failed: ", err.Error())))
+		os.Exit(1)
+	}
+}
 
-	applyArgumentRules(mv)
+func runBatch(c *cli.Context) {
+	cfg, err := newConfig(c)
+	if err == nil {
+		var b *operation.Batch
+		if b, err = cfg.newBatch(c.String("manifest"), c.Int("parallel")); err == nil {
+			err = b.Exec()
+		}
+	}
+	if err != nil {
+		fmt.Println(color.RedString(fmt.Sprint("Failed: ", err.Error())))
+		os.Exit(1)
+	}
+}
+
+func runExportTerraform(c *cli.Context) {
+	cfg, err := newConfig(c)
+	if err == nil {
+		// THE TARGET ONLY NAMES THE RESOURCES, THE SOURCE ORG IS USED WHEN NOT SET
+		if len(cfg.Target.Org) == 0 {
+			cfg.Target.Org = cfg.Source.Org
+		}
+		var mv *operation.Move
+		if mv, err = cfg.newMove(); err == nil {
+			err = mv.ExportTerraform(c.String("output"))
+		}
+	}
+	if err != nil {
+		fmt.Println(color.RedString(fmt.Sprint("Failed: ", err.Error())))
+		os.Exit(1)
+	}
+}
 
-	if err := mv.Exec(); err != nil {
+func runSync(c *cli.Context) {
+	cfg, err := newConfig(c)
+	if err == nil {
+		var s *operation.Sync
+		if s, err = cfg.newSync(c.Duration("interval"), c.Int("runs"), c.Bool("delete")); err == nil {
+			err = s.Exec()
+		}
+	}
+	if err != nil {
 		fmt.Println(color.RedString(fmt.Sprint("Failed: ", err.Error())))
 		os.Exit(1)
 	}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/Fernando-Dourado/harness-move-project/operation"
+	"github.com/Fernando-Dourado/harness-move-project/services"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -46,3 +49,31 @@ func TestArgumentRule_EmptyTargetAccount(t *testing.T) {
 	assert.Equal(t, mv.Source.Project, mv.Target.Project)
 	assert.Equal(t, mv.Source.Token, mv.Target.Token)
 }
+
+func TestLoadConfig_EnvInterpolation(t *testing.T) {
+	t.Setenv("MOVE_TEST_TOKEN", "TokenA")
+	path := filepath.Join(t.TempDir(), "move.yaml")
+	content := "source:\n  token: ${MOVE_TEST_TOKEN}\n  account: AccountA\n  org: OrgA\n  project: ProjectA\ntarget:\n  org: OrgB\nentities: [pipeline, template]\nfilters:\n  pipeline:\n    include: [\"deploy_*\"]\nconcurrency: 2\n"
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	cfg, err := loadConfig(path)
+	assert.NoError(t, err)
+
+	mv, err := cfg.newMove()
+	assert.NoError(t, err)
+	assert.Equal(t, "TokenA", mv.Source.Token)
+	assert.Equal(t, "TokenA", mv.Target.Token)
+	assert.Equal(t, "ProjectA", mv.Target.Project)
+	assert.Equal(t, []services.EntityType{services.EntityPipeline, services.EntityTemplate}, mv.Config.Entities)
+	assert.True(t, mv.Config.Filter.Match(services.EntityPipeline, "deploy_api"))
+	assert.False(t, mv.Config.Filter.Match(services.EntityPipeline, "build_api"))
+	assert.Equal(t, 2, mv.Config.Concurrency)
+}
+
+func TestLoadConfig_UnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "move.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("sources:\n  org: OrgA\n"), 0644))
+
+	_, err := loadConfig(path)
+	assert.Error(t, err)
+}
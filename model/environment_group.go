@@ -0,0 +1,38 @@
+package model
+
+type ListEnvironmentGroupResponse struct {
+	Status        string                   `json:"status"`
+	Data          ListEnvironmentGroupData `json:"data"`
+	CorrelationID string                   `json:"correlationId"`
+}
+
+type ListEnvironmentGroupData struct {
+	TotalPages    int64                          `json:"totalPages"`
+	TotalItems    int64                          `json:"totalItems"`
+	PageItemCount int64                          `json:"pageItemCount"`
+	PageSize      int64                          `json:"pageSize"`
+	Content       []*ListEnvironmentGroupContent `json:"content"`
+	PageIndex     int64                          `json:"pageIndex"`
+	Empty         bool                           `json:"empty"`
+}
+
+type ListEnvironmentGroupContent struct {
+	EnvGroup *EnvironmentGroup `json:"envGroup"`
+}
+
+type EnvironmentGroup struct {
+	OrgIdentifier     string `json:"orgIdentifier"`
+	ProjectIdentifier string `json:"projectIdentifier"`
+	Identifier        string `json:"identifier"`
+	Name              string `json:"name"`
+	Color             string `json:"color"`
+	Yaml              string `json:"yaml"`
+}
+
+type CreateEnvironmentGroupRequest struct {
+	OrgIdentifier     string `json:"orgIdentifier"`
+	ProjectIdentifier string `json:"projectIdentifier"`
+	Identifier        string `json:"identifier"`
+	Color             string `json:"color,omitempty"`
+	Yaml              string `json:"yaml"`
+}
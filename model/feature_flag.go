@@ -0,0 +1,155 @@
+package model
+
+// FLAGS
+
+type ListFeatureFlagResponse struct {
+	Features  []*FeatureFlagListItem `json:"features"`
+	PageCount int64                  `json:"pageCount"`
+	ItemCount int64                  `json:"itemCount"`
+	PageSize  int64                  `json:"pageSize"`
+	PageIndex int64                  `json:"pageIndex"`
+}
+
+// FeatureFlagListItem is a flag with its state in the environment of the list
+type FeatureFlagListItem struct {
+	FeatureFlag
+	Prerequisites []*Prerequisite      `json:"prerequisites,omitempty"`
+	EnvProperties *FeatureFlagEnvState `json:"envProperties,omitempty"`
+}
+
+type FeatureFlag struct {
+	Identifier          string            `json:"identifier"`
+	Name                string            `json:"name"`
+	Description         string            `json:"description,omitempty"`
+	Kind                string            `json:"kind"`
+	Permanent           bool              `json:"permanent"`
+	Tags                []*FeatureFlagTag `json:"tags,omitempty"`
+	Variations          []*Variation      `json:"variations"`
+	DefaultOnVariation  string            `json:"defaultOnVariation"`
+	DefaultOffVariation string            `json:"defaultOffVariation"`
+}
+
+type CreateFeatureFlagRequest struct {
+	Project string `json:"project"`
+	*FeatureFlag
+}
+
+type FeatureFlagTag struct {
+	Identifier string `json:"identifier"`
+	Name       string `json:"name"`
+}
+
+type Variation struct {
+	Identifier  string `json:"identifier"`
+	Name        string `json:"name,omitempty"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+}
+
+type Prerequisite struct {
+	Feature    string   `json:"feature"`
+	Variations []string `json:"variations"`
+}
+
+type FeatureFlagEnvState struct {
+	Environment  string          `json:"environment"`
+	State        string          `json:"state"`
+	DefaultServe *Serve          `json:"defaultServe,omitempty"`
+	OffVariation string          `json:"offVariation"`
+	Rules        []*ServingRule  `json:"rules,omitempty"`
+	VariationMap []*VariationMap `json:"variationMap,omitempty"`
+}
+
+type Serve struct {
+	Variation    *string       `json:"variation,omitempty"`
+	Distribution *Distribution `json:"distribution,omitempty"`
+}
+
+type Distribution struct {
+	BucketBy   string               `json:"bucketBy"`
+	Variations []*WeightedVariation `json:"variations"`
+}
+
+type WeightedVariation struct {
+	Variation string `json:"variation"`
+	Weight    int    `json:"weight"`
+}
+
+type ServingRule struct {
+	Priority int       `json:"priority"`
+	Clauses  []*Clause `json:"clauses"`
+	Serve    *Serve    `json:"serve"`
+}
+
+type Clause struct {
+	Attribute string   `json:"attribute"`
+	Op        string   `json:"op"`
+	Negate    bool     `json:"negate"`
+	Values    []string `json:"values"`
+}
+
+type VariationMap struct {
+	Variation      string       `json:"variation"`
+	Targets        []*TargetMap `json:"targets,omitempty"`
+	TargetSegments []string     `json:"targetSegments,omitempty"`
+}
+
+type TargetMap struct {
+	Identifier string `json:"identifier"`
+	Name       string `json:"name,omitempty"`
+}
+
+// PatchFeatureFlagRequest changes a flag in one environment, one instruction
+// per change
+type PatchFeatureFlagRequest struct {
+	Instructions []*PatchInstruction `json:"instructions"`
+}
+
+type PatchInstruction struct {
+	Kind       string      `json:"kind"`
+	Parameters interface{} `json:"parameters"`
+}
+
+// TARGET GROUPS
+
+type ListSegmentResponse struct {
+	Segments  []*Segment `json:"segments"`
+	PageCount int64      `json:"pageCount"`
+	ItemCount int64      `json:"itemCount"`
+}
+
+type Segment struct {
+	Identifier  string            `json:"identifier"`
+	Name        string            `json:"name"`
+	Environment string            `json:"environment"`
+	Tags        []*FeatureFlagTag `json:"tags,omitempty"`
+	Included    []*Target         `json:"included,omitempty"`
+	Excluded    []*Target         `json:"excluded,omitempty"`
+	Rules       []*Clause         `json:"rules,omitempty"`
+}
+
+type CreateSegmentRequest struct {
+	Identifier  string    `json:"identifier"`
+	Name        string    `json:"name"`
+	Environment string    `json:"environment"`
+	Project     string    `json:"project"`
+	Included    []string  `json:"included,omitempty"`
+	Excluded    []string  `json:"excluded,omitempty"`
+	Rules       []*Clause `json:"rules,omitempty"`
+}
+
+type ListTargetResponse struct {
+	Targets   []*Target `json:"targets"`
+	PageCount int64     `json:"pageCount"`
+	ItemCount int64     `json:"itemCount"`
+}
+
+type Target struct {
+	Identifier  string                 `json:"identifier"`
+	Name        string                 `json:"name"`
+	Account     string                 `json:"account,omitempty"`
+	Org         string                 `json:"org,omitempty"`
+	Project     string                 `json:"project,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+	Attributes  map[string]interface{} `json:"attributes,omitempty"`
+}
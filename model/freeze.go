@@ -0,0 +1,31 @@
+package model
+
+type ListFreezeResponse struct {
+	Status        string         `json:"status"`
+	Data          ListFreezeData `json:"data"`
+	CorrelationID string         `json:"correlationId"`
+}
+
+type ListFreezeData struct {
+	TotalPages    int64     `json:"totalPages"`
+	TotalItems    int64     `json:"totalItems"`
+	PageItemCount int64     `json:"pageItemCount"`
+	PageSize      int64     `json:"pageSize"`
+	Content       []*Freeze `json:"content"`
+	PageIndex     int64     `json:"pageIndex"`
+	Empty         bool      `json:"empty"`
+}
+
+type GetFreezeResponse struct {
+	Status        string  `json:"status"`
+	Data          *Freeze `json:"data"`
+	CorrelationID string  `json:"correlationId"`
+}
+
+type Freeze struct {
+	Identifier string `json:"identifier"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Type       string `json:"type"`
+	Yaml       string `json:"yaml"`
+}
@@ -0,0 +1,61 @@
+package model
+
+// CHANNELS
+
+type NotificationChannel struct {
+	Identifier              string                   `json:"identifier"`
+	Name                    string                   `json:"name"`
+	Org                     string                   `json:"org,omitempty"`
+	Project                 string                   `json:"project,omitempty"`
+	NotificationChannelType string                   `json:"notification_channel_type"`
+	Status                  string                   `json:"status,omitempty"`
+	Channel                 *NotificationChannelSpec `json:"channel"`
+}
+
+// NotificationChannelSpec holds the destination of the channel, the fields
+// used depend on the channel type
+type NotificationChannelSpec struct {
+	SlackWebhookUrls         []string                     `json:"slack_webhook_urls,omitempty"`
+	WebhookUrls              []string                     `json:"webhook_urls,omitempty"`
+	EmailIds                 []string                     `json:"email_ids,omitempty"`
+	PagerDutyIntegrationKeys []string                     `json:"pager_duty_integration_keys,omitempty"`
+	MsTeamKeys               []string                     `json:"ms_team_keys,omitempty"`
+	UserGroups               []*NotificationUserGroup     `json:"user_groups,omitempty"`
+	Headers                  []*NotificationChannelHeader `json:"headers,omitempty"`
+	DelegateSelectors        []string                     `json:"delegate_selectors,omitempty"`
+	ExecuteOnDelegate        bool                         `json:"execute_on_delegate"`
+}
+
+type NotificationUserGroup struct {
+	Identifier string `json:"identifier"`
+}
+
+type NotificationChannelHeader struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// RULES
+
+type NotificationRule struct {
+	Identifier                    string                   `json:"identifier"`
+	Name                          string                   `json:"name"`
+	Org                           string                   `json:"org,omitempty"`
+	Project                       string                   `json:"project,omitempty"`
+	Status                        string                   `json:"status,omitempty"`
+	NotificationConditions        []*NotificationCondition `json:"notification_conditions"`
+	CustomNotificationTemplateRef map[string]interface{}   `json:"custom_notification_template_ref,omitempty"`
+}
+
+type NotificationCondition struct {
+	ConditionName            string                     `json:"condition_name"`
+	NotificationEventConfigs []*NotificationEventConfig `json:"notification_event_configs"`
+	NotificationChannelRefs  []string                   `json:"notification_channel_refs"`
+}
+
+type NotificationEventConfig struct {
+	NotificationEntity    string                 `json:"notification_entity"`
+	NotificationEvent     string                 `json:"notification_event"`
+	NotificationEventData map[string]interface{} `json:"notification_event_data,omitempty"`
+	EntityIdentifiers     []string               `json:"entity_identifiers,omitempty"`
+}
@@ -0,0 +1,24 @@
+package model
+
+type GetOrganizationResponse struct {
+	Status        string               `json:"status"`
+	Data          *GetOrganizationData `json:"data"`
+	CorrelationID string               `json:"correlationId"`
+}
+
+type GetOrganizationData struct {
+	Organization   *Organization `json:"organization"`
+	CreatedAt      int64         `json:"createdAt"`
+	LastModifiedAt int64         `json:"lastModifiedAt"`
+}
+
+type Organization struct {
+	Identifier  string            `json:"identifier"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Tags        map[string]string `json:"tags,omitempty"`
+}
+
+type CreateOrganizationRequest struct {
+	Org *Organization `json:"org"`
+}
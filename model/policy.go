@@ -0,0 +1,29 @@
+package model
+
+type Policy struct {
+	Identifier string `json:"identifier"`
+	Name       string `json:"name"`
+	Rego       string `json:"rego"`
+}
+
+type UpdatePolicyRequest struct {
+	Name string `json:"name"`
+	Rego string `json:"rego"`
+}
+
+type PolicySet struct {
+	Identifier  string             `json:"identifier"`
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Type        string             `json:"type"`
+	Action      string             `json:"action"`
+	Enabled     bool               `json:"enabled"`
+	Policies    []*PolicySetPolicy `json:"policies,omitempty"`
+}
+
+// PolicySetPolicy links a policy to the set, the identifier has the "org." or
+// "account." prefix when the policy is outside the project
+type PolicySetPolicy struct {
+	Identifier string `json:"identifier"`
+	Severity   string `json:"severity"`
+}
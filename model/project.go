@@ -16,11 +16,13 @@ type GetProjectData struct {
 }
 
 type Project struct {
-	OrgIdentifier string `json:"orgIdentifier"`
-	Identifier    string `json:"identifier"`
-	Name          string `json:"name"`
-	Description   string `json:"description"`
-	Color         string `json:"color"`
+	OrgIdentifier string            `json:"orgIdentifier"`
+	Identifier    string            `json:"identifier"`
+	Name          string            `json:"name"`
+	Description   string            `json:"description"`
+	Color         string            `json:"color"`
+	Modules       []string          `json:"modules,omitempty"`
+	Tags          map[string]string `json:"tags,omitempty"`
 }
 
 type CreateProjectRequest struct {
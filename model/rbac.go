@@ -0,0 +1,254 @@
+package model
+
+// ROLES
+
+type ListRoleResponse struct {
+	Status        string       `json:"status"`
+	Data          ListRoleData `json:"data"`
+	CorrelationID string       `json:"correlationId"`
+}
+
+type ListRoleData struct {
+	TotalPages    int64              `json:"totalPages"`
+	TotalItems    int64              `json:"totalItems"`
+	PageItemCount int64              `json:"pageItemCount"`
+	PageSize      int64              `json:"pageSize"`
+	Content       []*ListRoleContent `json:"content"`
+	PageIndex     int64              `json:"pageIndex"`
+	Empty         bool               `json:"empty"`
+}
+
+type ListRoleContent struct {
+	Role           *Role `json:"role"`
+	HarnessManaged bool  `json:"harnessManaged"`
+}
+
+type Role struct {
+	Identifier         string            `json:"identifier"`
+	Name               string            `json:"name"`
+	Permissions        []string          `json:"permissions"`
+	AllowedScopeLevels []string          `json:"allowedScopeLevels,omitempty"`
+	Description        string            `json:"description,omitempty"`
+	Tags               map[string]string `json:"tags,omitempty"`
+}
+
+// RESOURCE GROUPS
+
+type ListResourceGroupResponse struct {
+	Status        string                `json:"status"`
+	Data          ListResourceGroupData `json:"data"`
+	CorrelationID string                `json:"correlationId"`
+}
+
+type ListResourceGroupData struct {
+	TotalPages    int64                       `json:"totalPages"`
+	TotalItems    int64                       `json:"totalItems"`
+	PageItemCount int64                       `json:"pageItemCount"`
+	PageSize      int64                       `json:"pageSize"`
+	Content       []*ListResourceGroupContent `json:"content"`
+	PageIndex     int64                       `json:"pageIndex"`
+	Empty         bool                        `json:"empty"`
+}
+
+type ListResourceGroupContent struct {
+	ResourceGroup  *ResourceGroup `json:"resourceGroup"`
+	HarnessManaged bool           `json:"harnessManaged"`
+}
+
+type ResourceGroup struct {
+	AccountIdentifier  string                `json:"accountIdentifier"`
+	OrgIdentifier      string                `json:"orgIdentifier,omitempty"`
+	ProjectIdentifier  string                `json:"projectIdentifier,omitempty"`
+	Identifier         string                `json:"identifier"`
+	Name               string                `json:"name"`
+	Color              string                `json:"color,omitempty"`
+	Tags               map[string]string     `json:"tags,omitempty"`
+	Description        string                `json:"description,omitempty"`
+	AllowedScopeLevels []string              `json:"allowedScopeLevels,omitempty"`
+	IncludedScopes     []*ResourceGroupScope `json:"includedScopes,omitempty"`
+	ResourceFilter     *ResourceFilter       `json:"resourceFilter,omitempty"`
+}
+
+type ResourceGroupScope struct {
+	Filter            string `json:"filter"`
+	AccountIdentifier string `json:"accountIdentifier"`
+	OrgIdentifier     string `json:"orgIdentifier,omitempty"`
+	ProjectIdentifier string `json:"projectIdentifier,omitempty"`
+}
+
+type ResourceFilter struct {
+	Resources           []*ResourceSelector `json:"resources,omitempty"`
+	IncludeAllResources bool                `json:"includeAllResources"`
+}
+
+type ResourceSelector struct {
+	ResourceType    string      `json:"resourceType"`
+	Identifiers     []string    `json:"identifiers,omitempty"`
+	AttributeFilter interface{} `json:"attributeFilter,omitempty"`
+}
+
+type CreateResourceGroupRequest struct {
+	ResourceGroup *ResourceGroup `json:"resourceGroup"`
+}
+
+// USER GROUPS
+
+type ListUserGroupResponse struct {
+	Status        string            `json:"status"`
+	Data          ListUserGroupData `json:"data"`
+	CorrelationID string            `json:"correlationId"`
+}
+
+type ListUserGroupData struct {
+	TotalPages    int64        `json:"totalPages"`
+	TotalItems    int64        `json:"totalItems"`
+	PageItemCount int64        `json:"pageItemCount"`
+	PageSize      int64        `json:"pageSize"`
+	Content       []*UserGroup `json:"content"`
+	PageIndex     int64        `json:"pageIndex"`
+	Empty         bool         `json:"empty"`
+}
+
+type UserGroup struct {
+	AccountIdentifier   string            `json:"accountIdentifier"`
+	OrgIdentifier       string            `json:"orgIdentifier,omitempty"`
+	ProjectIdentifier   string            `json:"projectIdentifier,omitempty"`
+	Identifier          string            `json:"identifier"`
+	Name                string            `json:"name"`
+	Users               []string          `json:"users"`
+	NotificationConfigs []interface{}     `json:"notificationConfigs,omitempty"`
+	Description         string            `json:"description,omitempty"`
+	Tags                map[string]string `json:"tags,omitempty"`
+	HarnessManaged      bool              `json:"harnessManaged,omitempty"`
+	ExternallyManaged   bool              `json:"externallyManaged,omitempty"`
+	SsoLinked           bool              `json:"ssoLinked,omitempty"`
+}
+
+// ROLE ASSIGNMENTS
+
+type ListRoleAssignmentResponse struct {
+	Status        string                 `json:"status"`
+	Data          ListRoleAssignmentData `json:"data"`
+	CorrelationID string                 `json:"correlationId"`
+}
+
+type ListRoleAssignmentData struct {
+	TotalPages    int64                        `json:"totalPages"`
+	TotalItems    int64                        `json:"totalItems"`
+	PageItemCount int64                        `json:"pageItemCount"`
+	PageSize      int64                        `json:"pageSize"`
+	Content       []*ListRoleAssignmentContent `json:"content"`
+	PageIndex     int64                        `json:"pageIndex"`
+	Empty         bool                         `json:"empty"`
+}
+
+type ListRoleAssignmentContent struct {
+	RoleAssignment *RoleAssignment `json:"roleAssignment"`
+	HarnessManaged bool            `json:"harnessManaged"`
+}
+
+type RoleAssignment struct {
+	Identifier              string     `json:"identifier,omitempty"`
+	ResourceGroupIdentifier string     `json:"resourceGroupIdentifier"`
+	RoleIdentifier          string     `json:"roleIdentifier"`
+	Principal               *Principal `json:"principal"`
+	Disabled                bool       `json:"disabled"`
+	Managed                 bool       `json:"managed"`
+}
+
+type PrincipalType string
+
+const (
+	PrincipalUser           PrincipalType = "USER"
+	PrincipalUserGroup      PrincipalType = "USER_GROUP"
+	PrincipalServiceAccount PrincipalType = "SERVICE_ACCOUNT"
+)
+
+type Principal struct {
+	Identifier string        `json:"identifier"`
+	Type       PrincipalType `json:"type"`
+	ScopeLevel string        `json:"scopeLevel,omitempty"`
+}
+
+// USERS
+
+type ListUserResponse struct {
+	Status        string       `json:"status"`
+	Data          ListUserData `json:"data"`
+	CorrelationID string       `json:"correlationId"`
+}
+
+type ListUserData struct {
+	TotalPages    int64              `json:"totalPages"`
+	TotalItems    int64              `json:"totalItems"`
+	PageItemCount int64              `json:"pageItemCount"`
+	PageSize      int64              `json:"pageSize"`
+	Content       []*ListUserContent `json:"content"`
+	PageIndex     int64              `json:"pageIndex"`
+	Empty         bool               `json:"empty"`
+}
+
+type ListUserContent struct {
+	User *User `json:"user"`
+}
+
+type User struct {
+	UUID  string `json:"uuid"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// SERVICE ACCOUNTS
+
+type ListServiceAccountResponse struct {
+	Status        string            `json:"status"`
+	Data          []*ServiceAccount `json:"data"`
+	CorrelationID string            `json:"correlationId"`
+}
+
+type ServiceAccount struct {
+	Identifier        string            `json:"identifier"`
+	Name              string            `json:"name"`
+	Email             string            `json:"email"`
+	Description       string            `json:"description,omitempty"`
+	Tags              map[string]string `json:"tags,omitempty"`
+	AccountIdentifier string            `json:"accountIdentifier"`
+	OrgIdentifier     string            `json:"orgIdentifier,omitempty"`
+	ProjectIdentifier string            `json:"projectIdentifier,omitempty"`
+}
+
+type ListApiKeyResponse struct {
+	Status        string    `json:"status"`
+	Data          []*ApiKey `json:"data"`
+	CorrelationID string    `json:"correlationId"`
+}
+
+type ApiKey struct {
+	Identifier               string            `json:"identifier"`
+	Name                     string            `json:"name"`
+	Description              string            `json:"description,omitempty"`
+	Tags                     map[string]string `json:"tags,omitempty"`
+	AccountIdentifier        string            `json:"accountIdentifier"`
+	OrgIdentifier            string            `json:"orgIdentifier,omitempty"`
+	ProjectIdentifier        string            `json:"projectIdentifier,omitempty"`
+	ApiKeyType               string            `json:"apiKeyType"`
+	ParentIdentifier         string            `json:"parentIdentifier"`
+	DefaultTimeToExpireToken int64             `json:"defaultTimeToExpireToken,omitempty"`
+}
+
+type CreateTokenRequest struct {
+	Identifier        string `json:"identifier"`
+	Name              string `json:"name"`
+	AccountIdentifier string `json:"accountIdentifier"`
+	OrgIdentifier     string `json:"orgIdentifier,omitempty"`
+	ProjectIdentifier string `json:"projectIdentifier,omitempty"`
+	ApiKeyIdentifier  string `json:"apiKeyIdentifier"`
+	ParentIdentifier  string `json:"parentIdentifier"`
+	ApiKeyType        string `json:"apiKeyType"`
+}
+
+type CreateTokenResponse struct {
+	Status        string `json:"status"`
+	Data          string `json:"data"`
+	CorrelationID string `json:"correlationId"`
+}
@@ -0,0 +1,49 @@
+package model
+
+type ListSettingResponse struct {
+	Status        string         `json:"status"`
+	Data          []*SettingItem `json:"data"`
+	CorrelationID string         `json:"correlationId"`
+}
+
+type SettingItem struct {
+	Setting        *Setting `json:"setting"`
+	LastModifiedAt int64    `json:"lastModifiedAt"`
+}
+
+// Setting is the value of a setting in a scope. The source tells where the
+// value comes from: DEFAULT, ACCOUNT, ORG or PROJECT.
+type Setting struct {
+	Identifier        string   `json:"identifier"`
+	Name              string   `json:"name"`
+	OrgIdentifier     string   `json:"orgIdentifier,omitempty"`
+	ProjectIdentifier string   `json:"projectIdentifier,omitempty"`
+	Category          string   `json:"category"`
+	GroupIdentifier   string   `json:"groupIdentifier"`
+	ValueType         string   `json:"valueType"`
+	AllowedValues     []string `json:"allowedValues,omitempty"`
+	AllowOverrides    bool     `json:"allowOverrides"`
+	Value             *string  `json:"value"`
+	DefaultValue      *string  `json:"defaultValue"`
+	SettingSource     string   `json:"settingSource"`
+	IsSettingEditable bool     `json:"isSettingEditable"`
+}
+
+type UpdateSettingRequest struct {
+	Identifier     string  `json:"identifier"`
+	Value          *string `json:"value"`
+	AllowOverrides bool    `json:"allowOverrides"`
+	UpdateType     string  `json:"updateType"`
+}
+
+type UpdateSettingResponse struct {
+	Status        string                 `json:"status"`
+	Data          []*UpdateSettingResult `json:"data"`
+	CorrelationID string                 `json:"correlationId"`
+}
+
+type UpdateSettingResult struct {
+	Identifier   string `json:"identifier"`
+	UpdateStatus bool   `json:"updateStatus"`
+	ErrorMessage string `json:"errorMessage"`
+}
@@ -0,0 +1,79 @@
+package model
+
+// MONITORED SERVICES
+
+type ListMonitoredServiceResponse struct {
+	Status        string                   `json:"status"`
+	Data          ListMonitoredServiceData `json:"data"`
+	CorrelationID string                   `json:"correlationId"`
+}
+
+type ListMonitoredServiceData struct {
+	TotalPages    int64                       `json:"totalPages"`
+	TotalItems    int64                       `json:"totalItems"`
+	PageItemCount int64                       `json:"pageItemCount"`
+	PageSize      int64                       `json:"pageSize"`
+	Content       []*MonitoredServiceListItem `json:"content"`
+	PageIndex     int64                       `json:"pageIndex"`
+	Empty         bool                        `json:"empty"`
+}
+
+type MonitoredServiceListItem struct {
+	Identifier     string `json:"identifier"`
+	Name           string `json:"name"`
+	ServiceRef     string `json:"serviceRef"`
+	EnvironmentRef string `json:"environmentRef"`
+}
+
+// GetMonitoredServiceResponse keeps the monitored service as decoded JSON, its
+// health and change sources have a spec by type
+type GetMonitoredServiceResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		MonitoredService map[string]interface{} `json:"monitoredService"`
+	} `json:"data"`
+}
+
+// SLOS
+
+type ListSLOResponse struct {
+	Status        string      `json:"status"`
+	Data          ListSLOData `json:"data"`
+	CorrelationID string      `json:"correlationId"`
+}
+
+type ListSLOData struct {
+	TotalPages    int64          `json:"totalPages"`
+	TotalItems    int64          `json:"totalItems"`
+	PageItemCount int64          `json:"pageItemCount"`
+	PageSize      int64          `json:"pageSize"`
+	Content       []*SLOListItem `json:"content"`
+	PageIndex     int64          `json:"pageIndex"`
+	Empty         bool           `json:"empty"`
+}
+
+type SLOListItem struct {
+	SloIdentifier string `json:"sloIdentifier"`
+	Name          string `json:"name"`
+	SloType       string `json:"sloType"`
+}
+
+type GetSLOResponse struct {
+	Resource struct {
+		ServiceLevelObjectiveV2 map[string]interface{} `json:"serviceLevelObjectiveV2"`
+	} `json:"resource"`
+}
+
+type ListUserJourneyResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		Content []*struct {
+			UserJourney *UserJourney `json:"userJourney"`
+		} `json:"content"`
+	} `json:"data"`
+}
+
+type UserJourney struct {
+	Identifier string `json:"identifier"`
+	Name       string `json:"name"`
+}
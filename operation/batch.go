@@ -0,0 +1,258 @@
+package operation
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Fernando-Dourado/harness-move-project/services"
+	"github.com/fatih/color"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// BatchRow is one project pair of the manifest, written as "org/project".
+	// The target project defaults to the source project.
+	BatchRow struct {
+		Source        string `yaml:"source"`
+		Target        string `yaml:"target"`
+		CreateProject bool   `yaml:"createProject"`
+	}
+
+	// Batch runs one Move for each row, using the accounts and settings from the
+	// source, target and config
+	Batch struct {
+		Source     CopyConfig
+		Target     CopyConfig
+		Config     OperationConfig
+		Rows       []BatchRow
+		Parallel   int
+		ReportFile string
+	}
+
+	BatchRowReport struct {
+		Source  string                 `json:"source"`
+		Target  string                 `json:"target"`
+		Status  string                 `json:"status"`
+		Error   string                 `json:"error,omitempty"`
+		Entries []services.ReportEntry `json:"entries"`
+	}
+)
+
+// LoadBatchManifest reads the rows from a CSV or YAML file. The CSV must have
+// the "source", "target" and "create-project" columns.
+func LoadBatchManifest(path string) ([]BatchRow, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rows []BatchRow
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		rows, err = readCsvManifest(f)
+	case ".yaml", ".yml":
+		err = yaml.NewDecoder(f).Decode(&rows)
+	default:
+		err = fmt.Errorf("unsupported manifest extension %s", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("manifest %s: %w", path, err)
+	}
+
+	for i, row := range rows {
+		if _, _, err := row.source(); err != nil {
+			return nil, fmt.Errorf("manifest %s row %d: %w", path, i+1, err)
+		}
+		if _, _, err := row.target(); err != nil {
+			return nil, fmt.Errorf("manifest %s row %d: %w", path, i+1, err)
+		}
+	}
+	return rows, nil
+}
+
+// FanOutRows copies the source "org/project" to every target, written as
+// "org/project" or "org" to keep the source project name
+func FanOutRows(source string, targets []string, createProject bool) ([]BatchRow, error) {
+	var rows []BatchRow
+	for _, target := range targets {
+		row := BatchRow{
+			Source:        source,
+			Target:        strings.TrimSpace(target),
+			CreateProject: createProject,
+		}
+		if _, _, err := row.source(); err != nil {
+			return nil, err
+		}
+		if _, _, err := row.target(); err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func readCsvManifest(r io.Reader) ([]BatchRow, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"source", "target"} {
+		if _, found := columns[name]; !found {
+			return nil, fmt.Errorf("missing column %s", name)
+		}
+	}
+
+	var rows []BatchRow
+	for _, record := range records[1:] {
+		row := BatchRow{
+			Source: strings.TrimSpace(record[columns["source"]]),
+			Target: strings.TrimSpace(record[columns["target"]]),
+		}
+		if i, found := columns["create-project"]; found && len(strings.TrimSpace(record[i])) > 0 {
+			if row.CreateProject, err = strconv.ParseBool(strings.TrimSpace(record[i])); err != nil {
+				return nil, err
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (r BatchRow) source() (string, string, error) {
+	org, project, found := strings.Cut(r.Source, "/")
+	if !found || len(org) == 0 || len(project) == 0 {
+		return "", "", fmt.Errorf("invalid source %q, expected org/project", r.Source)
+	}
+	return org, project, nil
+}
+
+func (r BatchRow) target() (string, string, error) {
+	org, project, _ := strings.Cut(r.Target, "/")
+	if len(org) == 0 {
+		return "", "", fmt.Errorf("invalid target %q, expected org/project", r.Target)
+	}
+	if len(project) == 0 {
+		_, project, _ = r.source()
+	}
+	return org, project, nil
+}
+
+func (b *Batch) Exec() error {
+
+	parallel := b.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	// THE ROWS OF THE SAME SOURCE PROJECT READ IT ONCE
+	cache := services.NewSourceCache()
+
+	sem := make(chan struct{}, parallel)
+	reports := make([]*BatchRowReport, len(b.Rows))
+	var wg sync.WaitGroup
+	for i, row := range b.Rows {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, row BatchRow) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			reports[i] = b.execRow(row, cache)
+		}(i, row)
+	}
+	wg.Wait()
+
+	var failed []string
+	for _, r := range reports {
+		if len(r.Error) > 0 {
+			failed = append(failed, fmt.Sprintf("%s -> %s - %s", r.Source, r.Target, r.Error))
+		}
+	}
+
+	if len(b.ReportFile) > 0 {
+		if err := b.writeReport(reports); err != nil {
+			return fmt.Errorf("writing report: %w", err)
+		}
+		fmt.Println("Report written to", b.ReportFile)
+	}
+
+	if len(failed) > 0 {
+		fmt.Println(color.RedString(strings.Join(failed, "\n")))
+		return fmt.Errorf("%d of %d rows failed", len(failed), len(b.Rows))
+	}
+	fmt.Println(color.GreenString("Batch done, %d rows", len(b.Rows)))
+	return nil
+}
+
+// execRow moves one project pair. The failure is kept in the row report, so
+// the other rows still run.
+func (b *Batch) execRow(row BatchRow, cache *services.SourceCache) *BatchRowReport {
+	sourceOrg, sourceProject, _ := row.source()
+	targetOrg, targetProject, _ := row.target()
+
+	source := b.Source
+	source.Org, source.Project = sourceOrg, sourceProject
+	target := b.Target
+	target.Org, target.Project = targetOrg, targetProject
+	config := b.Config
+	config.CreateProject = row.CreateProject
+	config.ReportFile = ""
+	config.TokenFile = rowFile(config.TokenFile, targetOrg, targetProject)
+	config.StateFile = rowFile(config.StateFile, targetOrg, targetProject)
+
+	fmt.Println(color.CyanString("Moving %s/%s to %s/%s", sourceOrg, sourceProject, targetOrg, targetProject))
+
+	mv := NewMove(source, target, config)
+	mv.SourceCache = cache
+	err := mv.Exec()
+
+	report := &BatchRowReport{
+		Source:  sourceOrg + "/" + sourceProject,
+		Target:  targetOrg + "/" + targetProject,
+		Status:  "done",
+		Entries: []services.ReportEntry{},
+	}
+	if mv.Report != nil {
+		report.Entries = mv.Report.Entries
+	}
+	if err != nil {
+		report.Status = "failed"
+		report.Error = err.Error()
+	}
+	return report
+}
+
+func (b *Batch) writeReport(reports []*BatchRowReport) error {
+	body, err := json.MarshalIndent(map[string]interface{}{
+		"rows": reports,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(b.ReportFile, body, 0644)
+}
+
+// rowFile adds the target org and project to the file name, so the rows don't
+// overwrite each other
+func rowFile(path, org, project string) string {
+	if len(path) == 0 {
+		return ""
+	}
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%s-%s%s", strings.TrimSuffix(path, ext), org, project, ext)
+}
@@ -0,0 +1,60 @@
+package operation
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/stretchr/testify/assert"
+
+	"testing"
+)
+
+func TestLoadBatchManifest_Csv(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "rows.csv")
+	content := "source,target,create-project\n" +
+		"org1/proj1,org2/proj1,true\n" +
+		"org1/proj2,org2,\n"
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	rows, err := LoadBatchManifest(path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []BatchRow{
+		{Source: "org1/proj1", Target: "org2/proj1", CreateProject: true},
+		{Source: "org1/proj2", Target: "org2"},
+	}, rows)
+
+	org, project, _ := rows[1].target()
+	assert.Equal(t, "org2", org)
+	assert.Equal(t, "proj2", project)
+}
+
+func TestLoadBatchManifest_InvalidSource(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "rows.yaml")
+	content := "- source: org1\n  target: org2/proj1\n"
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	_, err := LoadBatchManifest(path)
+
+	assert.ErrorContains(t, err, "row 1")
+}
+
+func TestRowFile(t *testing.T) {
+	assert.Equal(t, "tokens-org-project.json", rowFile("tokens.json", "org", "project"))
+	assert.Equal(t, "out/tokens-org-project", rowFile("out/tokens", "org", "project"))
+	assert.Equal(t, "", rowFile("", "org", "project"))
+}
+
+func TestFanOutRows(t *testing.T) {
+	rows, err := FanOutRows("default/golden", []string{"dev/payments", " staging"}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []BatchRow{
+		{Source: "default/golden", Target: "dev/payments", CreateProject: true},
+		{Source: "default/golden", Target: "staging", CreateProject: true},
+	}, rows)
+
+	_, err = FanOutRows("default/golden", []string{"/payments"}, false)
+	assert.Error(t, err)
+}
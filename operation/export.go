@@ -0,0 +1,47 @@
+package operation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Fernando-Dourado/harness-move-project/services"
+	"github.com/fatih/color"
+)
+
+// ExportTerraform writes the source entities to dir as Terraform
+// configuration of the target project, nothing is written to the target
+func (o *Move) ExportTerraform(dir string) error {
+
+	sourceApi, targetApi, st := o.requests()
+
+	if err := sourceApi.ValidateSource(o.Source.Org, o.Source.Project); err != nil {
+		return err
+	}
+
+	tf := services.NewTerraformExport(o.Target.Org, o.Target.Project)
+	if err := services.NewProjectOperation(sourceApi, targetApi, st).Export(tf); err != nil {
+		return fmt.Errorf("exporting project: %w", err)
+	}
+	for _, s := range o.selectSteps(o.newSteps(sourceApi, targetApi, st)) {
+		exporter, ok := s.op.(services.Exporter)
+		if !ok {
+			continue
+		}
+		fmt.Println("Exporting", s.entity)
+		if err := exporter.Export(tf); err != nil {
+			return fmt.Errorf("exporting %s: %w", s.entity, err)
+		}
+	}
+
+	if err := tf.Write(dir); err != nil {
+		return fmt.Errorf("writing terraform: %w", err)
+	}
+	fmt.Println(color.GreenString("%d resources written to %s", tf.Resources(), dir))
+
+	if missing := tf.NotExported(); len(missing) > 0 {
+		fmt.Println(color.RedString("Not exported %d", len(missing)))
+		fmt.Println(color.RedString(strings.Join(missing, "\n")))
+		return fmt.Errorf("%d entities are not exported, they must be created by hand", len(missing))
+	}
+	return nil
+}
@@ -3,6 +3,9 @@ package operation
 import (
 	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
 
 	"github.com/Fernando-Dourado/harness-move-project/services"
 	"github.com/fatih/color"
@@ -11,7 +14,27 @@ import (
 
 type (
 	OperationConfig struct {
+		CreateOrg     bool
 		CreateProject bool
+		Mapping       services.IdentifierMapping
+		Entities      []services.EntityType
+		Filter        services.EntityFilter
+		Concurrency   int
+		ReportFile    string
+		OnConflict    services.ConflictPolicy
+		Verify        bool
+		Principals    services.PrincipalMapping
+		TokenFile     string
+		ProjectAdmins []string
+		// StateFile keeps the content hash of the synced entities, the
+		// unchanged ones are skipped by the next runs
+		StateFile string
+		// ModifiedSince skips the source entities last modified before it, in
+		// epoch milliseconds, for the types exposing their modification time
+		ModifiedSince int64
+		// Delete removes the synced target entities whose source entity was
+		// deleted, it needs the state file
+		Delete bool
 	}
 
 	CopyConfig struct {
@@ -26,6 +49,16 @@ type (
 		Source CopyConfig
 		Target CopyConfig
 		Config OperationConfig
+		// Report holds the outcome of the entities after Exec
+		Report *services.Report
+		// SourceCache answers the source requests already sent by the other
+		// moves, the source is always read when nil
+		SourceCache *services.SourceCache
+	}
+
+	step struct {
+		entity services.EntityType
+		op     services.Operation
 	}
 )
 
@@ -39,59 +72,360 @@ func NewMove(s, t CopyConfig, c OperationConfig) *Move {
 
 func (o *Move) Exec() error {
 
+	if o.Config.Delete && len(o.Config.StateFile) == 0 {
+		return errors.New("deleting the target entities needs the state file")
+	}
+
+	sourceApi, targetApi, st := o.requests()
+
+	// SOURCE AND TARGET MUST EXIST
+	if err := sourceApi.ValidateSource(o.Source.Org, o.Source.Project); err != nil {
+		return err
+	}
+	if err := targetApi.ValidateTarget(o.Target.Org, o.Target.Project); err != nil {
+		if err := o.createOrgWhenRequired(sourceApi, targetApi, err); err != nil {
+			return err
+		}
+		if err = o.createProjectWhenRequired(sourceApi, targetApi, err); err != nil {
+			return err
+		}
+	}
+
+	state, err := o.loadState()
+	if err != nil {
+		return err
+	}
+	st.State = state
+
+	steps := o.selectSteps(o.newSteps(sourceApi, targetApi, st))
+	if o.Config.Delete {
+		warnUnprunable(steps)
+	}
+
+	// CHECK REFERENCES AND SORT THE OPERATIONS BEFORE WRITING ANYTHING
+	levels, err := o.planSteps(targetApi, steps)
+	if err != nil {
+		return err
+	}
+
+	if err := o.runLevels(levels, state, st.Tokens); err != nil {
+		return err
+	}
+
+	st.Report.Print()
+
+	var verifyErr error
+	if o.Config.Verify {
+		verifyErr = o.verifySteps(steps, st.Report)
+	}
+
+	// THE TOKENS COME FIRST, THEIR VALUES CAN'T BE READ AGAIN
+	if err := o.writeTokens(st.Tokens); err != nil {
+		return err
+	}
+	if err := o.writeReport(st.Report); err != nil {
+		return err
+	}
+	if err := o.writeState(state); err != nil {
+		return err
+	}
+	if verifyErr != nil {
+		return verifyErr
+	}
+
+	fmt.Println(color.GreenString("Done"))
+	return nil
+}
+
+// Verify checks the target against the source without copying anything
+func (o *Move) Verify() error {
+
+	sourceApi, targetApi, st := o.requests()
+
+	if err := sourceApi.ValidateSource(o.Source.Org, o.Source.Project); err != nil {
+		return err
+	}
+	if err := targetApi.ValidateTarget(o.Target.Org, o.Target.Project); err != nil {
+		return err
+	}
+
+	verifyErr := o.verifySteps(o.selectSteps(o.newSteps(sourceApi, targetApi, st)), st.Report)
+	if err := o.writeReport(st.Report); err != nil {
+		return err
+	}
+	if verifyErr != nil {
+		return verifyErr
+	}
+
+	fmt.Println(color.GreenString("Done"))
+	return nil
+}
+
+func (o *Move) requests() (*services.SourceRequest, *services.TargetRequest, *services.SourceTarget) {
+
 	client := resty.New()
 
-	sourceApi := services.SourceRequest{
+	sourceApi := &services.SourceRequest{
 		Client:  client,
 		Token:   o.Source.Token,
 		Account: o.Source.Account,
 		Url:     o.Source.Url,
 	}
-	targetApi := services.TargetRequest{
+	targetApi := &services.TargetRequest{
 		Client:  client,
 		Token:   o.Target.Token,
 		Account: o.Target.Account,
 		Url:     o.Target.Url,
 	}
-
-	// SOURCE AND TARGET MUST EXIST
-	if err := sourceApi.ValidateSource(o.Source.Org, o.Source.Project); err != nil {
-		return err
-	}
-	if err := targetApi.ValidateTarget(o.Target.Org, o.Target.Project); err != nil {
-		if err = o.createProjectWhenRequired(&sourceApi, &targetApi, err); err != nil {
-			return err
-		}
+	if o.SourceCache != nil {
+		sourceApi.Client = resty.New().SetTransport(o.SourceCache.Transport(http.DefaultTransport))
 	}
-
 	st := &services.SourceTarget{
 		SourceOrg:     o.Source.Org,
 		SourceProject: o.Source.Project,
 		TargetOrg:     o.Target.Org,
 		TargetProject: o.Target.Project,
+		Mapping:       o.Config.Mapping,
+		Filter:        o.Config.Filter,
+		Report:        services.NewReport(),
+		OnConflict:    o.Config.OnConflict,
+		Principals:    o.Config.Principals,
+		ModifiedSince: o.Config.ModifiedSince,
+	}
+	if len(o.Config.TokenFile) > 0 {
+		st.Tokens = services.NewTokenFile()
+	}
+	o.Report = st.Report
+	return sourceApi, targetApi, st
+}
+
+func (o *Move) newSteps(sourceApi *services.SourceRequest, targetApi *services.TargetRequest, st *services.SourceTarget) []step {
+	return []step{
+		{services.EntityVariable, services.NewVariableOperation(sourceApi, targetApi, st)},
+		{services.EntitySecret, services.NewSecretOperation(sourceApi, targetApi, st)},
+		{services.EntityConnector, services.NewConnectorOperation(sourceApi, targetApi, st)},
+		{services.EntityFileStore, services.NewFileStoreOperation(sourceApi, targetApi, st)},
+		{services.EntitySetting, services.NewSettingOperation(sourceApi, targetApi, st)},
+		{services.EntityEnvironment, services.NewEnvironmentOperation(sourceApi, targetApi, st)},
+		{services.EntityEnvironmentGroup, services.NewEnvironmentGroupOperation(sourceApi, targetApi, st)},
+		{services.EntityInfrastructure, services.NewInfrastructureOperation(sourceApi, targetApi, st)},
+		{services.EntityService, services.NewServiceOperation(sourceApi, targetApi, st)},
+		{services.EntityOverrideV1, services.NewServiceOverrideOperation(sourceApi, targetApi, st)},
+		{services.EntityOverrideV2, services.NewOverrideV2Operation(sourceApi, targetApi, st)},
+		{services.EntityTemplate, services.NewTemplateOperation(sourceApi, targetApi, st)},
+		{services.EntityPipeline, services.NewPipelineOperation(sourceApi, targetApi, st)},
+		{services.EntityInputset, services.NewInputsetOperation(sourceApi, targetApi, st)},
+		{services.EntityFreeze, services.NewFreezeOperation(sourceApi, targetApi, st)},
+		{services.EntityMonitoredService, services.NewMonitoredServiceOperation(sourceApi, targetApi, st)},
+		{services.EntitySLO, services.NewSLOOperation(sourceApi, targetApi, st)},
+		{services.EntityTargetGroup, services.NewTargetGroupOperation(sourceApi, targetApi, st)},
+		{services.EntityFeatureFlag, services.NewFeatureFlagOperation(sourceApi, targetApi, st)},
+		{services.EntityPolicy, services.NewPolicyOperation(sourceApi, targetApi, st)},
+		{services.EntityPolicySet, services.NewPolicySetOperation(sourceApi, targetApi, st)},
+		{services.EntityRole, services.NewRoleOperation(sourceApi, targetApi, st)},
+		{services.EntityResourceGroup, services.NewResourceGroupOperation(sourceApi, targetApi, st)},
+		{services.EntityUserGroup, services.NewUserGroupOperation(sourceApi, targetApi, st)},
+		{services.EntityServiceAccount, services.NewServiceAccountOperation(sourceApi, targetApi, st)},
+		{services.EntityRoleAssignment, services.NewRoleAssignmentOperation(sourceApi, targetApi, st)},
+		{services.EntityNotificationChannel, services.NewNotificationChannelOperation(sourceApi, targetApi, st)},
+		{services.EntityNotificationRule, services.NewNotificationRuleOperation(sourceApi, targetApi, st)},
+	}
+}
+
+// verifySteps lists the target again and checks every source entity, the
+// result is kept in the report
+func (o *Move) verifySteps(steps []step, report *services.Report) error {
+
+	fmt.Println("Verifying target...")
+
+	v := services.NewVerification()
+	for _, s := range steps {
+		if verifier, ok := s.op.(services.Verifier); ok {
+			if err := verifier.Verify(v); err != nil {
+				return fmt.Errorf("verifying %s: %w", s.entity, err)
+			}
+		}
+	}
+	report.Verification = v
+
+	v.Print()
+	if failed := v.Failed(); failed > 0 {
+		return fmt.Errorf("verification failed for %d checks", failed)
 	}
+	return nil
+}
 
-	var operations []services.Operation
-	operations = append(operations, services.NewVariableOperation(&sourceApi, &targetApi, st))
-	operations = append(operations, services.NewSecretOperation(&sourceApi, &targetApi, st))
-	operations = append(operations, services.NewConnectorOperation(&sourceApi, &targetApi, st))
-	operations = append(operations, services.NewFileStoreOperation(&sourceApi, &targetApi, st))
-	operations = append(operations, services.NewEnvironmentOperation(&sourceApi, &targetApi, st))
-	operations = append(operations, services.NewInfrastructureOperation(&sourceApi, &targetApi, st))
-	operations = append(operations, services.NewServiceOperation(&sourceApi, &targetApi, st))
-	operations = append(operations, services.NewServiceOverrideOperation(&sourceApi, &targetApi, st))
-	operations = append(operations, services.NewOverrideV2Operation(&sourceApi, &targetApi, st))
-	operations = append(operations, services.NewTemplateOperation(&sourceApi, &targetApi, st))
-	operations = append(operations, services.NewPipelineOperation(&sourceApi, &targetApi, st))
-	operations = append(operations, services.NewInputsetOperation(&sourceApi, &targetApi, st))
-
-	for _, op := range operations {
-		if err := op.Move(); err != nil {
+func (o *Move) writeReport(report *services.Report) error {
+	if len(o.Config.ReportFile) == 0 {
+		return nil
+	}
+	if err := report.Write(o.Config.ReportFile); err != nil {
+		return fmt.Errorf("writing report: %w", err)
+	}
+	fmt.Println("Report written to", o.Config.ReportFile)
+	return nil
+}
+
+// writeTokens saves the minted service account tokens
+func (o *Move) writeTokens(tokens *services.TokenFile) error {
+	if tokens == nil {
+		return nil
+	}
+	if err := tokens.Write(o.Config.TokenFile); err != nil {
+		return fmt.Errorf("writing tokens: %w", err)
+	}
+	fmt.Println(len(tokens.Tokens), "tokens written to", o.Config.TokenFile)
+	return nil
+}
+
+// loadState reads the content hashes of the previous runs, when the state file
+// is set
+func (o *Move) loadState() (*services.SyncState, error) {
+	if len(o.Config.StateFile) == 0 {
+		return nil, nil
+	}
+	state, err := services.LoadSyncState(o.Config.StateFile, o.Target.Org, o.Target.Project)
+	if err != nil {
+		return nil, fmt.Errorf("reading state: %w", err)
+	}
+	return state, nil
+}
+
+func (o *Move) writeState(state *services.SyncState) error {
+	if state == nil {
+		return nil
+	}
+	if err := state.Write(o.Config.StateFile); err != nil {
+		return fmt.Errorf("writing state: %w", err)
+	}
+	return nil
+}
+
+// pruneSteps deletes the target entities whose source was deleted, the levels
+// are walked backwards so the entities are deleted before their dependencies
+func (o *Move) pruneSteps(levels [][]step) error {
+	for i := len(levels) - 1; i >= 0; i-- {
+		for _, s := range levels[i] {
+			pruner, ok := s.op.(services.Pruner)
+			if !ok {
+				continue
+			}
+			if err := pruner.Prune(); err != nil {
+				return fmt.Errorf("deleting %s: %w", s.entity, err)
+			}
+		}
+	}
+	return nil
+}
+
+// warnUnprunable lists the selected entity types whose target entities are
+// never deleted by --delete
+func warnUnprunable(steps []step) {
+	var types []string
+	for _, s := range steps {
+		if _, ok := s.op.(services.Pruner); !ok {
+			types = append(types, string(s.entity))
+		}
+	}
+	if len(types) > 0 {
+		fmt.Println(color.YellowString("Deleting is not supported for %s, their removed source entities are kept in the target", strings.Join(types, ", ")))
+	}
+}
+
+// selectSteps keeps the steps of the selected entity types
+func (o *Move) selectSteps(steps []step) []step {
+	if len(o.Config.Entities) == 0 {
+		return steps
+	}
+	selected := map[services.EntityType]bool{}
+	for _, t := range o.Config.Entities {
+		selected[t] = true
+	}
+	var out []step
+	for _, s := range steps {
+		if selected[s.entity] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// runLevel runs the steps of one level, up to the configured concurrency at
+// the same time
+// runLevels runs the levels in order, then deletes the removed entities. On a
+// failure the tokens minted and the entities synced so far are still saved.
+func (o *Move) runLevels(levels [][]step, state *services.SyncState, tokens *services.TokenFile) error {
+	for _, level := range levels {
+		if err := o.runLevel(level); err != nil {
+			o.writeTokens(tokens)
+			o.writeState(state)
 			return err
 		}
 	}
 
-	fmt.Println(color.GreenString("Done"))
+	if o.Config.Delete {
+		if err := o.pruneSteps(levels); err != nil {
+			o.writeTokens(tokens)
+			o.writeState(state)
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *Move) runLevel(level []step) error {
+	if o.Config.Concurrency <= 1 {
+		for _, s := range level {
+			if err := s.op.Move(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	sem := make(chan struct{}, o.Config.Concurrency)
+	errs := make([]error, len(level))
+	var wg sync.WaitGroup
+	for i, s := range level {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, s step) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = s.op.Move()
+		}(i, s)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createOrgWhenRequired creates the missing target org, the project is
+// created next by createProjectWhenRequired
+func (o *Move) createOrgWhenRequired(sourceApi *services.SourceRequest, targetApi *services.TargetRequest, err error) error {
+
+	if !errors.Is(err, services.ErrEntityNotFound) || !o.Config.CreateOrg {
+		return nil
+	}
+	exists, err := targetApi.OrgExists(o.Target.Org)
+	if err != nil || exists {
+		return err
+	}
+
+	fmt.Println("Creating org in target...")
+	err = services.NewOrganizationOperation(sourceApi, targetApi, &services.SourceTarget{
+		SourceOrg: o.Source.Org,
+		TargetOrg: o.Target.Org,
+	}).Move()
+	if err != nil {
+		return err
+	}
+	fmt.Println(color.GreenString("Org %s created in target account", o.Target.Org))
 	return nil
 }
 
@@ -106,6 +440,7 @@ func (o *Move) createProjectWhenRequired(sourceApi *services.SourceRequest, targ
 				SourceProject: o.Source.Project,
 				TargetOrg:     o.Target.Org,
 				TargetProject: o.Target.Project,
+				ProjectAdmins: o.Config.ProjectAdmins,
 			}).Move()
 
 			if err == nil {
@@ -118,3 +453,47 @@ func (o *Move) createProjectWhenRequired(sourceApi *services.SourceRequest, targ
 	}
 	return err
 }
+
+// planSteps builds the dependency graph of the source entities, reports the
+// references missing in the target and groups the steps in levels, sorted by
+// their dependencies
+func (o *Move) planSteps(targetApi *services.TargetRequest, steps []step) ([][]step, error) {
+
+	fmt.Println("Scanning source references...")
+
+	graph := services.NewDependencyGraph()
+	for _, s := range steps {
+		if scanner, ok := s.op.(services.Scanner); ok {
+			// THE OPERATION REPORTS THE SAME ERROR WHEN IT RUNS
+			if err := scanner.Scan(graph); err != nil {
+				fmt.Println(color.YellowString("Unable to scan %s, its references are not checked: %s", s.entity, err.Error()))
+			}
+		}
+	}
+	services.ReportUnscanned(graph.Unscanned())
+
+	unresolved, err := graph.Unresolved(targetApi, o.Target.Org, o.Target.Project, o.Config.Mapping)
+	if err != nil {
+		return nil, err
+	}
+	services.ReportUnresolved(unresolved)
+
+	var types []services.EntityType
+	byType := map[services.EntityType]step{}
+	for _, s := range steps {
+		types = append(types, s.entity)
+		byType[s.entity] = s
+	}
+	levels, err := graph.TypeLevels(types)
+	if err != nil {
+		fmt.Println(color.YellowString("Keeping the default order: %s", err.Error()))
+	}
+
+	sorted := make([][]step, len(levels))
+	for i, level := range levels {
+		for _, t := range level {
+			sorted[i] = append(sorted[i], byType[t])
+		}
+	}
+	return sorted, nil
+}
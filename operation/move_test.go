@@ -1,9 +1,16 @@
 package operation
 
 import (
+	"encoding/json"
 	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 
+	"github.com/Fernando-Dourado/harness-move-project/model"
 	"github.com/Fernando-Dourado/harness-move-project/services"
+	"github.com/go-resty/resty/v2"
 	"github.com/stretchr/testify/assert"
 
 	"testing"
@@ -42,3 +49,119 @@ func TestCreateProjectWhenNotRequired_AndOtherError(t *testing.T) {
 
 	assert.Equal(t, expectedError, actualError)
 }
+
+func TestCreateOrgWhenNotRequired(t *testing.T) {
+
+	sourceApi := &services.SourceRequest{}
+	targetApi := &services.TargetRequest{}
+
+	move := Move{
+		Config: OperationConfig{
+			CreateOrg: false,
+		},
+	}
+
+	assert.NoError(t, move.createOrgWhenRequired(sourceApi, targetApi, services.ErrEntityNotFound))
+}
+
+// orgServer answers the org lookups from the given orgs and keeps the orgs
+// created through it
+func orgServer(orgs map[string]string, created *[]model.Organization) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/orgs":
+			request := model.CreateOrganizationRequest{}
+			json.NewDecoder(r.Body).Decode(&request)
+			*created = append(*created, *request.Org)
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodGet:
+			org := r.URL.Path[len("/ng/api/organizations/"):]
+			if name, found := orgs[org]; found {
+				w.Write([]byte(`{"status":"SUCCESS","data":{"organization":{"identifier":"` + org + `","name":"` + name + `","description":"platform team"}}}`))
+				return
+			}
+			w.Write([]byte(`{"status":"SUCCESS","data":null}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestCreateOrgWhenRequired_CreatesMissingOrg(t *testing.T) {
+
+	var created []model.Organization
+	server := orgServer(map[string]string{"platform": "Platform"}, &created)
+	defer server.Close()
+
+	sourceApi := &services.SourceRequest{Client: resty.New(), Url: server.URL}
+	targetApi := &services.TargetRequest{Client: resty.New(), Url: server.URL}
+
+	move := Move{
+		Source: CopyConfig{Org: "platform"},
+		Target: CopyConfig{Org: "platform_v2"},
+		Config: OperationConfig{
+			CreateOrg: true,
+		},
+	}
+
+	assert.NoError(t, move.createOrgWhenRequired(sourceApi, targetApi, services.ErrEntityNotFound))
+	assert.Equal(t, []model.Organization{
+		{Identifier: "platform_v2", Name: "Platform", Description: "platform team"},
+	}, created)
+}
+
+func TestCreateOrgWhenRequired_AndOrgExists(t *testing.T) {
+
+	var created []model.Organization
+	server := orgServer(map[string]string{"platform": "Platform", "platform_v2": "Platform V2"}, &created)
+	defer server.Close()
+
+	sourceApi := &services.SourceRequest{Client: resty.New(), Url: server.URL}
+	targetApi := &services.TargetRequest{Client: resty.New(), Url: server.URL}
+
+	move := Move{
+		Source: CopyConfig{Org: "platform"},
+		Target: CopyConfig{Org: "platform_v2"},
+		Config: OperationConfig{
+			CreateOrg: true,
+		},
+	}
+
+	// ONLY THE PROJECT IS MISSING, THE MOVE GOES ON WITHOUT CREATING THE ORG
+	assert.NoError(t, move.createOrgWhenRequired(sourceApi, targetApi, services.ErrEntityNotFound))
+	assert.Empty(t, created)
+}
+
+// fakeOperation runs the given function as the move of an entity type
+type fakeOperation func() error
+
+func (f fakeOperation) Move() error {
+	return f()
+}
+
+func TestRunLevels_WritesTokensWhenALaterLevelFails(t *testing.T) {
+
+	tokens := services.NewTokenFile()
+	move := Move{
+		Config: OperationConfig{
+			TokenFile: filepath.Join(t.TempDir(), "tokens.json"),
+		},
+	}
+	levels := [][]step{
+		{{services.EntityServiceAccount, fakeOperation(func() error {
+			tokens.Tokens = append(tokens.Tokens, services.MintedToken{ServiceAccount: "ci_bot", ApiKey: "deploy", Identifier: "deploy_moved", Token: "sat.xxx"})
+			return nil
+		})}},
+		{{services.EntityRoleAssignment, fakeOperation(func() error {
+			return errors.New("boom")
+		})}},
+	}
+
+	assert.EqualError(t, move.runLevels(levels, nil, tokens), "boom")
+
+	b, err := os.ReadFile(move.Config.TokenFile)
+	assert.NoError(t, err)
+	written := services.TokenFile{}
+	assert.NoError(t, json.Unmarshal(b, &written))
+	assert.Equal(t, tokens.Tokens, written.Tokens)
+}
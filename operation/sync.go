@@ -0,0 +1,67 @@
+package operation
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Fernando-Dourado/harness-move-project/services"
+	"github.com/fatih/color"
+)
+
+// syncClockSkew widens the window of the modified entities, so a source clock
+// behind the local one doesn't miss a change
+const syncClockSkew = time.Minute
+
+// Sync runs the move on an interval to keep the target project current. After
+// a clean run, only the entities modified since its start are read again.
+type Sync struct {
+	Move     *Move
+	Interval time.Duration
+	// Runs stops the sync after that many runs, it runs until stopped when
+	// zero
+	Runs int
+}
+
+func NewSync(mv *Move, interval time.Duration) *Sync {
+	return &Sync{
+		Move:     mv,
+		Interval: interval,
+	}
+}
+
+// Exec fails when the first run fails, the later failures are printed and
+// the entities are read again by the next run
+func (s *Sync) Exec() error {
+	for run := 1; ; run++ {
+		start := time.Now()
+		fmt.Println(color.CyanString("Sync run %d at %s", run, start.Format(time.RFC3339)))
+
+		err := s.Move.Exec()
+		switch {
+		case err != nil && run == 1:
+			return err
+		case err != nil:
+			fmt.Println(color.RedString(fmt.Sprint("Sync run failed: ", err.Error())))
+		case syncFailures(s.Move.Report) > 0:
+			// THE FAILED ENTITIES ARE NOT MODIFIED AGAIN, THE WINDOW IS KEPT
+			fmt.Println(color.YellowString("%d entities failed, the next run reads them again", syncFailures(s.Move.Report)))
+		default:
+			s.Move.Config.ModifiedSince = start.Add(-syncClockSkew).UnixMilli()
+		}
+
+		if s.Runs > 0 && run >= s.Runs {
+			return nil
+		}
+		if wait := s.Interval - time.Since(start); wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+}
+
+func syncFailures(report *services.Report) int {
+	failures := 0
+	for _, statuses := range report.Summary() {
+		failures += statuses[services.StatusFailed]
+	}
+	return failures
+}
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/Fernando-Dourado/harness-move-project/model"
 	"github.com/fatih/color"
@@ -16,8 +18,29 @@ const BaseURL = "https://app.harness.io"
 
 var (
 	ErrEntityNotFound = errors.New("entity not found")
+	ErrEntityExists   = errors.New("entity already exists")
 )
 
+// ConflictPolicy defines what happens when an entity already exists in the
+// target
+type ConflictPolicy string
+
+const (
+	ConflictSkip   ConflictPolicy = "skip"
+	ConflictUpdate ConflictPolicy = "update"
+	ConflictFail   ConflictPolicy = "fail"
+)
+
+func ParseConflictPolicy(name string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(strings.TrimSpace(name)); p {
+	case "":
+		return ConflictSkip, nil
+	case ConflictSkip, ConflictUpdate, ConflictFail:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown conflict policy %s, expected skip, update or fail", name)
+}
+
 type SourceRequest struct {
 	Client  *resty.Client
 	Token   string
@@ -32,11 +55,45 @@ type TargetRequest struct {
 	Url     string
 }
 
+// asSource reads the target with the requests written for the source
+func (t *TargetRequest) asSource() *SourceRequest {
+	s := SourceRequest(*t)
+	return &s
+}
+
 type SourceTarget struct {
 	SourceOrg     string
 	SourceProject string
 	TargetOrg     string
 	TargetProject string
+	Mapping       IdentifierMapping
+	Filter        EntityFilter
+	Report        *Report
+	OnConflict    ConflictPolicy
+	Principals    PrincipalMapping
+	// ProjectAdmins are made admins of the target project when the tool
+	// creates it, as "user:<email>", "usergroup:<id>" or "serviceaccount:<id>"
+	ProjectAdmins []string
+	// Tokens collects the tokens minted for the service accounts, none are
+	// minted when nil
+	Tokens *TokenFile
+	// State holds the content hash of the synced entities, every entity is
+	// synced when nil
+	State *SyncState
+	// ModifiedSince skips the source entities last modified before it, in
+	// epoch milliseconds, every entity is read when zero
+	ModifiedSince int64
+
+	resolverOnce sync.Once
+	resolver     *principalResolver
+}
+
+// principalResolver is shared by the operations, so the users are listed once
+func (st *SourceTarget) principalResolver(sourceApi *SourceRequest, targetApi *TargetRequest) *principalResolver {
+	st.resolverOnce.Do(func() {
+		st.resolver = newPrincipalResolver(sourceApi, targetApi, st.Principals)
+	})
+	return st.resolver
 }
 
 type Operation interface {
@@ -49,25 +106,89 @@ type OperationFactory interface {
 	NewVariableOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) VariableContext
 }
 
-func createYaml(yaml, sourceOrg, sourceProject, targetOrg, targetProject string) string {
-	var out string
+// createYaml sets the target org and project on the root entity of the YAML,
+// then applies the given transformations
+func createYaml(doc, targetOrg, targetProject string, transforms ...yamlTransform) (string, error) {
+	return transformYaml(doc, append([]yamlTransform{setScope(targetOrg, targetProject)}, transforms...)...)
+}
+
+// outcome is the result of copying one entity
+type outcome struct {
+	status  ReportStatus
+	message string
+	diff    string
+	// commit keeps the content hash of the entity, once it is synced
+	commit func()
+}
 
-	if strings.Contains(yaml, "orgIdentifier: ") {
-		out = strings.ReplaceAll(yaml, "orgIdentifier: "+sourceOrg, "orgIdentifier: "+targetOrg)
-	} else {
-		out = fmt.Sprintln(yaml, " orgIdentifier:", targetOrg)
+// outcomeIdentical is the message of the existing entities skipped because the
+// target already holds the source content
+const outcomeIdentical = "identical"
+
+// synced is true when the target holds the source content
+func (o outcome) synced() bool {
+	return o.status == StatusCreated || o.status == StatusUpdated || (o.status == StatusSkipped && o.message == outcomeIdentical)
+}
+
+// upsert creates the entity and follows the policy when it already exists in
+// the target. The compare function returns the diff between the target and the
+// source entity, identical entities are always skipped. Entities without
+// update are skipped.
+func (p ConflictPolicy) upsert(create, update func() error, compare func() (string, error)) (outcome, error) {
+	err := create()
+	if !errors.Is(err, ErrEntityExists) {
+		if err != nil {
+			return outcome{status: StatusFailed}, err
+		}
+		return outcome{status: StatusCreated}, nil
 	}
 
-	if strings.Contains(yaml, "projectIdentifier: ") {
-		out = strings.ReplaceAll(out, "projectIdentifier: "+sourceProject, "projectIdentifier: "+targetProject)
-	} else {
-		out = fmt.Sprintln(yaml, " projectIdentifier:", targetProject)
+	var diff string
+	if compare != nil {
+		var cmpErr error
+		diff, cmpErr = compare()
+		if errors.Is(cmpErr, ErrEntityNotFound) {
+			return outcome{status: StatusCollision}, errors.New("the name is used by another entity in the target")
+		}
+		if cmpErr != nil {
+			return outcome{status: StatusFailed}, fmt.Errorf("comparing with the target: %w", cmpErr)
+		}
+		if len(diff) == 0 {
+			return outcome{status: StatusSkipped, message: outcomeIdentical}, nil
+		}
 	}
 
-	return out
+	switch p {
+	case ConflictFail:
+		return outcome{status: StatusFailed, diff: diff}, err
+	case ConflictUpdate:
+		if update != nil {
+			if err := update(); err != nil {
+				return outcome{status: StatusFailed, diff: diff}, err
+			}
+			return outcome{status: StatusUpdated, diff: diff}, nil
+		}
+	}
+	if len(diff) > 0 {
+		return outcome{status: StatusConflict, diff: diff}, nil
+	}
+	return outcome{status: StatusSkipped}, nil
 }
 
+// handleErrorResponse ignores the duplicated entities, use handleCreateResponse
+// when the conflict matters
 func handleErrorResponse(resp *resty.Response) error {
+	err := handleCreateResponse(resp)
+	if errors.Is(err, ErrEntityExists) {
+		return nil
+	}
+	return err
+}
+
+func handleCreateResponse(resp *resty.Response) error {
+	if resp.StatusCode() == http.StatusConflict {
+		return ErrEntityExists
+	}
 	result := model.ErrorResponse{}
 	err := json.Unmarshal(resp.Body(), &result)
 	if err != nil {
@@ -77,10 +198,10 @@ func handleErrorResponse(resp *resty.Response) error {
 		return ErrEntityNotFound
 	}
 	if result.Code == "DUPLICATE_FIELD" {
-		return nil
+		return ErrEntityExists
 	}
 	if strings.Contains(result.Message, "already exists") {
-		return nil
+		return ErrEntityExists
 	}
 	return fmt.Errorf("%s: %s", result.Code, removeNewLine(result.Message))
 }
@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/Fernando-Dourado/harness-move-project/model"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -12,8 +13,9 @@ const MISSING_ORG_YAML = "service:\n  name: pi-svc\n  identifier: pisvc\n  servi
 const MISSING_PROJECT_YAML = "service:\n  name: pi-svc\n  identifier: pisvc\n  serviceDefinition:\n    type: Kubernetes\n    spec:\n      manifests:\n        - manifest:\n            identifier: job\n            type: K8sManifest\n            spec:\n              store:\n                type: Harness\n                spec:\n                  files:\n                    - /pi-svc/job.yaml\n              skipResourceVersioning: false\n              enableDeclarativeRollback: false\n  gitOpsEnabled: false\n  orgIdentifier: default\n"
 
 func TestCreateYaml(t *testing.T) {
-	yaml := createYaml(TEST_VALID_PIPELINE_YAML, "default", "FernandoD", "non_default", "DouradoF")
+	yaml, err := createYaml(TEST_VALID_PIPELINE_YAML, "non_default", "DouradoF")
 
+	assert.NoError(t, err)
 	assert.True(t, strings.Contains(yaml, "orgIdentifier: non_default"), "The orgIdentifier not replaced")
 	assert.True(t, strings.Contains(yaml, "projectIdentifier: DouradoF"), "The projectIdentifier not replaced")
 }
@@ -21,17 +23,33 @@ func TestCreateYaml(t *testing.T) {
 func TestCreateYaml_MissingOrg(t *testing.T) {
 	expectedOrg := "  orgIdentifier: non_default\n"
 
-	yaml := createYaml(MISSING_ORG_YAML, "default", "FernandoD", "non_default", "DouradoF")
+	yaml, err := createYaml(MISSING_ORG_YAML, "non_default", "DouradoF")
+	assert.NoError(t, err)
 	assert.True(t, strings.Contains(yaml, expectedOrg), "The orgIdentifier is missing")
 }
 
 func TestCreateYaml_MissingProject(t *testing.T) {
 	expectedProject := "  projectIdentifier: DouradoF\n"
 
-	yaml := createYaml(MISSING_PROJECT_YAML, "default", "FernandoD", "non_default", "DouradoF")
+	yaml, err := createYaml(MISSING_PROJECT_YAML, "non_default", "DouradoF")
+	assert.NoError(t, err)
 	assert.True(t, strings.Contains(yaml, expectedProject), "The projectIdentifier is missing")
 }
 
+func TestCreateYaml_OnlyRootEntity(t *testing.T) {
+	expected := "pipeline:\n  identifier: p1\n  orgIdentifier: non_default\n  projectIdentifier: DouradoF\n  stages:\n    - stage:\n        spec:\n          script: |-\n            echo \"orgIdentifier: default\"\n"
+
+	yaml, err := createYaml("pipeline:\n  identifier: p1\n  orgIdentifier: \"default\"\n  projectIdentifier: 'FernandoD'\n  stages:\n    - stage:\n        spec:\n          script: |-\n            echo \"orgIdentifier: default\"\n", "non_default", "DouradoF")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, yaml)
+}
+
+func TestCreateYaml_FlowStyle(t *testing.T) {
+	yaml, err := createYaml("service: {name: svc, identifier: svc, orgIdentifier: default}\n", "non_default", "DouradoF")
+	assert.NoError(t, err)
+	assert.Equal(t, "service: {name: svc, identifier: svc, orgIdentifier: non_default, projectIdentifier: DouradoF}\n", yaml)
+}
+
 func TestRemoveNewLine(t *testing.T) {
 	v1 := "There are no eligible delegates available in the account to execute the task.\n\n\n"
 	v2 := removeNewLine(v1)
@@ -40,3 +58,73 @@ func TestRemoveNewLine(t *testing.T) {
 	v3 := removeNewLine(v2)
 	assert.Equal(t, v2, v3)
 }
+
+func TestConflictPolicyUpsert(t *testing.T) {
+	exists := func() error { return ErrEntityExists }
+	updated := false
+	update := func() error { updated = true; return nil }
+
+	result, err := ConflictSkip.upsert(exists, update, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSkipped, result.status)
+	assert.False(t, updated)
+
+	result, err = ConflictFail.upsert(exists, update, nil)
+	assert.ErrorIs(t, err, ErrEntityExists)
+	assert.Equal(t, StatusFailed, result.status)
+
+	result, err = ConflictUpdate.upsert(exists, update, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusUpdated, result.status)
+	assert.True(t, updated)
+
+	result, err = ConflictUpdate.upsert(exists, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSkipped, result.status)
+
+	result, err = ConflictFail.upsert(func() error { return nil }, update, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCreated, result.status)
+}
+
+func TestConflictPolicyUpsert_Compare(t *testing.T) {
+	exists := func() error { return ErrEntityExists }
+	identical := func() (string, error) { return "", nil }
+	differs := func() (string, error) { return "-a\n+b\n", nil }
+	collision := func() (string, error) { return "", ErrEntityNotFound }
+
+	result, err := ConflictFail.upsert(exists, nil, identical)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSkipped, result.status)
+
+	result, err = ConflictSkip.upsert(exists, nil, differs)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusConflict, result.status)
+	assert.Equal(t, "-a\n+b\n", result.diff)
+
+	result, err = ConflictSkip.upsert(exists, nil, collision)
+	assert.Error(t, err)
+	assert.Equal(t, StatusCollision, result.status)
+}
+
+func TestDiffResponse_IgnoresServerFields(t *testing.T) {
+	body := []byte(`{"identifier":"deny","name":"Deny","rego":"package x","created":1700000000,"org_id":"default"}`)
+
+	diff, err := diffResponse(EntityPolicy, body, &model.Policy{Identifier: "deny", Name: "Deny", Rego: "package x"})
+	assert.NoError(t, err)
+	assert.Empty(t, diff)
+
+	diff, err = diffResponse(EntityPolicy, body, &model.Policy{Identifier: "deny", Name: "Deny", Rego: "package y"})
+	assert.NoError(t, err)
+	assert.Contains(t, diff, "+rego: package y")
+}
+
+func TestDiffEntities(t *testing.T) {
+	diff, err := diffEntities("service:\n  name: svc\n  tags: {}\n  identifier: svc\n", "service: {identifier: svc, name: svc}")
+	assert.NoError(t, err)
+	assert.Empty(t, diff)
+
+	diff, err = diffEntities(map[string]interface{}{"name": "a", "value": "1"}, map[string]interface{}{"name": "a", "value": "2"})
+	assert.NoError(t, err)
+	assert.Equal(t, "--- target\n+++ source\n@@ -1,2 +1,2 @@\n name: a\n-value: \"1\"\n+value: \"2\"\n", diff)
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+)
+
+// SourceCache keeps the source responses, so the moves of the same source
+// project to several targets read it once. Only the successful responses are
+// kept, the source is never written by the operations. It is safe for
+// concurrent use.
+type SourceCache struct {
+	mu        sync.Mutex
+	responses map[string][]byte
+}
+
+func NewSourceCache() *SourceCache {
+	return &SourceCache{responses: map[string][]byte{}}
+}
+
+// Transport answers the requests already sent from the cache, the others are
+// sent with next
+func (c *SourceCache) Transport(next http.RoundTripper) http.RoundTripper {
+	return &cachingTransport{cache: c, next: next}
+}
+
+type cachingTransport struct {
+	cache *SourceCache
+	next  http.RoundTripper
+}
+
+func (t *cachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key, err := cacheKey(req)
+	if err != nil {
+		return nil, err
+	}
+
+	t.cache.mu.Lock()
+	dump, found := t.cache.responses[key]
+	t.cache.mu.Unlock()
+	if found {
+		return http.ReadResponse(bufio.NewReader(bytes.NewReader(dump)), req)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp, err
+	}
+	if dump, err = httputil.DumpResponse(resp, true); err != nil {
+		return nil, err
+	}
+	t.cache.mu.Lock()
+	t.cache.responses[key] = dump
+	t.cache.mu.Unlock()
+	return resp, nil
+}
+
+// cacheKey is the method, URL and body of the request. The body is restored
+// for the next transport.
+func cacheKey(req *http.Request) (string, error) {
+	key := req.Method + " " + req.URL.String()
+	if req.Body == nil || req.Body == http.NoBody {
+		return key, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return key + " " + string(body), nil
+}
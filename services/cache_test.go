@@ -0,0 +1,46 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceCache(t *testing.T) {
+	hits := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path]++
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"status":"SUCCESS","path":"` + r.URL.Path + `"}`))
+	}))
+	defer server.Close()
+
+	cache := NewSourceCache()
+	for i := 0; i < 2; i++ {
+		// EVERY MOVE HAS ITS OWN CLIENT
+		client := resty.New().SetTransport(cache.Transport(http.DefaultTransport))
+
+		resp, err := client.R().SetQueryParam("orgIdentifier", "default").Get(server.URL + "/services")
+		assert.NoError(t, err)
+		assert.Equal(t, `{"status":"SUCCESS","path":"/services"}`, string(resp.Body()))
+
+		for _, filter := range []string{`{"filterType":"PipelineSetup"}`, `{"filterType":"Other"}`} {
+			resp, err = client.R().SetBody(filter).Post(server.URL + "/pipelines")
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode())
+		}
+
+		resp, err = client.R().Get(server.URL + "/missing")
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode())
+	}
+
+	// THE FAILED RESPONSES ARE NOT KEPT
+	assert.Equal(t, map[string]int{"/services": 1, "/pipelines": 2, "/missing": 2}, hits)
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/yaml.v3"
+)
+
+// comparePaths locate the part of the target response compared with the
+// source entity
+var comparePaths = map[EntityType][]string{
+	EntityConnector:        {"data", "connector"},
+	EntitySecret:           {"data", "secret"},
+	EntityTemplate:         {"data", "yaml"},
+	EntityService:          {"data", "service", "yaml"},
+	EntityEnvironment:      {"data", "environment", "yaml"},
+	EntityInfrastructure:   {"data", "infrastructure", "yaml"},
+	EntityPipeline:         {"data", "yamlPipeline"},
+	EntityVariable:         {"data", "variable"},
+	EntityInputset:         {"data", "inputSetYaml"},
+	EntityOverrideV2:       {"data", "yaml"},
+	EntityRole:             {"data", "role"},
+	EntityResourceGroup:    {"data", "resourceGroup"},
+	EntityUserGroup:        {"data"},
+	EntityServiceAccount:   {"data"},
+	EntityFreeze:           {"data", "yaml"},
+	EntityEnvironmentGroup: {"data", "envGroup", "yaml"},
+	EntityMonitoredService: {"data", "monitoredService"},
+	EntitySLO:              {"resource", "serviceLevelObjectiveV2"},
+	EntityTargetGroup:      {},
+	EntityFeatureFlag:      {},
+}
+
+// compareTarget reads the entity from the target and returns the unified diff
+// to the source, empty when both are the same. The source is either a YAML
+// document or a value encoded as JSON. ErrEntityNotFound means no target
+// entity uses the identifier.
+func (t *TargetRequest) compareTarget(ref EntityRef, org, project string, params map[string]string, source interface{}) (string, error) {
+	body, err := t.getEntity(ref, org, project, params)
+	if err != nil {
+		return "", err
+	}
+	return diffResponse(ref.Type, body, source)
+}
+
+// diffResponse compares the entity in the target response with the source.
+// When the source is a struct, the target is decoded into the same type, so
+// the fields set by the server and not copied by the tool are ignored.
+func diffResponse(entityType EntityType, body []byte, source interface{}) (string, error) {
+	var target interface{}
+	if err := json.Unmarshal(body, &target); err != nil {
+		return "", err
+	}
+	for _, key := range comparePaths[entityType] {
+		m, ok := target.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("unexpected %s response", entityType)
+		}
+		target = m[key]
+	}
+	if t := reflect.TypeOf(source); t != nil && t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct {
+		b, err := json.Marshal(target)
+		if err != nil {
+			return "", err
+		}
+		typed := reflect.New(t.Elem()).Interface()
+		if err := json.Unmarshal(b, typed); err != nil {
+			return "", err
+		}
+		target = typed
+	}
+	return diffEntities(target, source)
+}
+
+// diffEntities normalizes both entities and returns their unified diff
+func diffEntities(target, source interface{}) (string, error) {
+	a, err := normalizeEntity(target)
+	if err != nil {
+		return "", err
+	}
+	b, err := normalizeEntity(source)
+	if err != nil {
+		return "", err
+	}
+	if a == b {
+		return "", nil
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(strings.TrimSuffix(a, "\n")),
+		B:        difflib.SplitLines(strings.TrimSuffix(b, "\n")),
+		FromFile: "target",
+		ToFile:   "source",
+		Context:  3,
+	})
+}
+
+// normalizeEntity encodes the entity as YAML with sorted keys and without the
+// empty values, so formatting and defaults don't show as differences
+func normalizeEntity(v interface{}) (string, error) {
+	var data interface{}
+	switch value := v.(type) {
+	case string:
+		if err := yaml.Unmarshal([]byte(value), &data); err != nil {
+			return "", err
+		}
+	default:
+		b, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		if err := json.Unmarshal(b, &data); err != nil {
+			return "", err
+		}
+	}
+	b, err := yaml.Marshal(pruneEmpty(data))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func pruneEmpty(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		out := map[string]interface{}{}
+		for k, item := range value {
+			if item = pruneEmpty(item); item != nil {
+				out[k] = item
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+	case []interface{}:
+		var out []interface{}
+		for _, item := range value {
+			if item = pruneEmpty(item); item != nil {
+				out = append(out, item)
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+	case string:
+		if len(value) == 0 {
+			return nil
+		}
+	}
+	return v
+}
+
+// compareWith returns the compare function of upsert for a project entity
+func (t *TargetRequest) compareWith(entityType EntityType, identifier, org, project string, params map[string]string, source interface{}) func() (string, error) {
+	return t.compareRef(EntityRef{Type: entityType, Scope: ScopeProject, Identifier: identifier}, org, project, params, source)
+}
+
+// compareRef returns the compare function of upsert for an entity of any scope
+func (t *TargetRequest) compareRef(ref EntityRef, org, project string, params map[string]string, source interface{}) func() (string, error) {
+	return func() (string, error) {
+		return t.compareTarget(ref, org, project, params, source)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/Fernando-Dourado/harness-move-project/model"
 	"github.com/harness/harness-go-sdk/harness/nextgen"
 	"github.com/schollz/progressbar/v3"
+	"gopkg.in/yaml.v3"
 )
 
 type ConnectorContext struct {
@@ -16,6 +17,11 @@ type ConnectorContext struct {
 	sourceProject string
 	targetOrg     string
 	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
 }
 
 func NewConnectorOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) ConnectorContext {
@@ -26,6 +32,11 @@ func NewConnectorOperation(sourceApi *SourceRequest, targetApi *TargetRequest, s
 		sourceProject: st.SourceProject,
 		targetOrg:     st.TargetOrg,
 		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
 	}
 }
 
@@ -40,15 +51,35 @@ func (c ConnectorContext) Move() error {
 	var failed []string
 
 	for _, conn := range connectors {
-		conn.OrgIdentifier = c.targetOrg
-		conn.ProjectIdentifier = c.targetProject
+		if !c.filter.Match(EntityConnector, conn.Identifier) {
+			bar.Add(1)
+			continue
+		}
+		if reused, found := c.mapping.Reused(EntityConnector, conn.Identifier); found {
+			c.report.recordReused(EntityConnector, conn.Identifier, conn.Name, reused)
+			bar.Add(1)
+			continue
+		}
+		sourceId, name := conn.Identifier, conn.Name
+		result := outcome{status: StatusFailed}
+		conn, err = c.mapping.connector(conn)
+		if err == nil {
+			conn.OrgIdentifier = c.targetOrg
+			conn.ProjectIdentifier = c.targetProject
 
-		err = c.createConnector(&model.CreateConnectorRequest{
-			Connector: conn,
-		})
+			request := &model.CreateConnectorRequest{
+				Connector: conn,
+			}
+			result, err = c.state.upsert(c.onConflict, EntityConnector, sourceId, conn,
+				func() error { return c.createConnector(request) },
+				func() error { return c.updateConnector(request) },
+				c.target.compareWith(EntityConnector, conn.Identifier, c.targetOrg, c.targetProject, nil, conn),
+			)
+		}
 		if err != nil {
-			failed = append(failed, fmt.Sprintln(conn.Name, "-", err.Error()))
+			failed = append(failed, fmt.Sprintln(name, "-", err.Error()))
 		}
+		c.report.recordOutcome(EntityConnector, sourceId, name, result, err)
 		bar.Add(1)
 	}
 	bar.Finish()
@@ -57,6 +88,409 @@ func (c ConnectorContext) Move() error {
 	return nil
 }
 
+func (c ConnectorContext) Scan(g *DependencyGraph) error {
+
+	connectors, err := c.listConnectors(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, conn := range connectors {
+		if !c.filter.Match(EntityConnector, conn.Identifier) || c.mapping.reused(EntityConnector, conn.Identifier) {
+			continue
+		}
+		refs, err := extractConnectorReferences(conn)
+		if err != nil {
+			g.AddUnscanned(EntityConnector, conn.Identifier, conn.Name, err)
+			continue
+		}
+		g.Add(EntityConnector, conn.Identifier, conn.Name, refs)
+	}
+	return nil
+}
+
+func (c ConnectorContext) Verify(v *Verification) error {
+
+	connectors, err := c.listConnectors(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	target := c
+	target.source = c.target.asSource()
+	targetConnectors, err := target.listConnectors(c.targetOrg, c.targetProject)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, conn := range connectors {
+		if !c.filter.Match(EntityConnector, conn.Identifier) || c.mapping.reused(EntityConnector, conn.Identifier) {
+			continue
+		}
+		count++
+		sourceId, name := conn.Identifier, conn.Name
+		mapped, err := c.mapping.connector(conn)
+		if err != nil {
+			v.failed(EntityConnector, sourceId, name, err)
+			continue
+		}
+		mapped.OrgIdentifier = c.targetOrg
+		mapped.ProjectIdentifier = c.targetProject
+		v.check(EntityConnector, sourceId, name, c.target.verifyWith(EntityConnector, mapped.Identifier, c.targetOrg, c.targetProject, nil, mapped, false))
+	}
+	v.count(EntityConnector, count, len(targetConnectors))
+	return nil
+}
+
+// Export describes the GitHub, Docker and Kubernetes connectors, the provider
+// has one resource by connector type
+func (c ConnectorContext) Export(tf *TerraformExport) error {
+
+	connectors, err := c.listConnectors(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, conn := range connectors {
+		if !c.filter.Match(EntityConnector, conn.Identifier) || c.mapping.reused(EntityConnector, conn.Identifier) {
+			continue
+		}
+		name := conn.Name
+		if conn, err = c.mapping.connector(conn); err != nil {
+			return fmt.Errorf("connector %s: %w", name, err)
+		}
+		root, err := connectorNode(conn)
+		if err != nil {
+			return fmt.Errorf("connector %s: %w", name, err)
+		}
+		spec := mappingValue(root.Content[0], "spec")
+
+		var kind string
+		var attrs []tfAttr
+		switch conn.Type_ {
+		case nextgen.ConnectorTypes.Github:
+			kind, attrs = "harness_platform_connector_github", githubConnectorAttrs(spec)
+		case nextgen.ConnectorTypes.DockerRegistry:
+			kind, attrs = "harness_platform_connector_docker", dockerConnectorAttrs(spec)
+		case nextgen.ConnectorTypes.K8sCluster:
+			kind, attrs = "harness_platform_connector_kubernetes", kubernetesConnectorAttrs(spec)
+		case nextgen.ConnectorTypes.Git:
+			kind, attrs = "harness_platform_connector_git", gitConnectorAttrs(spec)
+		case nextgen.ConnectorTypes.Gitlab:
+			kind, attrs = "harness_platform_connector_gitlab", gitlabConnectorAttrs(spec)
+		case nextgen.ConnectorTypes.Bitbucket:
+			kind, attrs = "harness_platform_connector_bitbucket", bitbucketConnectorAttrs(spec)
+		case nextgen.ConnectorTypes.Artifactory:
+			kind, attrs = "harness_platform_connector_artifactory", artifactoryConnectorAttrs(spec)
+		case nextgen.ConnectorTypes.Aws:
+			kind, attrs = "harness_platform_connector_aws", awsConnectorAttrs(spec)
+		case nextgen.ConnectorTypes.Gcp:
+			kind, attrs = "harness_platform_connector_gcp", gcpConnectorAttrs(spec)
+		}
+		if attrs == nil {
+			tf.unsupported(EntityConnector, name, fmt.Sprintf("connector type %s or its authentication is not supported", conn.Type_))
+			continue
+		}
+		tf.add(EntityConnector, conn.Identifier, kind, conn.Identifier, tf.importId(conn.Identifier), tf.entity(conn.Identifier, name,
+			append([]tfAttr{
+				tfString("description", conn.Description),
+				tfTags(conn.Tags),
+			}, attrs...)...,
+		)...)
+	}
+	return nil
+}
+
+func githubConnectorAttrs(spec *yaml.Node) []tfAttr {
+	var credentials tfAttr
+	auth := mappingValue(spec, "authentication")
+	switch nodeValue(auth, "type") {
+	case "Http":
+		if nodeValue(auth, "spec", "type") != "UsernameToken" {
+			return nil
+		}
+		credentials = tfNested("credentials", tfNested("http",
+			tfString("username", nodeValue(auth, "spec", "spec", "username")),
+			tfReference("token_ref", EntitySecret, nodeValue(auth, "spec", "spec", "tokenRef")),
+		))
+	case "Ssh":
+		credentials = tfNested("credentials", tfNested("ssh",
+			tfReference("ssh_key_ref", EntitySecret, nodeValue(auth, "spec", "sshKeyRef")),
+		))
+	default:
+		return nil
+	}
+	attrs := []tfAttr{
+		tfString("url", nodeValue(spec, "url")),
+		tfString("connection_type", nodeValue(spec, "type")),
+		tfString("validation_repo", nodeValue(spec, "validationRepo")),
+		tfStrings("delegate_selectors", nodeValues(spec, "delegateSelectors")),
+		tfBool("execute_on_delegate", nodeValue(spec, "executeOnDelegate") == "true"),
+		credentials,
+	}
+	if api := mappingValue(spec, "apiAccess"); nodeValue(api, "type") == "Token" {
+		attrs = append(attrs, tfNested("api_authentication",
+			tfReference("token_ref", EntitySecret, nodeValue(api, "spec", "tokenRef")),
+		))
+	}
+	return attrs
+}
+
+func dockerConnectorAttrs(spec *yaml.Node) []tfAttr {
+	attrs := []tfAttr{
+		tfString("type", nodeValue(spec, "providerType")),
+		tfString("url", nodeValue(spec, "dockerRegistryUrl")),
+		tfStrings("delegate_selectors", nodeValues(spec, "delegateSelectors")),
+	}
+	switch nodeValue(spec, "auth", "type") {
+	case "Anonymous":
+		return attrs
+	case "UsernamePassword":
+		return append(attrs, tfNested("credentials",
+			tfString("username", nodeValue(spec, "auth", "spec", "username")),
+			tfReference("password_ref", EntitySecret, nodeValue(spec, "auth", "spec", "passwordRef")),
+		))
+	}
+	return nil
+}
+
+func kubernetesConnectorAttrs(spec *yaml.Node) []tfAttr {
+	selectors := nodeValues(spec, "delegateSelectors")
+	credential := mappingValue(spec, "credential")
+	switch nodeValue(credential, "type") {
+	case "InheritFromDelegate":
+		return []tfAttr{tfNested("inherit_from_delegate", tfStrings("delegate_selectors", selectors))}
+	case "ManualConfig":
+		if nodeValue(credential, "spec", "auth", "type") != "ServiceAccount" {
+			return nil
+		}
+		return []tfAttr{
+			tfStrings("delegate_selectors", selectors),
+			tfNested("service_account",
+				tfString("master_url", nodeValue(credential, "spec", "masterUrl")),
+				tfReference("service_account_token_ref", EntitySecret, nodeValue(credential, "spec", "auth", "spec", "serviceAccountTokenRef")),
+			),
+		}
+	}
+	return nil
+}
+
+// gitConnectorAttrs describes a generic Git connector, its credentials are
+// in the spec itself
+func gitConnectorAttrs(spec *yaml.Node) []tfAttr {
+	var credentials tfAttr
+	switch nodeValue(spec, "type") {
+	case "Http":
+		credentials = tfNested("credentials", tfNested("http",
+			tfString("username", nodeValue(spec, "spec", "username")),
+			tfReference("password_ref", EntitySecret, nodeValue(spec, "spec", "passwordRef")),
+		))
+	case "Ssh":
+		credentials = tfNested("credentials", tfNested("ssh",
+			tfReference("ssh_key_ref", EntitySecret, nodeValue(spec, "spec", "sshKeyRef")),
+		))
+	default:
+		return nil
+	}
+	return []tfAttr{
+		tfString("url", nodeValue(spec, "url")),
+		tfString("connection_type", nodeValue(spec, "connectionType")),
+		tfString("validation_repo", nodeValue(spec, "validationRepo")),
+		tfStrings("delegate_selectors", nodeValues(spec, "delegateSelectors")),
+		tfBool("execute_on_delegate", nodeValue(spec, "executeOnDelegate") == "true"),
+		credentials,
+	}
+}
+
+func gitlabConnectorAttrs(spec *yaml.Node) []tfAttr {
+	var credentials tfAttr
+	auth := mappingValue(spec, "authentication")
+	switch nodeValue(auth, "type") {
+	case "Http":
+		var secret tfAttr
+		switch nodeValue(auth, "spec", "type") {
+		case "UsernameToken":
+			secret = tfReference("token_ref", EntitySecret, nodeValue(auth, "spec", "spec", "tokenRef"))
+		case "UsernamePassword":
+			secret = tfReference("password_ref", EntitySecret, nodeValue(auth, "spec", "spec", "passwordRef"))
+		default:
+			return nil
+		}
+		credentials = tfNested("credentials", tfNested("http",
+			tfString("username", nodeValue(auth, "spec", "spec", "username")),
+			secret,
+		))
+	case "Ssh":
+		credentials = tfNested("credentials", tfNested("ssh",
+			tfReference("ssh_key_ref", EntitySecret, nodeValue(auth, "spec", "sshKeyRef")),
+		))
+	default:
+		return nil
+	}
+	attrs := []tfAttr{
+		tfString("url", nodeValue(spec, "url")),
+		tfString("connection_type", nodeValue(spec, "type")),
+		tfString("validation_repo", nodeValue(spec, "validationRepo")),
+		tfStrings("delegate_selectors", nodeValues(spec, "delegateSelectors")),
+		tfBool("execute_on_delegate", nodeValue(spec, "executeOnDelegate") == "true"),
+		credentials,
+	}
+	if api := mappingValue(spec, "apiAccess"); nodeValue(api, "type") == "Token" {
+		attrs = append(attrs, tfNested("api_authentication",
+			tfReference("token_ref", EntitySecret, nodeValue(api, "spec", "tokenRef")),
+		))
+	}
+	return attrs
+}
+
+func bitbucketConnectorAttrs(spec *yaml.Node) []tfAttr {
+	var credentials tfAttr
+	auth := mappingValue(spec, "authentication")
+	switch nodeValue(auth, "type") {
+	case "Http":
+		if nodeValue(auth, "spec", "type") != "UsernamePassword" {
+			return nil
+		}
+		credentials = tfNested("credentials", tfNested("http",
+			tfString("username", nodeValue(auth, "spec", "spec", "username")),
+			tfReference("password_ref", EntitySecret, nodeValue(auth, "spec", "spec", "passwordRef")),
+		))
+	case "Ssh":
+		credentials = tfNested("credentials", tfNested("ssh",
+			tfReference("ssh_key_ref", EntitySecret, nodeValue(auth, "spec", "sshKeyRef")),
+		))
+	default:
+		return nil
+	}
+	attrs := []tfAttr{
+		tfString("url", nodeValue(spec, "url")),
+		tfString("connection_type", nodeValue(spec, "type")),
+		tfString("validation_repo", nodeValue(spec, "validationRepo")),
+		tfStrings("delegate_selectors", nodeValues(spec, "delegateSelectors")),
+		credentials,
+	}
+	if api := mappingValue(spec, "apiAccess"); nodeValue(api, "type") == "UsernameToken" {
+		attrs = append(attrs, tfNested("api_authentication",
+			tfString("username", nodeValue(api, "spec", "username")),
+			tfReference("token_ref", EntitySecret, nodeValue(api, "spec", "tokenRef")),
+		))
+	}
+	return attrs
+}
+
+func artifactoryConnectorAttrs(spec *yaml.Node) []tfAttr {
+	attrs := []tfAttr{
+		tfString("url", nodeValue(spec, "artifactoryServerUrl")),
+		tfStrings("delegate_selectors", nodeValues(spec, "delegateSelectors")),
+	}
+	switch nodeValue(spec, "auth", "type") {
+	case "Anonymous":
+		return attrs
+	case "UsernamePassword":
+		return append(attrs, tfNested("credentials",
+			tfString("username", nodeValue(spec, "auth", "spec", "username")),
+			tfReference("password_ref", EntitySecret, nodeValue(spec, "auth", "spec", "passwordRef")),
+		))
+	}
+	return nil
+}
+
+func awsConnectorAttrs(spec *yaml.Node) []tfAttr {
+	selectors := tfStrings("delegate_selectors", nodeValues(spec, "delegateSelectors"))
+	credential := mappingValue(spec, "credential")
+	region := tfString("region", nodeValue(credential, "region"))
+
+	var auth tfAttr
+	switch nodeValue(credential, "type") {
+	case "ManualConfig":
+		auth = tfNested("manual", tfOptional(
+			tfString("access_key", nodeValue(credential, "spec", "accessKey")),
+			tfReference("access_key_ref", EntitySecret, nodeValue(credential, "spec", "accessKeyRef")),
+			tfReference("secret_key_ref", EntitySecret, nodeValue(credential, "spec", "secretKeyRef")),
+			selectors,
+			region,
+		)...)
+	case "InheritFromDelegate":
+		auth = tfNested("inherit_from_delegate", tfOptional(selectors, region)...)
+	case "Irsa":
+		auth = tfNested("irsa", tfOptional(selectors, region)...)
+	default:
+		return nil
+	}
+	attrs := []tfAttr{
+		tfBool("execute_on_delegate", nodeValue(spec, "executeOnDelegate") == "true"),
+		auth,
+	}
+	if cross := mappingValue(credential, "crossAccountAccess"); cross != nil {
+		attrs = append(attrs, tfNested("cross_account_access", tfOptional(
+			tfString("role_arn", nodeValue(cross, "crossAccountRoleArn")),
+			tfString("external_id", nodeValue(cross, "externalId")),
+		)...))
+	}
+	return attrs
+}
+
+func gcpConnectorAttrs(spec *yaml.Node) []tfAttr {
+	selectors := tfStrings("delegate_selectors", nodeValues(spec, "delegateSelectors"))
+	credential := mappingValue(spec, "credential")
+
+	var auth tfAttr
+	switch nodeValue(credential, "type") {
+	case "ManualConfig":
+		auth = tfNested("manual", tfOptional(
+			tfReference("secret_key_ref", EntitySecret, nodeValue(credential, "spec", "secretKeyRef")),
+			selectors,
+		)...)
+	case "InheritFromDelegate":
+		auth = tfNested("inherit_from_delegate", selectors)
+	default:
+		return nil
+	}
+	return []tfAttr{
+		tfBool("execute_on_delegate", nodeValue(spec, "executeOnDelegate") == "true"),
+		auth,
+	}
+}
+
+// nodeValue returns the scalar found following the keys, or an empty string
+func nodeValue(n *yaml.Node, keys ...string) string {
+	for _, key := range keys {
+		n = mappingValue(n, key)
+	}
+	if n == nil || n.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return n.Value
+}
+
+// nodeValues returns the scalars of the sequence found following the keys
+func nodeValues(n *yaml.Node, keys ...string) []string {
+	for _, key := range keys {
+		n = mappingValue(n, key)
+	}
+	var values []string
+	if n != nil && n.Kind == yaml.SequenceNode {
+		for _, item := range n.Content {
+			values = append(values, item.Value)
+		}
+	}
+	return values
+}
+
+func (c ConnectorContext) Prune() error {
+
+	connectors, err := c.listConnectors(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for _, conn := range connectors {
+		ids = append(ids, conn.Identifier)
+	}
+	c.state.prune(EntityConnector, ids, c.report, func(sourceId string) error {
+		return c.target.deleteSynced(c.mapping, EntityConnector, sourceId, c.targetOrg, c.targetProject)
+	})
+	return nil
+}
+
 func (c ConnectorContext) listConnectors(org, project string) ([]*nextgen.ConnectorInfo, error) {
 
 	api := c.source
@@ -111,6 +545,27 @@ func (c ConnectorContext) createConnector(connector *model.CreateConnectorReques
 	if err != nil {
 		return err
 	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
+
+func (c ConnectorContext) updateConnector(connector *model.CreateConnectorRequest) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(connector).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+		}).
+		Put(api.Url + "/ng/api/connectors")
+	if err != nil {
+		return err
+	}
 	if resp.IsError() {
 		return handleErrorResponse(resp)
 	}
@@ -18,6 +18,11 @@ type EnvironmentContext struct {
 	sourceProject string
 	targetOrg     string
 	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
 }
 
 func NewEnvironmentOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) EnvironmentContext {
@@ -28,6 +33,11 @@ func NewEnvironmentOperation(sourceApi *SourceRequest, targetApi *TargetRequest,
 		sourceProject: st.SourceProject,
 		targetOrg:     st.TargetOrg,
 		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
 	}
 }
 
@@ -43,8 +53,18 @@ func (c EnvironmentContext) Move() error {
 
 	for _, env := range envs {
 		e := env.Environment
+		if !c.filter.Match(EntityEnvironment, e.Identifier) {
+			bar.Add(1)
+			continue
+		}
 
-		newYaml := createYaml(sanitizeEnvYaml(e.Yaml), c.sourceOrg, c.sourceProject, c.targetOrg, c.targetProject)
+		newYaml, err := createYaml(sanitizeEnvYaml(e.Yaml), c.targetOrg, c.targetProject, c.mapping.transform(EntityEnvironment))
+		if err != nil {
+			failed = append(failed, fmt.Sprintln(e.Name, "-", err.Error()))
+			c.report.record(EntityEnvironment, e.Identifier, e.Name, err)
+			bar.Add(1)
+			continue
+		}
 
 		var descriptionToUse string
 		if e.Description != nil {
@@ -54,16 +74,22 @@ func (c EnvironmentContext) Move() error {
 		req := &model.CreateEnvironmentRequest{
 			OrgIdentifier:     c.targetOrg,
 			ProjectIdentifier: c.targetProject,
-			Identifier:        e.Identifier,
+			Identifier:        c.mapping.Identifier(EntityEnvironment, e.Identifier),
 			Name:              e.Name,
 			Description:       &descriptionToUse,
 			Color:             e.Color,
 			Type:              e.Type,
 			Yaml:              newYaml,
 		}
-		if err := createEnvironment(c.target, req); err != nil {
+		result, err := c.state.upsert(c.onConflict, EntityEnvironment, e.Identifier, req,
+			func() error { return createEnvironment(c.target, req) },
+			func() error { return updateEnvironment(c.target, req) },
+			c.target.compareWith(EntityEnvironment, req.Identifier, c.targetOrg, c.targetProject, nil, newYaml),
+		)
+		if err != nil {
 			failed = append(failed, fmt.Sprintln(e.Name, "-", err.Error()))
 		}
+		c.report.recordOutcome(EntityEnvironment, e.Identifier, e.Name, result, err)
 		bar.Add(1)
 	}
 	bar.Finish()
@@ -72,6 +98,103 @@ func (c EnvironmentContext) Move() error {
 	return nil
 }
 
+func (c EnvironmentContext) Scan(g *DependencyGraph) error {
+
+	envs, err := c.source.listEnvironments(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, env := range envs {
+		e := env.Environment
+		if !c.filter.Match(EntityEnvironment, e.Identifier) {
+			continue
+		}
+		refs, err := extractReferences(e.Yaml)
+		if err != nil {
+			g.AddUnscanned(EntityEnvironment, e.Identifier, e.Name, err)
+			continue
+		}
+		g.Add(EntityEnvironment, e.Identifier, e.Name, refs)
+	}
+	return nil
+}
+
+func (c EnvironmentContext) Verify(v *Verification) error {
+
+	envs, err := c.source.listEnvironments(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	targetEnvs, err := c.target.asSource().listEnvironments(c.targetOrg, c.targetProject)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, env := range envs {
+		e := env.Environment
+		if !c.filter.Match(EntityEnvironment, e.Identifier) {
+			continue
+		}
+		count++
+		newYaml, err := createYaml(sanitizeEnvYaml(e.Yaml), c.targetOrg, c.targetProject, c.mapping.transform(EntityEnvironment))
+		if err != nil {
+			v.failed(EntityEnvironment, e.Identifier, e.Name, err)
+			continue
+		}
+		targetId := c.mapping.Identifier(EntityEnvironment, e.Identifier)
+		v.check(EntityEnvironment, e.Identifier, e.Name, c.target.verifyWith(EntityEnvironment, targetId, c.targetOrg, c.targetProject, nil, newYaml, false))
+	}
+	v.count(EntityEnvironment, count, len(targetEnvs))
+	return nil
+}
+
+func (c EnvironmentContext) Export(tf *TerraformExport) error {
+
+	envs, err := c.source.listEnvironments(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, env := range envs {
+		e := env.Environment
+		if !c.filter.Match(EntityEnvironment, e.Identifier) {
+			continue
+		}
+		newYaml, err := createYaml(sanitizeEnvYaml(e.Yaml), c.targetOrg, c.targetProject, c.mapping.transform(EntityEnvironment))
+		if err != nil {
+			return fmt.Errorf("environment %s: %w", e.Name, err)
+		}
+		var description string
+		if e.Description != nil {
+			description = *e.Description
+		}
+		id := c.mapping.Identifier(EntityEnvironment, e.Identifier)
+		tf.add(EntityEnvironment, id, "harness_platform_environment", id, tf.importId(id), tf.entity(id, e.Name,
+			tfString("description", description),
+			tfString("color", e.Color),
+			tfString("type", e.Type),
+			tfYamlDoc("yaml", newYaml),
+		)...)
+	}
+	return nil
+}
+
+func (c EnvironmentContext) Prune() error {
+
+	envs, err := c.source.listEnvironments(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for _, env := range envs {
+		ids = append(ids, env.Environment.Identifier)
+	}
+	c.state.prune(EntityEnvironment, ids, c.report, func(sourceId string) error {
+		return c.target.deleteSynced(c.mapping, EntityEnvironment, sourceId, c.targetOrg, c.targetProject)
+	})
+	return nil
+}
+
 func (s *SourceRequest) listEnvironments(org, project string) ([]*model.ListEnvironmentContent, error) {
 
 	resp, err := s.Client.R().
@@ -113,6 +236,26 @@ func createEnvironment(t *TargetRequest, env *model.CreateEnvironmentRequest) er
 	if err != nil {
 		return err
 	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
+
+func updateEnvironment(t *TargetRequest, env *model.CreateEnvironmentRequest) error {
+
+	resp, err := t.Client.R().
+		SetHeader("x-api-key", t.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(env).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": t.Account,
+		}).
+		Put(t.Url + "/ng/api/environmentsV2")
+	if err != nil {
+		return err
+	}
 	if resp.IsError() {
 		return handleErrorResponse(resp)
 	}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/schollz/progressbar/v3"
+)
+
+type EnvironmentGroupContext struct {
+	source        *SourceRequest
+	target        *TargetRequest
+	sourceOrg     string
+	sourceProject string
+	targetOrg     string
+	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
+}
+
+func NewEnvironmentGroupOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) EnvironmentGroupContext {
+	return EnvironmentGroupContext{
+		source:        sourceApi,
+		target:        targetApi,
+		sourceOrg:     st.SourceOrg,
+		sourceProject: st.SourceProject,
+		targetOrg:     st.TargetOrg,
+		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
+	}
+}
+
+func (c EnvironmentGroupContext) Move() error {
+
+	groups, err := listEnvironmentGroups(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+
+	bar := progressbar.Default(int64(len(groups)), "Environment Groups")
+	var failed []string
+
+	for _, g := range groups {
+		if !c.filter.Match(EntityEnvironmentGroup, g.Identifier) {
+			bar.Add(1)
+			continue
+		}
+
+		newYaml, err := createYaml(g.Yaml, c.targetOrg, c.targetProject, c.mapping.transform(EntityEnvironmentGroup))
+		if err != nil {
+			failed = append(failed, fmt.Sprintln(g.Name, "-", err.Error()))
+			c.report.record(EntityEnvironmentGroup, g.Identifier, g.Name, err)
+			bar.Add(1)
+			continue
+		}
+
+		req := &model.CreateEnvironmentGroupRequest{
+			OrgIdentifier:     c.targetOrg,
+			ProjectIdentifier: c.targetProject,
+			Identifier:        c.mapping.Identifier(EntityEnvironmentGroup, g.Identifier),
+			Color:             g.Color,
+			Yaml:              newYaml,
+		}
+		result, err := c.state.upsert(c.onConflict, EntityEnvironmentGroup, g.Identifier, req,
+			func() error { return c.createEnvironmentGroup(req) },
+			func() error { return c.updateEnvironmentGroup(req) },
+			c.target.compareWith(EntityEnvironmentGroup, req.Identifier, c.targetOrg, c.targetProject, nil, newYaml),
+		)
+		if err != nil {
+			failed = append(failed, fmt.Sprintln(g.Name, "-", err.Error()))
+		}
+		c.report.recordOutcome(EntityEnvironmentGroup, g.Identifier, g.Name, result, err)
+		bar.Add(1)
+	}
+	bar.Finish()
+
+	reportFailed(failed, "environment groups:")
+	return nil
+}
+
+func (c EnvironmentGroupContext) Scan(g *DependencyGraph) error {
+
+	groups, err := listEnvironmentGroups(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		if !c.filter.Match(EntityEnvironmentGroup, group.Identifier) {
+			continue
+		}
+		refs, err := extractReferences(group.Yaml)
+		if err != nil {
+			g.AddUnscanned(EntityEnvironmentGroup, group.Identifier, group.Name, err)
+			continue
+		}
+		g.Add(EntityEnvironmentGroup, group.Identifier, group.Name, refs)
+	}
+	return nil
+}
+
+func (c EnvironmentGroupContext) Verify(v *Verification) error {
+
+	groups, err := listEnvironmentGroups(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	targetGroups, err := listEnvironmentGroups(c.target.asSource(), c.targetOrg, c.targetProject)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, g := range groups {
+		if !c.filter.Match(EntityEnvironmentGroup, g.Identifier) {
+			continue
+		}
+		count++
+		newYaml, err := createYaml(g.Yaml, c.targetOrg, c.targetProject, c.mapping.transform(EntityEnvironmentGroup))
+		if err != nil {
+			v.failed(EntityEnvironmentGroup, g.Identifier, g.Name, err)
+			continue
+		}
+		targetId := c.mapping.Identifier(EntityEnvironmentGroup, g.Identifier)
+		v.check(EntityEnvironmentGroup, g.Identifier, g.Name, c.target.verifyWith(EntityEnvironmentGroup, targetId, c.targetOrg, c.targetProject, nil, newYaml, false))
+	}
+	v.count(EntityEnvironmentGroup, count, len(targetGroups))
+	return nil
+}
+
+func (c EnvironmentGroupContext) Prune() error {
+
+	groups, err := listEnvironmentGroups(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for _, group := range groups {
+		ids = append(ids, group.Identifier)
+	}
+	c.state.prune(EntityEnvironmentGroup, ids, c.report, func(sourceId string) error {
+		return c.target.deleteSynced(c.mapping, EntityEnvironmentGroup, sourceId, c.targetOrg, c.targetProject)
+	})
+	return nil
+}
+
+func listEnvironmentGroups(s *SourceRequest, org, project string) ([]*model.EnvironmentGroup, error) {
+
+	resp, err := s.Client.R().
+		SetHeader("x-api-key", s.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(`{"filterType": "EnvironmentGroup"}`).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": s.Account,
+			"orgIdentifier":     org,
+			"projectIdentifier": project,
+			"size":              "1000",
+		}).
+		Post(s.Url + "/ng/api/environmentGroup/list")
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, handleErrorResponse(resp)
+	}
+
+	result := model.ListEnvironmentGroupResponse{}
+	err = json.Unmarshal(resp.Body(), &result)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []*model.EnvironmentGroup{}
+	for _, c := range result.Data.Content {
+		groups = append(groups, c.EnvGroup)
+	}
+	return groups, nil
+}
+
+func (c EnvironmentGroupContext) createEnvironmentGroup(req *model.CreateEnvironmentGroupRequest) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(req).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+		}).
+		Post(api.Url + "/ng/api/environmentGroup")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
+
+func (c EnvironmentGroupContext) updateEnvironmentGroup(req *model.CreateEnvironmentGroupRequest) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(req).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+			"orgIdentifier":     req.OrgIdentifier,
+			"projectIdentifier": req.ProjectIdentifier,
+		}).
+		Put(api.Url + "/ng/api/environmentGroup/" + req.Identifier)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleErrorResponse(resp)
+	}
+
+	return nil
+}
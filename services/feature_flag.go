@@ -0,0 +1,370 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/schollz/progressbar/v3"
+)
+
+type FeatureFlagContext struct {
+	source        *SourceRequest
+	target        *TargetRequest
+	sourceOrg     string
+	sourceProject string
+	targetOrg     string
+	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
+}
+
+func NewFeatureFlagOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) FeatureFlagContext {
+	return FeatureFlagContext{
+		source:        sourceApi,
+		target:        targetApi,
+		sourceOrg:     st.SourceOrg,
+		sourceProject: st.SourceProject,
+		targetOrg:     st.TargetOrg,
+		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
+	}
+}
+
+func (c FeatureFlagContext) Move() error {
+
+	flags, err := listFeatureFlags(c.source, c.sourceOrg, c.sourceProject, "")
+	if err != nil {
+		return err
+	}
+	envs, err := ffEnvironments(c.source, c.sourceOrg, c.sourceProject, c.filter)
+	if err != nil {
+		return err
+	}
+
+	bar := progressbar.Default(int64(len(flags)), "Feature Flags")
+	var failed []string
+	var created []*model.FeatureFlagListItem
+
+	for _, f := range flags {
+		if !c.filter.Match(EntityFeatureFlag, f.Identifier) {
+			bar.Add(1)
+			continue
+		}
+		flag := c.toTarget(&f.FeatureFlag)
+		result, err := c.state.upsert(c.onConflict, EntityFeatureFlag, f.Identifier, flag,
+			func() error { return c.createFeatureFlag(flag) },
+			nil,
+			c.target.compareWith(EntityFeatureFlag, flag.Identifier, c.targetOrg, c.targetProject, nil, flag),
+		)
+		if err != nil {
+			failed = append(failed, fmt.Sprintln(f.Name, "-", err.Error()))
+		}
+		if result.status == StatusCreated {
+			created = append(created, f)
+		}
+		c.report.recordOutcome(EntityFeatureFlag, f.Identifier, f.Name, result, err)
+		bar.Add(1)
+	}
+	bar.Finish()
+
+	// THE STATE IS APPLIED ONCE ALL FLAGS EXIST, THE PREREQUISITES POINT AT
+	// OTHER FLAGS. FLAGS ALREADY IN THE TARGET KEEP THEIR STATE.
+	for _, f := range created {
+		targetId := c.mapping.Identifier(EntityFeatureFlag, f.Identifier)
+		if err := c.patchFeatureFlag(targetId, "", prerequisiteInstructions(f.Prerequisites, c.mapping)); err != nil {
+			failed = append(failed, fmt.Sprintln(f.Name, "prerequisites -", err.Error()))
+		}
+	}
+	for _, env := range envs {
+		states, err := c.envStates(env)
+		if err != nil {
+			failed = append(failed, fmt.Sprintln(env, "-", err.Error()))
+			continue
+		}
+		targetEnv := c.mapping.Identifier(EntityEnvironment, env)
+		for _, f := range created {
+			state, found := states[f.Identifier]
+			if !found {
+				continue
+			}
+			targetId := c.mapping.Identifier(EntityFeatureFlag, f.Identifier)
+			if err := c.patchFeatureFlag(targetId, targetEnv, envInstructions(state, c.mapping)); err != nil {
+				failed = append(failed, fmt.Sprintln(f.Name, "in", env, "-", err.Error()))
+			}
+		}
+	}
+
+	reportFailed(failed, "feature flags:")
+	return nil
+}
+
+func (c FeatureFlagContext) Scan(g *DependencyGraph) error {
+
+	flags, err := listFeatureFlags(c.source, c.sourceOrg, c.sourceProject, "")
+	if err != nil {
+		return err
+	}
+	envs, err := ffEnvironments(c.source, c.sourceOrg, c.sourceProject, c.filter)
+	if err != nil {
+		return err
+	}
+
+	refs := map[string][]EntityRef{}
+	for _, f := range flags {
+		for _, p := range f.Prerequisites {
+			refs[f.Identifier] = append(refs[f.Identifier], EntityRef{Type: EntityFeatureFlag, Scope: ScopeProject, Identifier: p.Feature})
+		}
+	}
+	for _, env := range envs {
+		states, err := c.envStates(env)
+		if err != nil {
+			return fmt.Errorf("feature flags of %s: %w", env, err)
+		}
+		for id, state := range states {
+			refs[id] = append(refs[id], envStateRefs(env, state)...)
+		}
+	}
+
+	for _, f := range flags {
+		if !c.filter.Match(EntityFeatureFlag, f.Identifier) {
+			continue
+		}
+		g.Add(EntityFeatureFlag, f.Identifier, f.Name, refs[f.Identifier])
+	}
+	return nil
+}
+
+func (c FeatureFlagContext) Verify(v *Verification) error {
+
+	flags, err := listFeatureFlags(c.source, c.sourceOrg, c.sourceProject, "")
+	if err != nil {
+		return err
+	}
+	targetFlags, err := listFeatureFlags(c.target.asSource(), c.targetOrg, c.targetProject, "")
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, f := range flags {
+		if !c.filter.Match(EntityFeatureFlag, f.Identifier) {
+			continue
+		}
+		count++
+		flag := c.toTarget(&f.FeatureFlag)
+		v.check(EntityFeatureFlag, f.Identifier, f.Name, c.target.verifyWith(EntityFeatureFlag, flag.Identifier, c.targetOrg, c.targetProject, nil, flag, false))
+	}
+	v.count(EntityFeatureFlag, count, len(targetFlags))
+	return nil
+}
+
+func (c FeatureFlagContext) toTarget(f *model.FeatureFlag) *model.FeatureFlag {
+	flag := *f
+	flag.Identifier = c.mapping.Identifier(EntityFeatureFlag, f.Identifier)
+	return &flag
+}
+
+// envStates returns the state of the flags in the source environment, by
+// flag identifier
+func (c FeatureFlagContext) envStates(env string) (map[string]*model.FeatureFlagEnvState, error) {
+	flags, err := listFeatureFlags(c.source, c.sourceOrg, c.sourceProject, env)
+	if err != nil {
+		return nil, err
+	}
+	states := map[string]*model.FeatureFlagEnvState{}
+	for _, f := range flags {
+		if f.EnvProperties != nil {
+			states[f.Identifier] = f.EnvProperties
+		}
+	}
+	return states, nil
+}
+
+// envStateRefs returns the environment, target groups and targets used by the
+// state of a flag
+func envStateRefs(env string, state *model.FeatureFlagEnvState) []EntityRef {
+	refs := []EntityRef{{Type: EntityEnvironment, Scope: ScopeProject, Identifier: env}}
+	for _, r := range state.Rules {
+		refs = append(refs, clauseSegmentRefs(env, r.Clauses)...)
+	}
+	hasTargets := false
+	for _, vm := range state.VariationMap {
+		for _, id := range vm.TargetSegments {
+			refs = append(refs, EntityRef{Type: EntityTargetGroup, Scope: ScopeProject, Identifier: env + "/" + id})
+		}
+		hasTargets = hasTargets || len(vm.Targets) > 0
+	}
+	if hasTargets {
+		refs = append(refs, EntityRef{Type: EntityTargetGroup, Scope: ScopeProject, Identifier: env + "/" + ffTargetsNode})
+	}
+	return refs
+}
+
+// prerequisiteInstructions adds the prerequisites of a flag, renamed
+func prerequisiteInstructions(prerequisites []*model.Prerequisite, mapping IdentifierMapping) []*model.PatchInstruction {
+	var instructions []*model.PatchInstruction
+	for _, p := range prerequisites {
+		instructions = append(instructions, &model.PatchInstruction{
+			Kind: "addPrerequisite",
+			Parameters: &model.Prerequisite{
+				Feature:    mapping.Identifier(EntityFeatureFlag, p.Feature),
+				Variations: p.Variations,
+			},
+		})
+	}
+	return instructions
+}
+
+// envInstructions recreates the state of a flag in an environment: on or off,
+// the default and off variations, the targeting rules and the targets and
+// target groups served each variation
+func envInstructions(state *model.FeatureFlagEnvState, mapping IdentifierMapping) []*model.PatchInstruction {
+	instructions := []*model.PatchInstruction{{
+		Kind:       "setFeatureFlagState",
+		Parameters: map[string]string{"state": state.State},
+	}}
+
+	if serve := state.DefaultServe; serve != nil {
+		if serve.Distribution != nil {
+			instructions = append(instructions, &model.PatchInstruction{
+				Kind:       "updateDefaultServe",
+				Parameters: serve.Distribution,
+			})
+		} else if serve.Variation != nil {
+			instructions = append(instructions, &model.PatchInstruction{
+				Kind:       "updateDefaultServe",
+				Parameters: map[string]string{"variation": *serve.Variation},
+			})
+		}
+	}
+	if len(state.OffVariation) > 0 {
+		instructions = append(instructions, &model.PatchInstruction{
+			Kind:       "updateOffVariation",
+			Parameters: map[string]string{"variation": state.OffVariation},
+		})
+	}
+
+	for _, r := range state.Rules {
+		instructions = append(instructions, &model.PatchInstruction{
+			Kind: "addRule",
+			Parameters: &model.ServingRule{
+				Priority: r.Priority,
+				Clauses:  mapClauses(mapping, r.Clauses),
+				Serve:    r.Serve,
+			},
+		})
+	}
+
+	for _, vm := range state.VariationMap {
+		if len(vm.Targets) > 0 {
+			var targets []string
+			for _, t := range vm.Targets {
+				targets = append(targets, t.Identifier)
+			}
+			instructions = append(instructions, &model.PatchInstruction{
+				Kind:       "addTargetsToVariationTargetMap",
+				Parameters: map[string]interface{}{"variation": vm.Variation, "targets": targets},
+			})
+		}
+		if len(vm.TargetSegments) > 0 {
+			var segments []string
+			for _, id := range vm.TargetSegments {
+				segments = append(segments, mapping.Identifier(EntityTargetGroup, id))
+			}
+			instructions = append(instructions, &model.PatchInstruction{
+				Kind:       "addSegmentToVariationTargetMap",
+				Parameters: map[string]interface{}{"variation": vm.Variation, "targetSegments": segments},
+			})
+		}
+	}
+	return instructions
+}
+
+// listFeatureFlags returns the flags of the project, with their state in the
+// environment when one is given
+func listFeatureFlags(s *SourceRequest, org, project, env string) ([]*model.FeatureFlagListItem, error) {
+
+	flags := []*model.FeatureFlagListItem{}
+	for page := 0; ; page++ {
+		params := ffParams(s.Account, org, project, env)
+		params["pageNumber"] = strconv.Itoa(page)
+		params["pageSize"] = "100"
+
+		resp, err := s.Client.R().
+			SetHeader("x-api-key", s.Token).
+			SetHeader("Content-Type", "application/json").
+			SetQueryParams(params).
+			Get(s.Url + "/cf/admin/features")
+		if err != nil {
+			return nil, err
+		}
+		if resp.IsError() {
+			return nil, handleErrorResponse(resp)
+		}
+
+		result := model.ListFeatureFlagResponse{}
+		err = json.Unmarshal(resp.Body(), &result)
+		if err != nil {
+			return nil, err
+		}
+		flags = append(flags, result.Features...)
+		if int64(page+1) >= result.PageCount {
+			return flags, nil
+		}
+	}
+}
+
+func (c FeatureFlagContext) createFeatureFlag(flag *model.FeatureFlag) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(&model.CreateFeatureFlagRequest{Project: c.targetProject, FeatureFlag: flag}).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+			"orgIdentifier":     c.targetOrg,
+		}).
+		Post(api.Url + "/cf/admin/features")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
+
+// patchFeatureFlag applies the instructions to the flag, in the environment
+// when one is given
+func (c FeatureFlagContext) patchFeatureFlag(identifier, env string, instructions []*model.PatchInstruction) error {
+
+	if len(instructions) == 0 {
+		return nil
+	}
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(&model.PatchFeatureFlagRequest{Instructions: instructions}).
+		SetQueryParams(ffParams(api.Account, c.targetOrg, c.targetProject, env)).
+		Patch(api.Url + "/cf/admin/features/" + identifier)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleErrorResponse(resp)
+	}
+
+	return nil
+}
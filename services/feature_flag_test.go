@@ -0,0 +1,57 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvInstructions(t *testing.T) {
+	mapping := IdentifierMapping{EntityTargetGroup: {Prefix: "legacy_"}}
+	on := "true"
+	state := &model.FeatureFlagEnvState{
+		Environment:  "prod",
+		State:        "on",
+		DefaultServe: &model.Serve{Variation: &on},
+		OffVariation: "false",
+		Rules: []*model.ServingRule{{
+			Priority: 1,
+			Clauses:  []*model.Clause{{Op: "segmentMatch", Values: []string{"beta"}}},
+			Serve:    &model.Serve{Variation: &on},
+		}},
+		VariationMap: []*model.VariationMap{{
+			Variation:      "true",
+			Targets:        []*model.TargetMap{{Identifier: "alice", Name: "Alice"}},
+			TargetSegments: []string{"internal"},
+		}},
+	}
+
+	instructions := envInstructions(state, mapping)
+
+	var kinds []string
+	for _, i := range instructions {
+		kinds = append(kinds, i.Kind)
+	}
+	assert.Equal(t, []string{"setFeatureFlagState", "updateDefaultServe", "updateOffVariation", "addRule", "addTargetsToVariationTargetMap", "addSegmentToVariationTargetMap"}, kinds)
+	assert.Equal(t, []string{"legacy_beta"}, instructions[3].Parameters.(*model.ServingRule).Clauses[0].Values)
+	assert.Equal(t, []string{"beta"}, state.Rules[0].Clauses[0].Values)
+	assert.Equal(t, []string{"alice"}, instructions[4].Parameters.(map[string]interface{})["targets"])
+	assert.Equal(t, []string{"legacy_internal"}, instructions[5].Parameters.(map[string]interface{})["targetSegments"])
+}
+
+func TestEnvStateRefs(t *testing.T) {
+	state := &model.FeatureFlagEnvState{
+		VariationMap: []*model.VariationMap{{
+			Variation: "true",
+			Targets:   []*model.TargetMap{{Identifier: "alice"}},
+		}},
+	}
+
+	refs := envStateRefs("prod", state)
+
+	assert.Equal(t, []EntityRef{
+		{Type: EntityEnvironment, Scope: ScopeProject, Identifier: "prod"},
+		{Type: EntityTargetGroup, Scope: ScopeProject, Identifier: "prod/Targets"},
+	}, refs)
+}
@@ -18,6 +18,9 @@ type FileStoreContext struct {
 	sourceProject string
 	targetOrg     string
 	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
 }
 
 func NewFileStoreOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) FileStoreContext {
@@ -28,6 +31,9 @@ func NewFileStoreOperation(sourceApi *SourceRequest, targetApi *TargetRequest, s
 		sourceProject: st.SourceProject,
 		targetOrg:     st.TargetOrg,
 		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
 	}
 }
 
@@ -53,6 +59,13 @@ func (c FileStoreContext) Move() error {
 	return nil
 }
 
+// Scan adds the project file store as a single node, the entities using
+// Harness store files reference it
+func (c FileStoreContext) Scan(g *DependencyGraph) error {
+	g.Add(EntityFileStore, fileStoreRoot, "File Store", nil)
+	return nil
+}
+
 func handeNodeFailure(node *model.FileStoreNode, failures []string, err error) []string {
 	return append(failures, fmt.Sprintf("%s (%s) - %s", node.Name, node.Path, err.Error()))
 }
@@ -60,7 +73,9 @@ func handeNodeFailure(node *model.FileStoreNode, failures []string, err error) [
 func (c FileStoreContext) handleNode(n *model.FileStoreNode, failures []string) error {
 
 	// CREATE FOLDER OR FILE
-	if err := c.createNode(n); err != nil {
+	err := c.createNode(n)
+	c.report.record(EntityFileStore, n.Path, n.Name, err)
+	if err != nil {
 		return err
 	}
 
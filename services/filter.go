@@ -0,0 +1,67 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// FilterRule selects entities by identifier using glob patterns. When include
+// is empty every entity not excluded is selected.
+type FilterRule struct {
+	Include []string `yaml:"include,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty"`
+}
+
+// EntityFilter holds the filter rules by entity type. A nil filter selects
+// every entity.
+type EntityFilter map[EntityType]*FilterRule
+
+func (f EntityFilter) Validate() error {
+	for t, rule := range f {
+		if !entityTypes[t] {
+			return fmt.Errorf("unknown entity type %s", t)
+		}
+		for _, pattern := range append(rule.Include, rule.Exclude...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("filter %s pattern %q: %w", t, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Match reports whether the entity is selected. Infrastructures, overrides
+// and input sets are matched by the identifier after their parent.
+func (f EntityFilter) Match(t EntityType, identifier string) bool {
+	rule := f[t]
+	if rule == nil {
+		return true
+	}
+	if i := strings.LastIndex(identifier, "/"); i >= 0 {
+		identifier = identifier[i+1:]
+	}
+	for _, pattern := range rule.Exclude {
+		if matched, _ := path.Match(pattern, identifier); matched {
+			return false
+		}
+	}
+	if len(rule.Include) == 0 {
+		return true
+	}
+	for _, pattern := range rule.Include {
+		if matched, _ := path.Match(pattern, identifier); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseEntityType validates the name of an entity type
+func ParseEntityType(name string) (EntityType, error) {
+	t := EntityType(strings.TrimSpace(name))
+	if !entityTypes[t] {
+		return "", fmt.Errorf("unknown entity type %s", name)
+	}
+	return t, nil
+}
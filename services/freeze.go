@@ -0,0 +1,297 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/schollz/progressbar/v3"
+)
+
+type FreezeContext struct {
+	source        *SourceRequest
+	target        *TargetRequest
+	sourceOrg     string
+	sourceProject string
+	targetOrg     string
+	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
+}
+
+func NewFreezeOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) FreezeContext {
+	return FreezeContext{
+		source:        sourceApi,
+		target:        targetApi,
+		sourceOrg:     st.SourceOrg,
+		sourceProject: st.SourceProject,
+		targetOrg:     st.TargetOrg,
+		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
+	}
+}
+
+func (c FreezeContext) Move() error {
+
+	freezes, err := listFreezes(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+
+	bar := progressbar.Default(int64(len(freezes)), "Freeze Windows")
+	var failed []string
+
+	for _, f := range freezes {
+		if !c.filter.Match(EntityFreeze, f.Identifier) {
+			bar.Add(1)
+			continue
+		}
+		result := outcome{status: StatusFailed}
+		data, err := c.getFreeze(f.Identifier)
+		if err == nil {
+			var newYaml string
+			if newYaml, err = createYaml(data.Yaml, c.targetOrg, c.targetProject, c.mapping.transform(EntityFreeze)); err == nil {
+				targetId := c.mapping.Identifier(EntityFreeze, f.Identifier)
+				result, err = c.state.upsert(c.onConflict, EntityFreeze, f.Identifier, map[string]interface{}{"yaml": newYaml, "status": data.Status},
+					func() error { return c.createFreeze(newYaml) },
+					func() error { return c.updateFreeze(targetId, newYaml) },
+					c.target.compareWith(EntityFreeze, targetId, c.targetOrg, c.targetProject, nil, newYaml),
+				)
+				// THE SERVER MAY NOT APPLY THE STATUS OF THE YAML
+				if err == nil && (result.status == StatusCreated || result.status == StatusUpdated) {
+					if err = c.updateFreezeStatus(targetId, data.Status); err != nil {
+						result.status = StatusFailed
+					}
+				}
+			}
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintln(f.Name, "-", err.Error()))
+		}
+		c.report.recordOutcome(EntityFreeze, f.Identifier, f.Name, result, err)
+		bar.Add(1)
+	}
+	bar.Finish()
+
+	reportFailed(failed, "freeze windows:")
+	return nil
+}
+
+func (c FreezeContext) Scan(g *DependencyGraph) error {
+
+	freezes, err := listFreezes(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, f := range freezes {
+		if !c.filter.Match(EntityFreeze, f.Identifier) {
+			continue
+		}
+		data, err := c.getFreeze(f.Identifier)
+		if err != nil {
+			g.AddUnscanned(EntityFreeze, f.Identifier, f.Name, err)
+			continue
+		}
+		refs, err := extractReferences(data.Yaml)
+		if err != nil {
+			g.AddUnscanned(EntityFreeze, f.Identifier, f.Name, err)
+			continue
+		}
+		g.Add(EntityFreeze, f.Identifier, f.Name, refs)
+	}
+	return nil
+}
+
+func (c FreezeContext) Verify(v *Verification) error {
+
+	freezes, err := listFreezes(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	targetFreezes, err := listFreezes(c.target.asSource(), c.targetOrg, c.targetProject)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, f := range freezes {
+		if !c.filter.Match(EntityFreeze, f.Identifier) {
+			continue
+		}
+		count++
+		data, err := c.getFreeze(f.Identifier)
+		if err != nil {
+			v.failed(EntityFreeze, f.Identifier, f.Name, err)
+			continue
+		}
+		newYaml, err := createYaml(data.Yaml, c.targetOrg, c.targetProject, c.mapping.transform(EntityFreeze))
+		if err != nil {
+			v.failed(EntityFreeze, f.Identifier, f.Name, err)
+			continue
+		}
+		targetId := c.mapping.Identifier(EntityFreeze, f.Identifier)
+		v.check(EntityFreeze, f.Identifier, f.Name, c.target.verifyWith(EntityFreeze, targetId, c.targetOrg, c.targetProject, nil, newYaml, false))
+	}
+	v.count(EntityFreeze, count, len(targetFreezes))
+	return nil
+}
+
+// listFreezes returns the manual freeze windows of the project, the global
+// freeze is managed by Harness
+func (c FreezeContext) Prune() error {
+
+	freezes, err := listFreezes(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for _, f := range freezes {
+		ids = append(ids, f.Identifier)
+	}
+	c.state.prune(EntityFreeze, ids, c.report, func(sourceId string) error {
+		return c.target.deleteSynced(c.mapping, EntityFreeze, sourceId, c.targetOrg, c.targetProject)
+	})
+	return nil
+}
+
+func listFreezes(s *SourceRequest, org, project string) ([]*model.Freeze, error) {
+
+	resp, err := s.Client.R().
+		SetHeader("x-api-key", s.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(`{}`).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": s.Account,
+			"orgIdentifier":     org,
+			"projectIdentifier": project,
+			"size":              "1000",
+		}).
+		Post(s.Url + "/ng/api/freeze/list")
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, handleErrorResponse(resp)
+	}
+
+	result := model.ListFreezeResponse{}
+	err = json.Unmarshal(resp.Body(), &result)
+	if err != nil {
+		return nil, err
+	}
+
+	freezes := []*model.Freeze{}
+	for _, f := range result.Data.Content {
+		if !isBuiltIn(f.Identifier) {
+			freezes = append(freezes, f)
+		}
+	}
+	return freezes, nil
+}
+
+func (c FreezeContext) getFreeze(identifier string) (*model.Freeze, error) {
+
+	api := c.source
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+			"orgIdentifier":     c.sourceOrg,
+			"projectIdentifier": c.sourceProject,
+		}).
+		Get(api.Url + "/ng/api/freeze/" + identifier)
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, handleErrorResponse(resp)
+	}
+
+	result := model.GetFreezeResponse{}
+	err = json.Unmarshal(resp.Body(), &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Data, nil
+}
+
+func (c FreezeContext) createFreeze(yaml string) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/yaml").
+		SetBody(yaml).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+			"orgIdentifier":     c.targetOrg,
+			"projectIdentifier": c.targetProject,
+		}).
+		Post(api.Url + "/ng/api/freeze")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
+
+func (c FreezeContext) updateFreeze(identifier, yaml string) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/yaml").
+		SetBody(yaml).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+			"orgIdentifier":     c.targetOrg,
+			"projectIdentifier": c.targetProject,
+		}).
+		Put(api.Url + "/ng/api/freeze/" + identifier)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleErrorResponse(resp)
+	}
+
+	return nil
+}
+
+func (c FreezeContext) updateFreezeStatus(identifier, status string) error {
+
+	if len(status) == 0 {
+		return nil
+	}
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody([]string{identifier}).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+			"orgIdentifier":     c.targetOrg,
+			"projectIdentifier": c.targetProject,
+			"status":            status,
+		}).
+		Post(api.Url + "/ng/api/freeze/updateFreezeStatus")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleErrorResponse(resp)
+	}
+
+	return nil
+}
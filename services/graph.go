@@ -0,0 +1,378 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Scanner is implemented by the operations able to describe the source
+// entities they copy, before anything is written to the target.
+type Scanner interface {
+	Scan(g *DependencyGraph) error
+}
+
+// DependencyGraph holds the source entities being copied and the entities each
+// one references. Nodes are always project scoped.
+type DependencyGraph struct {
+	nodes     map[EntityRef]*graphNode
+	keys      []EntityRef
+	unscanned []UnscannedEntity
+}
+
+type graphNode struct {
+	name string
+	refs []EntityRef
+}
+
+// UnscannedEntity is a source entity whose references could not be read. It
+// is still copied, its own operation reports the failure.
+type UnscannedEntity struct {
+	Ref  EntityRef
+	Name string
+	Err  error
+}
+
+type UnresolvedRef struct {
+	From     EntityRef
+	FromName string
+	Ref      EntityRef
+}
+
+func NewDependencyGraph() *DependencyGraph {
+	return &DependencyGraph{
+		nodes: map[EntityRef]*graphNode{},
+	}
+}
+
+func (g *DependencyGraph) Add(t EntityType, identifier, name string, refs []EntityRef) {
+	key := EntityRef{Type: t, Scope: ScopeProject, Identifier: identifier}
+	if n, found := g.nodes[key]; found {
+		set := newRefSet()
+		for _, r := range append(n.refs, refs...) {
+			set.add(r)
+		}
+		n.refs = set.list
+		return
+	}
+	g.nodes[key] = &graphNode{name: name, refs: refs}
+	g.keys = append(g.keys, key)
+}
+
+// AddUnscanned adds the entity without references, so a single entity that
+// can't be read doesn't stop the scan
+func (g *DependencyGraph) AddUnscanned(t EntityType, identifier, name string, err error) {
+	g.Add(t, identifier, name, nil)
+	g.unscanned = append(g.unscanned, UnscannedEntity{
+		Ref:  EntityRef{Type: t, Scope: ScopeProject, Identifier: identifier},
+		Name: name,
+		Err:  err,
+	})
+}
+
+func (g *DependencyGraph) Unscanned() []UnscannedEntity {
+	return g.unscanned
+}
+
+func (g *DependencyGraph) Has(ref EntityRef) bool {
+	if ref.Scope != ScopeProject {
+		return false
+	}
+	_, found := g.nodes[ref]
+	return found
+}
+
+// Unresolved returns the references not copied by this move and missing in the
+// target. Org and account references are always checked against the target,
+// project references by their mapped identifier.
+func (g *DependencyGraph) Unresolved(t *TargetRequest, org, project string, mapping IdentifierMapping) ([]UnresolvedRef, error) {
+	checked := map[EntityRef]bool{}
+	var unresolved []UnresolvedRef
+
+	for _, key := range g.keys {
+		n := g.nodes[key]
+		for _, ref := range n.refs {
+			if g.Has(ref) {
+				continue
+			}
+			exists, found := checked[ref]
+			if !found {
+				var err error
+				if exists, err = t.entityExists(mapping.Ref(ref), org, project); err != nil {
+					return nil, fmt.Errorf("checking %s: %w", ref, err)
+				}
+				checked[ref] = exists
+			}
+			if !exists {
+				unresolved = append(unresolved, UnresolvedRef{From: key, FromName: n.name, Ref: ref})
+			}
+		}
+	}
+	return unresolved, nil
+}
+
+// TypeOrder sorts the entity types so every type comes after the types it
+// references. Types without dependencies between them keep the given order.
+func (g *DependencyGraph) TypeOrder(types []EntityType) ([]EntityType, error) {
+	index := map[EntityType]int{}
+	for i, t := range types {
+		index[t] = i
+	}
+	deps := g.typeDependencies(index)
+
+	var ids []string
+	for _, t := range types {
+		ids = append(ids, string(t))
+	}
+	sorted, err := topologicalSort(ids, func(id string) []string {
+		var out []string
+		for d := range deps[EntityType(id)] {
+			out = append(out, string(d))
+		}
+		sort.Slice(out, func(i, j int) bool { return index[EntityType(out[i])] < index[EntityType(out[j])] })
+		return out
+	})
+	if err != nil {
+		return types, err
+	}
+
+	order := make([]EntityType, len(sorted))
+	for i, id := range sorted {
+		order[i] = EntityType(id)
+	}
+	return order, nil
+}
+
+// TypeLevels groups the sorted types in levels. A type only depends on types
+// from previous levels, so the types of the same level can run together.
+func (g *DependencyGraph) TypeLevels(types []EntityType) ([][]EntityType, error) {
+	order, err := g.TypeOrder(types)
+	if err != nil {
+		// WITHOUT A VALID ORDER EVERY TYPE RUNS ALONE
+		var levels [][]EntityType
+		for _, t := range types {
+			levels = append(levels, []EntityType{t})
+		}
+		return levels, err
+	}
+
+	index := map[EntityType]int{}
+	for i, t := range types {
+		index[t] = i
+	}
+	deps := g.typeDependencies(index)
+
+	var levels [][]EntityType
+	level := map[EntityType]int{}
+	for _, t := range order {
+		l := 0
+		for d := range deps[t] {
+			if level[d]+1 > l {
+				l = level[d] + 1
+			}
+		}
+		level[t] = l
+		if l == len(levels) {
+			levels = append(levels, nil)
+		}
+		levels[l] = append(levels[l], t)
+	}
+	return levels, nil
+}
+
+// typeDependencies returns the types each type references, limited to the
+// given types
+func (g *DependencyGraph) typeDependencies(index map[EntityType]int) map[EntityType]map[EntityType]bool {
+	deps := map[EntityType]map[EntityType]bool{}
+	for _, key := range g.keys {
+		for _, ref := range g.nodes[key].refs {
+			if ref.Type == key.Type || !g.Has(ref) {
+				continue
+			}
+			if _, found := index[ref.Type]; !found {
+				continue
+			}
+			if deps[key.Type] == nil {
+				deps[key.Type] = map[EntityType]bool{}
+			}
+			deps[key.Type][ref.Type] = true
+		}
+	}
+	return deps
+}
+
+// EntityOrder returns the identifiers of the given type, sorted so entities
+// referenced by others of the same type come first.
+func (g *DependencyGraph) EntityOrder(t EntityType) ([]string, error) {
+	var ids []string
+	for _, key := range g.keys {
+		if key.Type == t {
+			ids = append(ids, key.Identifier)
+		}
+	}
+	sorted, err := topologicalSort(ids, func(id string) []string {
+		var out []string
+		for _, ref := range g.nodes[EntityRef{Type: t, Scope: ScopeProject, Identifier: id}].refs {
+			if ref.Type == t && ref.Identifier != id && g.Has(ref) {
+				out = append(out, ref.Identifier)
+			}
+		}
+		return out
+	})
+	if err != nil {
+		return ids, err
+	}
+	return sorted, nil
+}
+
+// topologicalSort visits the ids in the given order, placing the dependencies
+// of each id before it
+func topologicalSort(ids []string, dependencies func(id string) []string) ([]string, error) {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	var sorted []string
+	var cycle []string
+
+	var visit func(id string) bool
+	visit = func(id string) bool {
+		switch state[id] {
+		case done:
+			return true
+		case visiting:
+			cycle = append(cycle, id)
+			return false
+		}
+		state[id] = visiting
+		for _, dep := range dependencies(id) {
+			if !visit(dep) {
+				cycle = append(cycle, id)
+				return false
+			}
+		}
+		state[id] = done
+		sorted = append(sorted, id)
+		return true
+	}
+
+	for _, id := range ids {
+		if !visit(id) {
+			return nil, fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " <- "))
+		}
+	}
+	return sorted, nil
+}
+
+// ReportUnresolved prints the references that will not exist in the target
+func ReportUnresolved(unresolved []UnresolvedRef) {
+	if len(unresolved) > 0 {
+		fmt.Println(color.YellowString(fmt.Sprintf("Unresolved references %d", len(unresolved))))
+		for _, u := range unresolved {
+			fmt.Println(color.YellowString(fmt.Sprintf("%s (%s) -> %s", u.From, u.FromName, u.Ref)))
+		}
+	}
+}
+
+// ReportUnscanned prints the entities whose references are not checked
+func ReportUnscanned(unscanned []UnscannedEntity) {
+	if len(unscanned) > 0 {
+		fmt.Println(color.YellowString(fmt.Sprintf("Unscanned entities %d", len(unscanned))))
+		for _, u := range unscanned {
+			fmt.Println(color.YellowString(fmt.Sprintf("%s (%s) - %s", u.Ref, u.Name, u.Err.Error())))
+		}
+	}
+}
+
+var existsEndpoints = map[EntityType]string{
+	EntityConnector:        "/ng/api/connectors/{identifier}",
+	EntitySecret:           "/ng/api/v2/secrets/{identifier}",
+	EntityTemplate:         "/template/api/templates/{identifier}",
+	EntityService:          "/ng/api/servicesV2/{identifier}",
+	EntityEnvironment:      "/ng/api/environmentsV2/{identifier}",
+	EntityInfrastructure:   "/ng/api/infrastructures/{identifier}",
+	EntityPipeline:         "/pipeline/api/pipelines/{identifier}",
+	EntityVariable:         "/ng/api/variables/{identifier}",
+	EntityInputset:         "/pipeline/api/inputSets/{identifier}",
+	EntityOverrideV2:       "/ng/api/serviceOverrides/{identifier}",
+	EntityRole:             "/authz/api/roles/{identifier}",
+	EntityResourceGroup:    "/resourcegroup/api/v2/resourcegroup/{identifier}",
+	EntityUserGroup:        "/ng/api/user-groups/{identifier}",
+	EntityServiceAccount:   "/ng/api/serviceaccount/{identifier}",
+	EntityPolicy:           "/pm/api/v1/policies/{identifier}",
+	EntityPolicySet:        "/pm/api/v1/policysets/{identifier}",
+	EntityFreeze:           "/ng/api/freeze/{identifier}",
+	EntityEnvironmentGroup: "/ng/api/environmentGroup/{identifier}",
+	EntityMonitoredService: "/cv/api/monitored-service/{identifier}",
+	EntitySLO:              "/cv/api/slo/v2/identifier/{identifier}",
+	EntityTargetGroup:      "/cf/admin/segments/{identifier}",
+	EntityFeatureFlag:      "/cf/admin/features/{identifier}",
+}
+
+// entityExists looks the reference up in the target. Types without a lookup
+// endpoint are reported as existing.
+func (t *TargetRequest) entityExists(ref EntityRef, org, project string) (bool, error) {
+	if _, found := existsEndpoints[ref.Type]; !found {
+		return true, nil
+	}
+	_, err := t.getEntity(ref, org, project, nil)
+	if errors.Is(err, ErrEntityNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// getEntity reads the reference from the target, returning ErrEntityNotFound
+// when it doesn't exist. The params are added to the query.
+func (t *TargetRequest) getEntity(ref EntityRef, org, project string, params map[string]string) ([]byte, error) {
+	endpoint, found := existsEndpoints[ref.Type]
+	if !found {
+		return nil, fmt.Errorf("no lookup for %s", ref.Type)
+	}
+
+	query := map[string]string{
+		"accountIdentifier": t.Account,
+	}
+	if ref.Scope != ScopeAccount {
+		query["orgIdentifier"] = org
+	}
+	if ref.Scope == ScopeProject {
+		query["projectIdentifier"] = project
+	}
+	for k, v := range params {
+		query[k] = v
+	}
+
+	identifier := ref.Identifier
+	if ref.Type == EntityInfrastructure || ref.Type == EntityTargetGroup {
+		env, infra, _ := strings.Cut(ref.Identifier, "/")
+		query["environmentIdentifier"] = env
+		identifier = infra
+	}
+
+	resp, err := t.Client.R().
+		SetHeader("x-api-key", t.Token).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("Load-From-Cache", "false").
+		SetPathParam("identifier", identifier).
+		SetQueryParams(query).
+		Get(t.Url + endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() == http.StatusNotFound {
+		return nil, ErrEntityNotFound
+	}
+	if resp.IsError() {
+		if err := handleErrorResponse(resp); err != nil {
+			return nil, err
+		}
+		return nil, ErrEntityNotFound
+	}
+	return resp.Body(), nil
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/Fernando-Dourado/harness-move-project/model"
@@ -15,6 +16,11 @@ type InfrastructureContext struct {
 	sourceProject string
 	targetOrg     string
 	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
 }
 
 func NewInfrastructureOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) InfrastructureContext {
@@ -25,6 +31,11 @@ func NewInfrastructureOperation(sourceApi *SourceRequest, targetApi *TargetReque
 		sourceProject: st.SourceProject,
 		targetOrg:     st.TargetOrg,
 		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
 	}
 }
 
@@ -50,22 +61,39 @@ func (c InfrastructureContext) Move() error {
 
 		for _, infra := range infras {
 			i := infra.Infrastructure
-			newYaml := createYaml(i.Yaml, c.sourceOrg, c.sourceProject, c.targetOrg, c.targetProject)
+			id := e.Identifier + "/" + i.Identifier
+			if !c.filter.Match(EntityInfrastructure, id) {
+				bar.Add(1)
+				continue
+			}
+			newYaml, err := createYaml(i.Yaml, c.targetOrg, c.targetProject, c.mapping.transform(EntityInfrastructure))
+			if err != nil {
+				failed = append(failed, fmt.Sprintln(e.Name, "/", i.Name, "-", err.Error()))
+				c.report.record(EntityInfrastructure, id, i.Name, err)
+				bar.Add(1)
+				continue
+			}
 
-			err := createInfrastructure(c.target, &model.CreateInfrastructureRequest{
+			req := &model.CreateInfrastructureRequest{
 				Name:              i.Name,
-				Identifier:        i.Identifier,
+				Identifier:        c.mapping.Identifier(EntityInfrastructure, i.Identifier),
 				OrgIdentifier:     c.targetOrg,
 				ProjectIdentifier: c.targetProject,
 				Description:       i.Description,
-				EnvironmentRef:    e.Identifier,
+				EnvironmentRef:    c.mapping.Identifier(EntityEnvironment, e.Identifier),
 				DeploymentType:    i.DeploymentType,
 				Type:              i.Type,
 				Yaml:              newYaml,
-			})
+			}
+			result, err := c.state.upsert(c.onConflict, EntityInfrastructure, id, req,
+				func() error { return createInfrastructure(c.target, req) },
+				func() error { return updateInfrastructure(c.target, req) },
+				c.target.compareWith(EntityInfrastructure, req.EnvironmentRef+"/"+req.Identifier, c.targetOrg, c.targetProject, nil, newYaml),
+			)
 			if err != nil {
 				failed = append(failed, fmt.Sprintln(e.Name, "/", i.Name, "-", err.Error()))
 			}
+			c.report.recordOutcome(EntityInfrastructure, id, i.Name, result, err)
 			bar.Add(1)
 		}
 		bar.Add(1)
@@ -76,6 +104,140 @@ func (c InfrastructureContext) Move() error {
 	return nil
 }
 
+func (c InfrastructureContext) Scan(g *DependencyGraph) error {
+
+	envs, err := c.source.listEnvironments(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, env := range envs {
+		e := env.Environment
+		infras, err := listInfraDef(c.source, c.sourceOrg, c.sourceProject, e.Identifier)
+		if err != nil {
+			return err
+		}
+		for _, infra := range infras {
+			i := infra.Infrastructure
+			if !c.filter.Match(EntityInfrastructure, e.Identifier+"/"+i.Identifier) {
+				continue
+			}
+			refs, err := extractReferences(i.Yaml)
+			if err != nil {
+				g.AddUnscanned(EntityInfrastructure, e.Identifier+"/"+i.Identifier, i.Name, err)
+				continue
+			}
+			refs = append(refs, EntityRef{Type: EntityEnvironment, Scope: ScopeProject, Identifier: e.Identifier})
+			g.Add(EntityInfrastructure, e.Identifier+"/"+i.Identifier, i.Name, refs)
+		}
+	}
+	return nil
+}
+
+func (c InfrastructureContext) Verify(v *Verification) error {
+
+	envs, err := c.source.listEnvironments(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+
+	count, targetCount := 0, 0
+	for _, env := range envs {
+		e := env.Environment
+		infras, err := listInfraDef(c.source, c.sourceOrg, c.sourceProject, e.Identifier)
+		if err != nil {
+			return err
+		}
+		targetEnv := c.mapping.Identifier(EntityEnvironment, e.Identifier)
+		targetInfras, err := listInfraDef(c.target.asSource(), c.targetOrg, c.targetProject, targetEnv)
+		if err != nil && !errors.Is(err, ErrEntityNotFound) {
+			return err
+		}
+		targetCount += len(targetInfras)
+
+		for _, infra := range infras {
+			i := infra.Infrastructure
+			id := e.Identifier + "/" + i.Identifier
+			if !c.filter.Match(EntityInfrastructure, id) {
+				continue
+			}
+			count++
+			newYaml, err := createYaml(i.Yaml, c.targetOrg, c.targetProject, c.mapping.transform(EntityInfrastructure))
+			if err != nil {
+				v.failed(EntityInfrastructure, id, i.Name, err)
+				continue
+			}
+			targetId := targetEnv + "/" + c.mapping.Identifier(EntityInfrastructure, i.Identifier)
+			v.check(EntityInfrastructure, id, i.Name, c.target.verifyWith(EntityInfrastructure, targetId, c.targetOrg, c.targetProject, nil, newYaml, false))
+		}
+	}
+	v.count(EntityInfrastructure, count, targetCount)
+	return nil
+}
+
+func (c InfrastructureContext) Export(tf *TerraformExport) error {
+
+	envs, err := c.source.listEnvironments(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, env := range envs {
+		e := env.Environment
+		infras, err := listInfraDef(c.source, c.sourceOrg, c.sourceProject, e.Identifier)
+		if err != nil {
+			return fmt.Errorf("infrastructures of environment %s: %w", e.Name, err)
+		}
+		envId := c.mapping.Identifier(EntityEnvironment, e.Identifier)
+		for _, infra := range infras {
+			i := infra.Infrastructure
+			if !c.filter.Match(EntityInfrastructure, e.Identifier+"/"+i.Identifier) {
+				continue
+			}
+			newYaml, err := createYaml(i.Yaml, c.targetOrg, c.targetProject, c.mapping.transform(EntityInfrastructure))
+			if err != nil {
+				return fmt.Errorf("infrastructure %s/%s: %w", e.Name, i.Name, err)
+			}
+			var description string
+			if i.Description != nil {
+				description = *i.Description
+			}
+			id := c.mapping.Identifier(EntityInfrastructure, i.Identifier)
+			tf.add(EntityInfrastructure, envId+"/"+id, "harness_platform_infrastructure", envId+"_"+id, tf.importId(envId, id), tf.entity(id, i.Name,
+				tfString("description", description),
+				tfReference("env_id", EntityEnvironment, envId),
+				tfString("type", i.Type),
+				tfString("deployment_type", i.DeploymentType),
+				tfYamlDoc("yaml", newYaml),
+			)...)
+		}
+	}
+	return nil
+}
+
+// Prune deletes the synced infrastructures missing in the source, including
+// the ones of a deleted environment
+func (c InfrastructureContext) Prune() error {
+
+	envs, err := c.source.listEnvironments(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for _, env := range envs {
+		e := env.Environment
+		infras, err := listInfraDef(c.source, c.sourceOrg, c.sourceProject, e.Identifier)
+		if err != nil {
+			return err
+		}
+		for _, infra := range infras {
+			ids = append(ids, e.Identifier+"/"+infra.Infrastructure.Identifier)
+		}
+	}
+	c.state.prune(EntityInfrastructure, ids, c.report, func(sourceId string) error {
+		return c.target.deleteSynced(c.mapping, EntityInfrastructure, sourceId, c.targetOrg, c.targetProject)
+	})
+	return nil
+}
+
 func listInfraDef(s *SourceRequest, org, project, envId string) ([]*model.InfraDefListContent, error) {
 
 	resp, err := s.Client.R().
@@ -118,6 +280,26 @@ func createInfrastructure(t *TargetRequest, infra *model.CreateInfrastructureReq
 	if err != nil {
 		return err
 	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
+
+func updateInfrastructure(t *TargetRequest, infra *model.CreateInfrastructureRequest) error {
+
+	resp, err := t.Client.R().
+		SetHeader("x-api-key", t.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(infra).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": t.Account,
+		}).
+		Put(t.Url + "/ng/api/infrastructures")
+	if err != nil {
+		return err
+	}
 	if resp.IsError() {
 		return handleErrorResponse(resp)
 	}
@@ -2,7 +2,9 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/Fernando-Dourado/harness-move-project/model"
 	"github.com/schollz/progressbar/v3"
@@ -15,6 +17,11 @@ type InputsetContext struct {
 	sourceProject string
 	targetOrg     string
 	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
 }
 
 func NewInputsetOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) InputsetContext {
@@ -25,6 +32,11 @@ func NewInputsetOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st
 		sourceProject: st.SourceProject,
 		targetOrg:     st.TargetOrg,
 		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
 	}
 }
 
@@ -39,6 +51,11 @@ func (c InputsetContext) Move() error {
 	var failed []string
 
 	for _, pipeline := range pipelines {
+		// INPUT SETS ARE ONLY COPIED WITH THEIR PIPELINE
+		if !c.filter.Match(EntityPipeline, pipeline.Identifier) {
+			bar.Add(1)
+			continue
+		}
 		inputsets, err := c.listInputsets(c.sourceOrg, c.sourceProject, pipeline.Identifier)
 		if err != nil {
 			failed = append(failed, fmt.Sprintf("Unable to list inputsets for pipeline %s [%s]", pipeline.Name, err))
@@ -48,14 +65,33 @@ func (c InputsetContext) Move() error {
 		bar.ChangeMax(bar.GetMax() + len(inputsets))
 
 		for _, inputset := range inputsets {
+			id := pipeline.Identifier + "/" + inputset.Identifier
+			if !c.filter.Match(EntityInputset, id) {
+				bar.Add(1)
+				continue
+			}
+			result := outcome{status: StatusFailed}
 			is, err := c.getInputset(c.sourceOrg, c.sourceProject, pipeline.Identifier, inputset.Identifier)
 			if err == nil {
-				newYaml := createYaml(is.Yaml, c.sourceOrg, c.sourceProject, c.targetOrg, c.targetProject)
-				err = c.createInputset(c.targetOrg, c.targetProject, pipeline.Identifier, newYaml)
+				var newYaml string
+				if newYaml, err = createYaml(is.Yaml, c.targetOrg, c.targetProject, c.mapping.transform(EntityInputset)); err == nil {
+					pipelineId := c.mapping.Identifier(EntityPipeline, pipeline.Identifier)
+					inputsetId := c.mapping.Identifier(EntityInputset, inputset.Identifier)
+					result, err = c.state.upsert(c.onConflict, EntityInputset, id, newYaml,
+						func() error { return c.createInputset(c.targetOrg, c.targetProject, pipelineId, newYaml) },
+						func() error {
+							return c.updateInputset(c.targetOrg, c.targetProject, pipelineId, inputsetId, newYaml)
+						},
+						c.target.compareWith(EntityInputset, inputsetId, c.targetOrg, c.targetProject, map[string]string{
+							"pipelineIdentifier": pipelineId,
+						}, newYaml),
+					)
+				}
 			}
 			if err != nil {
 				failed = append(failed, fmt.Sprintln(pipeline.Name, "/", err.Error()))
 			}
+			c.report.recordOutcome(EntityInputset, id, inputset.Name, result, err)
 			bar.Add(1)
 		}
 		bar.Add(1)
@@ -66,6 +102,158 @@ func (c InputsetContext) Move() error {
 	return nil
 }
 
+func (c InputsetContext) Scan(g *DependencyGraph) error {
+
+	pipelines, err := c.source.listPipelines(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, pipeline := range pipelines {
+		if !c.filter.Match(EntityPipeline, pipeline.Identifier) {
+			continue
+		}
+		inputsets, err := c.listInputsets(c.sourceOrg, c.sourceProject, pipeline.Identifier)
+		if err != nil {
+			return err
+		}
+		for _, inputset := range inputsets {
+			if !c.filter.Match(EntityInputset, pipeline.Identifier+"/"+inputset.Identifier) {
+				continue
+			}
+			is, err := c.getInputset(c.sourceOrg, c.sourceProject, pipeline.Identifier, inputset.Identifier)
+			if err != nil {
+				g.AddUnscanned(EntityInputset, pipeline.Identifier+"/"+inputset.Identifier, inputset.Name, err)
+				continue
+			}
+			refs, err := extractReferences(is.Yaml)
+			if err != nil {
+				g.AddUnscanned(EntityInputset, pipeline.Identifier+"/"+inputset.Identifier, inputset.Name, err)
+				continue
+			}
+			refs = append(refs, EntityRef{Type: EntityPipeline, Scope: ScopeProject, Identifier: pipeline.Identifier})
+			g.Add(EntityInputset, pipeline.Identifier+"/"+inputset.Identifier, inputset.Name, refs)
+		}
+	}
+	return nil
+}
+
+func (c InputsetContext) Verify(v *Verification) error {
+
+	pipelines, err := c.source.listPipelines(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	target := c
+	target.source = c.target.asSource()
+
+	count, targetCount := 0, 0
+	for _, pipeline := range pipelines {
+		if !c.filter.Match(EntityPipeline, pipeline.Identifier) {
+			continue
+		}
+		inputsets, err := c.listInputsets(c.sourceOrg, c.sourceProject, pipeline.Identifier)
+		if err != nil {
+			return err
+		}
+		pipelineId := c.mapping.Identifier(EntityPipeline, pipeline.Identifier)
+		targetInputsets, err := target.listInputsets(c.targetOrg, c.targetProject, pipelineId)
+		if err != nil && !errors.Is(err, ErrEntityNotFound) {
+			return err
+		}
+		targetCount += len(targetInputsets)
+
+		for _, inputset := range inputsets {
+			id := pipeline.Identifier + "/" + inputset.Identifier
+			if !c.filter.Match(EntityInputset, id) {
+				continue
+			}
+			count++
+			is, err := c.getInputset(c.sourceOrg, c.sourceProject, pipeline.Identifier, inputset.Identifier)
+			if err != nil {
+				v.failed(EntityInputset, id, inputset.Name, err)
+				continue
+			}
+			newYaml, err := createYaml(is.Yaml, c.targetOrg, c.targetProject, c.mapping.transform(EntityInputset))
+			if err != nil {
+				v.failed(EntityInputset, id, inputset.Name, err)
+				continue
+			}
+			inputsetId := c.mapping.Identifier(EntityInputset, inputset.Identifier)
+			v.check(EntityInputset, id, inputset.Name, c.target.verifyWith(EntityInputset, inputsetId, c.targetOrg, c.targetProject, map[string]string{
+				"pipelineIdentifier": pipelineId,
+			}, newYaml, true))
+		}
+	}
+	v.count(EntityInputset, count, targetCount)
+	return nil
+}
+
+func (c InputsetContext) Export(tf *TerraformExport) error {
+
+	pipelines, err := c.source.listPipelines(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, pipeline := range pipelines {
+		// INPUT SETS ARE ONLY EXPORTED WITH THEIR PIPELINE
+		if !c.filter.Match(EntityPipeline, pipeline.Identifier) {
+			continue
+		}
+		inputsets, err := c.listInputsets(c.sourceOrg, c.sourceProject, pipeline.Identifier)
+		if err != nil {
+			return fmt.Errorf("inputsets of pipeline %s: %w", pipeline.Name, err)
+		}
+		pipelineId := c.mapping.Identifier(EntityPipeline, pipeline.Identifier)
+		for _, inputset := range inputsets {
+			if !c.filter.Match(EntityInputset, pipeline.Identifier+"/"+inputset.Identifier) {
+				continue
+			}
+			is, err := c.getInputset(c.sourceOrg, c.sourceProject, pipeline.Identifier, inputset.Identifier)
+			if err != nil {
+				return fmt.Errorf("inputset %s/%s: %w", pipeline.Name, inputset.Name, err)
+			}
+			newYaml, err := createYaml(is.Yaml, c.targetOrg, c.targetProject, c.mapping.transform(EntityInputset))
+			if err != nil {
+				return fmt.Errorf("inputset %s/%s: %w", pipeline.Name, inputset.Name, err)
+			}
+			id := c.mapping.Identifier(EntityInputset, inputset.Identifier)
+			tf.add(EntityInputset, "", "harness_platform_input_set", pipelineId+"_"+id, tf.importId(pipelineId, id), tf.entity(id, inputset.Name,
+				tfReference("pipeline_id", EntityPipeline, pipelineId),
+				tfYamlDoc("yaml", newYaml),
+			)...)
+		}
+	}
+	return nil
+}
+
+// Prune deletes the synced input sets missing in the source, including the
+// ones of a deleted pipeline
+func (c InputsetContext) Prune() error {
+
+	pipelines, err := c.source.listPipelines(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for _, pipeline := range pipelines {
+		inputsets, err := c.listInputsets(c.sourceOrg, c.sourceProject, pipeline.Identifier)
+		if err != nil {
+			return err
+		}
+		for _, inputset := range inputsets {
+			ids = append(ids, pipeline.Identifier+"/"+inputset.Identifier)
+		}
+	}
+	c.state.prune(EntityInputset, ids, c.report, func(sourceId string) error {
+		pipeline, inputset, _ := strings.Cut(sourceId, "/")
+		ref := EntityRef{Type: EntityInputset, Scope: ScopeProject, Identifier: c.mapping.Identifier(EntityInputset, inputset)}
+		return c.target.deleteEntity(ref, c.targetOrg, c.targetProject, map[string]string{
+			"pipelineIdentifier": c.mapping.Identifier(EntityPipeline, pipeline),
+		})
+	})
+	return nil
+}
+
 func (c InputsetContext) listInputsets(org, project, pipelineIdentifier string) ([]*model.ListInputsetContent, error) {
 
 	api := c.source
@@ -144,6 +332,30 @@ func (c InputsetContext) createInputset(org, project, pipelineIdentifier, yaml s
 	if err != nil {
 		return err
 	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
+
+func (c InputsetContext) updateInputset(org, project, pipelineIdentifier, isIdentifier, yaml string) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/yaml").
+		SetBody(yaml).
+		SetQueryParams(map[string]string{
+			"accountIdentifier":  api.Account,
+			"orgIdentifier":      org,
+			"projectIdentifier":  project,
+			"pipelineIdentifier": pipelineIdentifier,
+		}).
+		Put(api.Url + "/pipeline/api/inputSets/" + isIdentifier)
+	if err != nil {
+		return err
+	}
 	if resp.IsError() {
 		return handleErrorResponse(resp)
 	}
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/harness/harness-go-sdk/harness/nextgen"
+	"gopkg.in/yaml.v3"
+)
+
+// RenameRule changes the identifier of the entities of one type. An explicit
+// rename wins over the prefix and suffix. Promote creates the listed templates
+// at the org or account scope of the target, instead of the project. Reuse
+// points the references to an entity already in the target, written like
+// "org.github", instead of copying it.
+type RenameRule struct {
+	Prefix  string            `yaml:"prefix,omitempty"`
+	Suffix  string            `yaml:"suffix,omitempty"`
+	Rename  map[string]string `yaml:"rename,omitempty"`
+	Promote map[string]Scope  `yaml:"promote,omitempty"`
+	Reuse   map[string]string `yaml:"reuse,omitempty"`
+}
+
+// reuseTypes are the types whose entities can be shared from the target org
+// or account
+var reuseTypes = map[EntityType]bool{
+	EntityConnector: true,
+	EntitySecret:    true,
+	EntityTemplate:  true,
+}
+
+// UnmarshalYAML also reads the rule as "<identifier> -> <target reference>", or
+// a list of them, reusing the target entities
+func (r *RenameRule) UnmarshalYAML(value *yaml.Node) error {
+	var lines []string
+	switch value.Kind {
+	case yaml.ScalarNode:
+		lines = []string{value.Value}
+	case yaml.SequenceNode:
+		if err := value.Decode(&lines); err != nil {
+			return err
+		}
+	default:
+		type plain RenameRule
+		return value.Decode((*plain)(r))
+	}
+
+	r.Reuse = map[string]string{}
+	for _, line := range lines {
+		source, target, found := strings.Cut(line, "->")
+		source, target = strings.TrimSpace(source), strings.TrimSpace(target)
+		if !found || len(source) == 0 || len(target) == 0 {
+			return fmt.Errorf("invalid reuse %q, expected <identifier> -> <org.|account.><identifier>", line)
+		}
+		r.Reuse[source] = target
+	}
+	return nil
+}
+
+// IdentifierMapping holds the rename rules by entity type. A nil mapping keeps
+// every identifier.
+type IdentifierMapping map[EntityType]*RenameRule
+
+func LoadIdentifierMapping(path string) (IdentifierMapping, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	mapping := IdentifierMapping{}
+	if err := yaml.Unmarshal(b, &mapping); err != nil {
+		return nil, fmt.Errorf("mapping file %s: %w", path, err)
+	}
+	if err := mapping.Validate(); err != nil {
+		return nil, fmt.Errorf("mapping file %s: %w", path, err)
+	}
+	return mapping, nil
+}
+
+func (m IdentifierMapping) Validate() error {
+	for t, rule := range m {
+		if _, found := entityTypes[t]; !found {
+			return fmt.Errorf("unknown entity type %s", t)
+		}
+		if rule == nil {
+			continue
+		}
+		if len(rule.Reuse) > 0 && !reuseTypes[t] {
+			return fmt.Errorf("only connectors, secrets and templates can be reused, not %s", t)
+		}
+		for id, value := range rule.Reuse {
+			if _, ok := newEntityRef(t, value); !ok {
+				return fmt.Errorf("%s %s reuses invalid reference %q", t, id, value)
+			}
+		}
+		if len(rule.Promote) == 0 {
+			continue
+		}
+		if t != EntityTemplate {
+			return fmt.Errorf("only templates can be promoted, not %s", t)
+		}
+		for id, scope := range rule.Promote {
+			if scope != ScopeOrg && scope != ScopeAccount {
+				return fmt.Errorf("template %s promoted to unknown scope %s, expected org or account", id, scope)
+			}
+		}
+	}
+	return nil
+}
+
+// Identifier returns the target identifier of a project entity
+func (m IdentifierMapping) Identifier(t EntityType, identifier string) string {
+	rule := m[t]
+	if rule == nil || len(identifier) == 0 {
+		return identifier
+	}
+	if renamed, found := rule.Rename[identifier]; found {
+		return renamed
+	}
+	return rule.Prefix + identifier + rule.Suffix
+}
+
+// Scope returns the scope of the project entity in the target, the promoted
+// templates leave the project
+func (m IdentifierMapping) Scope(t EntityType, identifier string) Scope {
+	if rule := m[t]; rule != nil {
+		if scope, found := rule.Promote[identifier]; found {
+			return scope
+		}
+	}
+	return ScopeProject
+}
+
+// Reused returns the target entity replacing the project entity, which is not
+// copied
+func (m IdentifierMapping) Reused(t EntityType, identifier string) (EntityRef, bool) {
+	rule := m[t]
+	if rule == nil {
+		return EntityRef{}, false
+	}
+	value, found := rule.Reuse[identifier]
+	if !found {
+		return EntityRef{}, false
+	}
+	return newEntityRef(t, value)
+}
+
+func (m IdentifierMapping) reused(t EntityType, identifier string) bool {
+	_, found := m.Reused(t, identifier)
+	return found
+}
+
+// recordReused reports the entity skipped because a target entity is used
+// instead
+func (r *Report) recordReused(t EntityType, identifier, name string, target EntityRef) {
+	r.recordOutcome(t, identifier, name, outcome{status: StatusSkipped, message: "reuses " + target.value()}, nil)
+}
+
+// Ref returns the reference pointing to the target identifier and scope. Only
+// project references are mapped, org and account entities are not copied.
+func (m IdentifierMapping) Ref(ref EntityRef) EntityRef {
+	if ref.Scope != ScopeProject {
+		return ref
+	}
+	if reused, found := m.Reused(ref.Type, ref.Identifier); found {
+		return reused
+	}
+	if ref.Type == EntityInfrastructure {
+		env, infra, _ := strings.Cut(ref.Identifier, "/")
+		ref.Identifier = m.Identifier(EntityEnvironment, env) + "/" + m.Identifier(EntityInfrastructure, infra)
+		return ref
+	}
+	ref.Scope = m.Scope(ref.Type, ref.Identifier)
+	ref.Identifier = m.Identifier(ref.Type, ref.Identifier)
+	return ref
+}
+
+// refValue maps a reference written as in YAML, keeping its scope prefix
+func (m IdentifierMapping) refValue(t EntityType, value string) string {
+	ref, ok := newEntityRef(t, value)
+	if !ok {
+		return value
+	}
+	return m.Ref(ref).value()
+}
+
+// transform renames the root entity of the given type and rewrites every
+// reference in the document
+func (m IdentifierMapping) transform(t EntityType) yamlTransform {
+	return func(doc *yaml.Node) error {
+		if len(m) == 0 {
+			return nil
+		}
+		entity, err := entityNode(doc)
+		if err != nil {
+			return err
+		}
+		if id := mappingValue(entity, "identifier"); id != nil && id.Kind == yaml.ScalarNode {
+			setScalar(id, m.Identifier(t, id.Value))
+		}
+		// THE INPUT SET KEEPS THE IDENTIFIER OF ITS PIPELINE
+		if t == EntityInputset {
+			if id := mappingValue(mappingValue(entity, "pipeline"), "identifier"); id != nil && id.Kind == yaml.ScalarNode {
+				setScalar(id, m.Identifier(EntityPipeline, id.Value))
+			}
+		}
+		m.rewriteReferences(doc)
+		return nil
+	}
+}
+
+func (m IdentifierMapping) rewriteReferences(doc *yaml.Node) {
+	visitReferences(doc, func(ref EntityRef, value *yaml.Node) {
+		mapped := m.Ref(ref)
+		if mapped == ref || ref.Type == EntityFileStore {
+			return
+		}
+		if ref.Type == EntityInfrastructure {
+			_, infra, _ := strings.Cut(mapped.Identifier, "/")
+			value.Value = infra
+			return
+		}
+		value.Value = mapped.value()
+	})
+	walkScalars(doc, func(n *yaml.Node) {
+		n.Value = m.rewriteExpressions(n.Value)
+	})
+}
+
+// connector renames the connector and the secrets and connectors it uses
+func (m IdentifierMapping) connector(conn *nextgen.ConnectorInfo) (*nextgen.ConnectorInfo, error) {
+	if len(m) == 0 {
+		return conn, nil
+	}
+	root, err := connectorNode(conn)
+	if err != nil {
+		return nil, err
+	}
+	visitConnectorReferences(root, func(ref EntityRef, value *yaml.Node) {
+		value.Value = m.Ref(ref).value()
+	})
+
+	var v interface{}
+	if err := root.Decode(&v); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	out := &nextgen.ConnectorInfo{}
+	if err := json.Unmarshal(b, out); err != nil {
+		return nil, err
+	}
+	out.Identifier = m.Identifier(EntityConnector, conn.Identifier)
+	return out, nil
+}
+
+// rewriteEntity returns a copy of the entity, encoded as JSON, with its
+// references renamed
+func (m IdentifierMapping) rewriteEntity(entity map[string]interface{}) (map[string]interface{}, error) {
+	root, err := jsonNode(entity)
+	if err != nil {
+		return nil, err
+	}
+	m.rewriteReferences(root)
+	out := map[string]interface{}{}
+	if err := root.Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// rewriteExpressions renames the secrets used by <+secrets.getValue()>
+func (m IdentifierMapping) rewriteExpressions(value string) string {
+	if len(m) == 0 {
+		return value
+	}
+	return secretExpression.ReplaceAllStringFunc(value, func(expr string) string {
+		match := secretExpression.FindStringSubmatch(expr)
+		ref, ok := newEntityRef(EntitySecret, match[1])
+		if !ok {
+			return expr
+		}
+		return strings.Replace(expr, match[1], m.Ref(ref).value(), 1)
+	})
+}
+
+// walkScalars calls fn for every scalar node in the tree
+func walkScalars(n *yaml.Node, fn func(n *yaml.Node)) {
+	if n == nil {
+		return
+	}
+	if n.Kind == yaml.ScalarNode {
+		fn(n)
+	}
+	for _, c := range n.Content {
+		walkScalars(c, fn)
+	}
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+const TEST_MAPPING_PIPELINE_YAML = "pipeline:\n  identifier: deploy\n  orgIdentifier: default\n  projectIdentifier: FernandoD\n  stages:\n    - stage:\n        identifier: deploy\n        spec:\n          service:\n            serviceRef: api\n          environment:\n            environmentRef: prod\n            infrastructureDefinitions:\n              - identifier: k8s\n          execution:\n            steps:\n              - step:\n                  identifier: script\n                  spec:\n                    connectorRef: prod_k8s\n                    script: echo <+secrets.getValue(\"token\")> <+secrets.getValue(\"org.token\")>\n"
+
+func TestMappingTransform(t *testing.T) {
+	mapping := IdentifierMapping{
+		EntityConnector:      {Rename: map[string]string{"prod_k8s": "k8s_prod"}},
+		EntitySecret:         {Prefix: "legacy_"},
+		EntityPipeline:       {Suffix: "_v2"},
+		EntityInfrastructure: {Prefix: "infra_"},
+	}
+	expected := "pipeline:\n  identifier: deploy_v2\n  orgIdentifier: non_default\n  projectIdentifier: DouradoF\n  stages:\n    - stage:\n        identifier: deploy\n        spec:\n          service:\n            serviceRef: api\n          environment:\n            environmentRef: prod\n            infrastructureDefinitions:\n              - identifier: infra_k8s\n          execution:\n            steps:\n              - step:\n                  identifier: script\n                  spec:\n                    connectorRef: k8s_prod\n                    script: echo <+secrets.getValue(\"legacy_token\")> <+secrets.getValue(\"org.token\")>\n"
+
+	yaml, err := createYaml(TEST_MAPPING_PIPELINE_YAML, "non_default", "DouradoF", mapping.transform(EntityPipeline))
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, yaml)
+}
+
+func TestMappingRef_KeepOrgAndAccount(t *testing.T) {
+	mapping := IdentifierMapping{
+		EntityConnector: {Prefix: "legacy_"},
+	}
+
+	assert.Equal(t, "legacy_github", mapping.refValue(EntityConnector, "github"))
+	assert.Equal(t, "org.github", mapping.refValue(EntityConnector, "org.github"))
+	assert.Equal(t, "account.github", mapping.refValue(EntityConnector, "account.github"))
+	assert.Equal(t, "<+input>", mapping.refValue(EntityConnector, "<+input>"))
+}
+
+func TestMappingTransform_PromotedTemplates(t *testing.T) {
+	mapping := IdentifierMapping{
+		EntityTemplate: {Prefix: "shared_", Promote: map[string]Scope{"build": ScopeOrg, "scan": ScopeAccount}},
+	}
+	doc := "pipeline:\n  identifier: deploy\n  stages:\n    - stage:\n        template:\n          templateRef: build\n    - stage:\n        template:\n          templateRef: scan\n    - stage:\n        template:\n          templateRef: deploy\n"
+
+	yaml, err := createYaml(doc, "other", "DouradoF", mapping.transform(EntityPipeline))
+
+	assert.NoError(t, err)
+	assert.Contains(t, yaml, "          templateRef: org.shared_build\n")
+	assert.Contains(t, yaml, "          templateRef: account.shared_scan\n")
+	assert.Contains(t, yaml, "          templateRef: shared_deploy\n")
+}
+
+func TestMappingValidate_Promote(t *testing.T) {
+	assert.NoError(t, IdentifierMapping{EntityTemplate: {Promote: map[string]Scope{"build": ScopeOrg}}}.Validate())
+	assert.Error(t, IdentifierMapping{EntityTemplate: {Promote: map[string]Scope{"build": ScopeProject}}}.Validate())
+	assert.Error(t, IdentifierMapping{EntityConnector: {Promote: map[string]Scope{"github": ScopeOrg}}}.Validate())
+}
+
+func TestTemplateYaml_Promoted(t *testing.T) {
+	c := TemplateContext{
+		targetOrg:     "other",
+		targetProject: "DouradoF",
+		mapping:       IdentifierMapping{EntityTemplate: {Promote: map[string]Scope{"build": ScopeOrg, "step": ScopeOrg}}},
+	}
+	doc := "template:\n  identifier: build\n  orgIdentifier: default\n  projectIdentifier: FernandoD\n  spec:\n    stages:\n      - stage:\n          template:\n            templateRef: step\n"
+
+	ref, org, project := c.targetRef("build")
+	assert.Equal(t, EntityRef{Type: EntityTemplate, Scope: ScopeOrg, Identifier: "build"}, ref)
+	yaml, err := c.templateYaml(doc, ref, org, project)
+	assert.NoError(t, err)
+	assert.Equal(t, "template:\n  identifier: build\n  orgIdentifier: other\n  spec:\n    stages:\n      - stage:\n          template:\n            templateRef: org.step\n", yaml)
+
+	// THE PROJECT CONNECTOR IS NOT VISIBLE FROM THE ORG
+	_, err = c.templateYaml(doc+"    connectorRef: github\n", ref, org, project)
+	assert.Error(t, err)
+}
+
+func TestMappingTransform_FreezeRules(t *testing.T) {
+	mapping := IdentifierMapping{EntityService: {Prefix: "legacy_"}}
+
+	yaml, err := createYaml(TEST_FREEZE_YAML, "other", "DouradoF", mapping.transform(EntityFreeze))
+
+	assert.NoError(t, err)
+	assert.Contains(t, yaml, "            - legacy_api\n            - legacy_web\n")
+	assert.Contains(t, yaml, "            - org.prod\n")
+	assert.Contains(t, yaml, "  status: Enabled\n")
+	assert.Contains(t, yaml, "  orgIdentifier: other\n")
+}
+
+func TestMappingRewriteEntity_MonitoredService(t *testing.T) {
+	mapping := IdentifierMapping{
+		EntityConnector:   {Prefix: "legacy_"},
+		EntityEnvironment: {Rename: map[string]string{"prod": "production"}},
+	}
+	entity := map[string]interface{}{
+		"identifier":         "api_prod",
+		"serviceRef":         "api",
+		"environmentRef":     "prod",
+		"environmentRefList": []interface{}{"prod"},
+		"sources": map[string]interface{}{
+			"healthSources": []interface{}{
+				map[string]interface{}{"identifier": "prometheus", "spec": map[string]interface{}{"connectorRef": "prom", "metricDefinitions": []interface{}{}}},
+			},
+		},
+	}
+
+	out, err := mapping.rewriteEntity(entity)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "production", out["environmentRef"])
+	assert.Equal(t, []interface{}{"production"}, out["environmentRefList"])
+	spec := out["sources"].(map[string]interface{})["healthSources"].([]interface{})[0].(map[string]interface{})["spec"].(map[string]interface{})
+	assert.Equal(t, "legacy_prom", spec["connectorRef"])
+	assert.Equal(t, "prod", entity["environmentRef"])
+}
+
+func TestSLOToTarget_CompositeScope(t *testing.T) {
+	c := SLOContext{
+		target:        &TargetRequest{Account: "acc2"},
+		sourceOrg:     "default",
+		sourceProject: "FernandoD",
+		targetOrg:     "other",
+		targetProject: "DouradoF",
+		mapping:       IdentifierMapping{EntitySLO: {Suffix: "_v2"}},
+	}
+	entity := map[string]interface{}{
+		"identifier": "composite",
+		"spec": map[string]interface{}{
+			"serviceLevelObjectivesDetails": []interface{}{
+				map[string]interface{}{"serviceLevelObjectiveRef": "latency", "accountId": "acc1", "orgIdentifier": "default", "projectIdentifier": "FernandoD"},
+				map[string]interface{}{"serviceLevelObjectiveRef": "uptime", "accountId": "acc1", "orgIdentifier": "default", "projectIdentifier": "shared"},
+			},
+		},
+	}
+
+	out, err := c.toTarget(entity)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "composite_v2", out["identifier"])
+	details := out["spec"].(map[string]interface{})["serviceLevelObjectivesDetails"].([]interface{})
+	assert.Equal(t, map[string]interface{}{"serviceLevelObjectiveRef": "latency_v2", "accountId": "acc2", "orgIdentifier": "other", "projectIdentifier": "DouradoF"}, details[0])
+	assert.Equal(t, "shared", details[1].(map[string]interface{})["projectIdentifier"])
+}
+
+func TestLoadIdentifierMapping_Reuse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mapping.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`connector: github_src -> org.github_shared
+secret:
+  - token -> account.shared_token
+  - org_token -> org.token
+template:
+  prefix: legacy_
+  reuse:
+    build: org.build
+`), 0644))
+
+	mapping, err := LoadIdentifierMapping(path)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"github_src": "org.github_shared"}, mapping[EntityConnector].Reuse)
+	assert.Equal(t, "org.github_shared", mapping.refValue(EntityConnector, "github_src"))
+	assert.Equal(t, "other", mapping.refValue(EntityConnector, "other"))
+	assert.Equal(t, "org.build", mapping.refValue(EntityTemplate, "build"))
+	assert.Equal(t, "legacy_deploy", mapping.refValue(EntityTemplate, "deploy"))
+	assert.Equal(t, `echo <+secrets.getValue("account.shared_token")>`, mapping.rewriteExpressions(`echo <+secrets.getValue("token")>`))
+}
+
+func TestMappingValidate_Reuse(t *testing.T) {
+	assert.Error(t, IdentifierMapping{EntityService: {Reuse: map[string]string{"api": "org.api"}}}.Validate())
+	assert.Error(t, IdentifierMapping{EntityConnector: {Reuse: map[string]string{"github": "<+input>"}}}.Validate())
+
+	var rule RenameRule
+	assert.Error(t, yaml.Unmarshal([]byte(`github_src org.github_shared`), &rule))
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/schollz/progressbar/v3"
+)
+
+type MonitoredServiceContext struct {
+	source        *SourceRequest
+	target        *TargetRequest
+	sourceOrg     string
+	sourceProject string
+	targetOrg     string
+	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
+}
+
+func NewMonitoredServiceOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) MonitoredServiceContext {
+	return MonitoredServiceContext{
+		source:        sourceApi,
+		target:        targetApi,
+		sourceOrg:     st.SourceOrg,
+		sourceProject: st.SourceProject,
+		targetOrg:     st.TargetOrg,
+		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
+	}
+}
+
+func (c MonitoredServiceContext) Move() error {
+
+	services, err := listMonitoredServices(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+
+	bar := progressbar.Default(int64(len(services)), "Monitored Services")
+	var failed []string
+
+	for _, ms := range services {
+		if !c.filter.Match(EntityMonitoredService, ms.Identifier) {
+			bar.Add(1)
+			continue
+		}
+		result := outcome{status: StatusFailed}
+		entity, err := c.getMonitoredService(ms.Identifier)
+		if err == nil {
+			if entity, err = c.toTarget(entity); err == nil {
+				targetId := c.mapping.Identifier(EntityMonitoredService, ms.Identifier)
+				result, err = c.state.upsert(c.onConflict, EntityMonitoredService, ms.Identifier, entity,
+					func() error { return c.createMonitoredService(entity) },
+					func() error { return c.updateMonitoredService(targetId, entity) },
+					c.target.compareWith(EntityMonitoredService, targetId, c.targetOrg, c.targetProject, srmParams(c.target.Account), entity),
+				)
+			}
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintln(ms.Name, "-", err.Error()))
+		}
+		c.report.recordOutcome(EntityMonitoredService, ms.Identifier, ms.Name, result, err)
+		bar.Add(1)
+	}
+	bar.Finish()
+
+	reportFailed(failed, "monitored services:")
+	return nil
+}
+
+func (c MonitoredServiceContext) Scan(g *DependencyGraph) error {
+
+	services, err := listMonitoredServices(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, ms := range services {
+		if !c.filter.Match(EntityMonitoredService, ms.Identifier) {
+			continue
+		}
+		entity, err := c.getMonitoredService(ms.Identifier)
+		if err != nil {
+			g.AddUnscanned(EntityMonitoredService, ms.Identifier, ms.Name, err)
+			continue
+		}
+		refs, err := extractEntityReferences(entity)
+		if err != nil {
+			g.AddUnscanned(EntityMonitoredService, ms.Identifier, ms.Name, err)
+			continue
+		}
+		g.Add(EntityMonitoredService, ms.Identifier, ms.Name, refs)
+	}
+	return nil
+}
+
+func (c MonitoredServiceContext) Verify(v *Verification) error {
+
+	services, err := listMonitoredServices(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	targetServices, err := listMonitoredServices(c.target.asSource(), c.targetOrg, c.targetProject)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, ms := range services {
+		if !c.filter.Match(EntityMonitoredService, ms.Identifier) {
+			continue
+		}
+		count++
+		entity, err := c.getMonitoredService(ms.Identifier)
+		if err == nil {
+			entity, err = c.toTarget(entity)
+		}
+		if err != nil {
+			v.failed(EntityMonitoredService, ms.Identifier, ms.Name, err)
+			continue
+		}
+		targetId := c.mapping.Identifier(EntityMonitoredService, ms.Identifier)
+		v.check(EntityMonitoredService, ms.Identifier, ms.Name, c.target.verifyWith(EntityMonitoredService, targetId, c.targetOrg, c.targetProject, srmParams(c.target.Account), entity, false))
+	}
+	v.count(EntityMonitoredService, count, len(targetServices))
+	return nil
+}
+
+// toTarget renames the monitored service and the service, environments and
+// connectors of its health and change sources. The notification rules are
+// not copied, so they are unlinked.
+func (c MonitoredServiceContext) toTarget(entity map[string]interface{}) (map[string]interface{}, error) {
+	out, err := c.mapping.rewriteEntity(entity)
+	if err != nil {
+		return nil, err
+	}
+	if id, ok := out["identifier"].(string); ok {
+		out["identifier"] = c.mapping.Identifier(EntityMonitoredService, id)
+	}
+	out["orgIdentifier"] = c.targetOrg
+	out["projectIdentifier"] = c.targetProject
+	delete(out, "notificationRuleRefs")
+	return out, nil
+}
+
+// srmParams are the query parameters of the SRM API, which reads the account
+// from accountId
+func srmParams(account string) map[string]string {
+	return map[string]string{"accountId": account}
+}
+
+func listMonitoredServices(s *SourceRequest, org, project string) ([]*model.MonitoredServiceListItem, error) {
+
+	resp, err := s.Client.R().
+		SetHeader("x-api-key", s.Token).
+		SetHeader("Content-Type", "application/json").
+		SetQueryParams(map[string]string{
+			"accountId":         s.Account,
+			"orgIdentifier":     org,
+			"projectIdentifier": project,
+			"offset":            "0",
+			"pageSize":          "1000",
+		}).
+		Get(s.Url + "/cv/api/monitored-service")
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, handleErrorResponse(resp)
+	}
+
+	result := model.ListMonitoredServiceResponse{}
+	err = json.Unmarshal(resp.Body(), &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Data.Content, nil
+}
+
+func (c MonitoredServiceContext) getMonitoredService(identifier string) (map[string]interface{}, error) {
+
+	api := c.source
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetQueryParams(map[string]string{
+			"accountId":         api.Account,
+			"orgIdentifier":     c.sourceOrg,
+			"projectIdentifier": c.sourceProject,
+		}).
+		Get(api.Url + "/cv/api/monitored-service/" + identifier)
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, handleErrorResponse(resp)
+	}
+
+	result := model.GetMonitoredServiceResponse{}
+	err = json.Unmarshal(resp.Body(), &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Data.MonitoredService, nil
+}
+
+func (c MonitoredServiceContext) createMonitoredService(entity map[string]interface{}) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(entity).
+		SetQueryParams(map[string]string{
+			"accountId": api.Account,
+		}).
+		Post(api.Url + "/cv/api/monitored-service")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
+
+func (c MonitoredServiceContext) updateMonitoredService(identifier string, entity map[string]interface{}) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(entity).
+		SetQueryParams(map[string]string{
+			"accountId": api.Account,
+		}).
+		Put(api.Url + "/cv/api/monitored-service/" + identifier)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleErrorResponse(resp)
+	}
+
+	return nil
+}
@@ -0,0 +1,272 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/schollz/progressbar/v3"
+)
+
+const NOTIFICATION_CHANNELS_ENDPOINT = "/v1/orgs/{org}/projects/{project}/notification-channels"
+
+type NotificationChannelContext struct {
+	source        *SourceRequest
+	target        *TargetRequest
+	sourceOrg     string
+	sourceProject string
+	targetOrg     string
+	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
+}
+
+func NewNotificationChannelOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) NotificationChannelContext {
+	return NotificationChannelContext{
+		source:        sourceApi,
+		target:        targetApi,
+		sourceOrg:     st.SourceOrg,
+		sourceProject: st.SourceProject,
+		targetOrg:     st.TargetOrg,
+		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
+	}
+}
+
+func (c NotificationChannelContext) Move() error {
+
+	channels, err := listNotificationChannels(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	targetChannels, err := c.targetChannels()
+	if err != nil {
+		return err
+	}
+
+	bar := progressbar.Default(int64(len(channels)), "Notification Channels")
+	var failed []string
+
+	for _, ch := range channels {
+		if !c.filter.Match(EntityNotificationChannel, ch.Identifier) {
+			bar.Add(1)
+			continue
+		}
+		sourceId := ch.Identifier
+		c.toTarget(ch)
+
+		current, found := targetChannels[ch.Identifier]
+		result, err := c.state.upsert(c.onConflict, EntityNotificationChannel, sourceId, ch,
+			func() error { return c.createChannel(ch) },
+			func() error { return c.updateChannel(ch) },
+			compareListed(current, ch, found),
+		)
+		if err != nil {
+			failed = append(failed, fmt.Sprintln(ch.Name, "-", err.Error()))
+		}
+		c.report.recordOutcome(EntityNotificationChannel, sourceId, ch.Name, result, err)
+		bar.Add(1)
+	}
+	bar.Finish()
+
+	reportFailed(failed, "notification channels:")
+	return nil
+}
+
+func (c NotificationChannelContext) Scan(g *DependencyGraph) error {
+
+	channels, err := listNotificationChannels(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, ch := range channels {
+		if !c.filter.Match(EntityNotificationChannel, ch.Identifier) {
+			continue
+		}
+		g.Add(EntityNotificationChannel, ch.Identifier, ch.Name, channelReferences(ch))
+	}
+	return nil
+}
+
+func (c NotificationChannelContext) Verify(v *Verification) error {
+
+	channels, err := listNotificationChannels(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	targetChannels, err := c.targetChannels()
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, ch := range channels {
+		if !c.filter.Match(EntityNotificationChannel, ch.Identifier) {
+			continue
+		}
+		count++
+		sourceId := ch.Identifier
+		c.toTarget(ch)
+		current, found := targetChannels[ch.Identifier]
+		v.check(EntityNotificationChannel, sourceId, ch.Name, compareListed(current, ch, found))
+	}
+	v.count(EntityNotificationChannel, count, len(targetChannels))
+	return nil
+}
+
+// toTarget moves the channel to the target project and renames its user
+// groups and the secrets of its URLs, keys and headers
+func (c NotificationChannelContext) toTarget(ch *model.NotificationChannel) {
+	ch.Identifier = c.mapping.Identifier(EntityNotificationChannel, ch.Identifier)
+	ch.Org = c.targetOrg
+	ch.Project = c.targetProject
+	spec := ch.Channel
+	if spec == nil {
+		return
+	}
+	for _, values := range [][]string{spec.SlackWebhookUrls, spec.WebhookUrls, spec.EmailIds, spec.PagerDutyIntegrationKeys, spec.MsTeamKeys} {
+		for i, value := range values {
+			values[i] = c.mapping.rewriteExpressions(value)
+		}
+	}
+	for _, h := range spec.Headers {
+		h.Value = c.mapping.rewriteExpressions(h.Value)
+	}
+	for _, ug := range spec.UserGroups {
+		ug.Identifier = c.mapping.refValue(EntityUserGroup, ug.Identifier)
+	}
+}
+
+// channelReferences returns the user groups and secrets of the channel
+func channelReferences(ch *model.NotificationChannel) []EntityRef {
+	refs := newRefSet()
+	spec := ch.Channel
+	if spec == nil {
+		return nil
+	}
+	for _, values := range [][]string{spec.SlackWebhookUrls, spec.WebhookUrls, spec.EmailIds, spec.PagerDutyIntegrationKeys, spec.MsTeamKeys} {
+		for _, value := range values {
+			for _, ref := range extractExpressionReferences(value) {
+				refs.add(ref)
+			}
+		}
+	}
+	for _, h := range spec.Headers {
+		for _, ref := range extractExpressionReferences(h.Value) {
+			refs.add(ref)
+		}
+	}
+	for _, ug := range spec.UserGroups {
+		if ref, ok := newEntityRef(EntityUserGroup, ug.Identifier); ok {
+			refs.add(ref)
+		}
+	}
+	return refs.list
+}
+
+// compareListed returns the compare function of an entity already read from
+// the target list
+func compareListed(target, source interface{}, found bool) func() (string, error) {
+	return func() (string, error) {
+		if !found {
+			return "", ErrEntityNotFound
+		}
+		return diffEntities(target, source)
+	}
+}
+
+func (c NotificationChannelContext) targetChannels() (map[string]*model.NotificationChannel, error) {
+	channels, err := listNotificationChannels(c.target.asSource(), c.targetOrg, c.targetProject)
+	if err != nil {
+		return nil, err
+	}
+	byId := map[string]*model.NotificationChannel{}
+	for _, ch := range channels {
+		byId[ch.Identifier] = ch
+	}
+	return byId, nil
+}
+
+func listNotificationChannels(s *SourceRequest, org, project string) ([]*model.NotificationChannel, error) {
+
+	channels := []*model.NotificationChannel{}
+	for page := 0; ; page++ {
+		resp, err := s.Client.R().
+			SetHeader("x-api-key", s.Token).
+			SetHeader("Content-Type", "application/json").
+			SetHeader("Harness-Account", s.Account).
+			SetPathParam("org", org).
+			SetPathParam("project", project).
+			SetQueryParams(map[string]string{
+				"page":  strconv.Itoa(page),
+				"limit": "100",
+			}).
+			Get(s.Url + NOTIFICATION_CHANNELS_ENDPOINT)
+		if err != nil {
+			return nil, err
+		}
+		if resp.IsError() {
+			return nil, handleErrorResponse(resp)
+		}
+
+		result := []*model.NotificationChannel{}
+		err = json.Unmarshal(resp.Body(), &result)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, result...)
+		if len(result) < 100 {
+			return channels, nil
+		}
+	}
+}
+
+func (c NotificationChannelContext) createChannel(ch *model.NotificationChannel) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("Harness-Account", api.Account).
+		SetPathParam("org", c.targetOrg).
+		SetPathParam("project", c.targetProject).
+		SetBody(ch).
+		Post(api.Url + NOTIFICATION_CHANNELS_ENDPOINT)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
+
+func (c NotificationChannelContext) updateChannel(ch *model.NotificationChannel) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("Harness-Account", api.Account).
+		SetPathParam("org", c.targetOrg).
+		SetPathParam("project", c.targetProject).
+		SetBody(ch).
+		Put(api.Url + NOTIFICATION_CHANNELS_ENDPOINT + "/" + ch.Identifier)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleErrorResponse(resp)
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationChannelToTarget(t *testing.T) {
+	c := NotificationChannelContext{
+		targetOrg:     "other",
+		targetProject: "DouradoF",
+		mapping: IdentifierMapping{
+			EntitySecret:    {Prefix: "legacy_"},
+			EntityUserGroup: {Prefix: "legacy_"},
+		},
+	}
+	ch := &model.NotificationChannel{
+		Identifier: "slack",
+		Channel: &model.NotificationChannelSpec{
+			SlackWebhookUrls: []string{`<+secrets.getValue("slack_hook")>`},
+			UserGroups:       []*model.NotificationUserGroup{{Identifier: "devops"}, {Identifier: "account.admins"}},
+		},
+	}
+
+	refs := channelReferences(ch)
+	c.toTarget(ch)
+
+	assert.ElementsMatch(t, []EntityRef{
+		{Type: EntitySecret, Scope: ScopeProject, Identifier: "slack_hook"},
+		{Type: EntityUserGroup, Scope: ScopeProject, Identifier: "devops"},
+		{Type: EntityUserGroup, Scope: ScopeAccount, Identifier: "admins"},
+	}, refs)
+	assert.Equal(t, "DouradoF", ch.Project)
+	assert.Equal(t, `<+secrets.getValue("legacy_slack_hook")>`, ch.Channel.SlackWebhookUrls[0])
+	assert.Equal(t, "legacy_devops", ch.Channel.UserGroups[0].Identifier)
+	assert.Equal(t, "account.admins", ch.Channel.UserGroups[1].Identifier)
+}
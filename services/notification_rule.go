@@ -0,0 +1,260 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/schollz/progressbar/v3"
+)
+
+const NOTIFICATION_RULES_ENDPOINT = "/v1/orgs/{org}/projects/{project}/notification-rules"
+
+// notificationEntityTypes are the notification entities whose identifiers
+// point at entities copied by the tool
+var notificationEntityTypes = map[string]EntityType{
+	"PIPELINE":  EntityPipeline,
+	"CONNECTOR": EntityConnector,
+}
+
+type NotificationRuleContext struct {
+	source        *SourceRequest
+	target        *TargetRequest
+	sourceOrg     string
+	sourceProject string
+	targetOrg     string
+	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
+}
+
+func NewNotificationRuleOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) NotificationRuleContext {
+	return NotificationRuleContext{
+		source:        sourceApi,
+		target:        targetApi,
+		sourceOrg:     st.SourceOrg,
+		sourceProject: st.SourceProject,
+		targetOrg:     st.TargetOrg,
+		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
+	}
+}
+
+func (c NotificationRuleContext) Move() error {
+
+	rules, err := listNotificationRules(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	targetRules, err := c.targetRules()
+	if err != nil {
+		return err
+	}
+
+	bar := progressbar.Default(int64(len(rules)), "Notification Rules")
+	var failed []string
+
+	for _, r := range rules {
+		if !c.filter.Match(EntityNotificationRule, r.Identifier) {
+			bar.Add(1)
+			continue
+		}
+		sourceId := r.Identifier
+		c.toTarget(r)
+
+		current, found := targetRules[r.Identifier]
+		result, err := c.state.upsert(c.onConflict, EntityNotificationRule, sourceId, r,
+			func() error { return c.createRule(r) },
+			func() error { return c.updateRule(r) },
+			compareListed(current, r, found),
+		)
+		if err != nil {
+			failed = append(failed, fmt.Sprintln(r.Name, "-", err.Error()))
+		}
+		c.report.recordOutcome(EntityNotificationRule, sourceId, r.Name, result, err)
+		bar.Add(1)
+	}
+	bar.Finish()
+
+	reportFailed(failed, "notification rules:")
+	return nil
+}
+
+func (c NotificationRuleContext) Scan(g *DependencyGraph) error {
+
+	rules, err := listNotificationRules(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, r := range rules {
+		if !c.filter.Match(EntityNotificationRule, r.Identifier) {
+			continue
+		}
+		g.Add(EntityNotificationRule, r.Identifier, r.Name, ruleReferences(r))
+	}
+	return nil
+}
+
+func (c NotificationRuleContext) Verify(v *Verification) error {
+
+	rules, err := listNotificationRules(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	targetRules, err := c.targetRules()
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, r := range rules {
+		if !c.filter.Match(EntityNotificationRule, r.Identifier) {
+			continue
+		}
+		count++
+		sourceId := r.Identifier
+		c.toTarget(r)
+		current, found := targetRules[r.Identifier]
+		v.check(EntityNotificationRule, sourceId, r.Name, compareListed(current, r, found))
+	}
+	v.count(EntityNotificationRule, count, len(targetRules))
+	return nil
+}
+
+// toTarget moves the rule to the target project and renames its channels and
+// the entities it watches
+func (c NotificationRuleContext) toTarget(r *model.NotificationRule) {
+	r.Identifier = c.mapping.Identifier(EntityNotificationRule, r.Identifier)
+	r.Org = c.targetOrg
+	r.Project = c.targetProject
+	for _, cond := range r.NotificationConditions {
+		for i, ref := range cond.NotificationChannelRefs {
+			cond.NotificationChannelRefs[i] = c.mapping.refValue(EntityNotificationChannel, ref)
+		}
+		for _, e := range cond.NotificationEventConfigs {
+			if t := notificationEntityTypes[e.NotificationEntity]; len(t) > 0 {
+				for i, id := range e.EntityIdentifiers {
+					e.EntityIdentifiers[i] = c.mapping.refValue(t, id)
+				}
+			}
+		}
+	}
+}
+
+// ruleReferences returns the channels and the entities used by the rule
+func ruleReferences(r *model.NotificationRule) []EntityRef {
+	refs := newRefSet()
+	for _, cond := range r.NotificationConditions {
+		for _, id := range cond.NotificationChannelRefs {
+			if ref, ok := newEntityRef(EntityNotificationChannel, id); ok {
+				refs.add(ref)
+			}
+		}
+		for _, e := range cond.NotificationEventConfigs {
+			if t := notificationEntityTypes[e.NotificationEntity]; len(t) > 0 {
+				for _, id := range e.EntityIdentifiers {
+					if ref, ok := newEntityRef(t, id); ok {
+						refs.add(ref)
+					}
+				}
+			}
+		}
+	}
+	return refs.list
+}
+
+func (c NotificationRuleContext) targetRules() (map[string]*model.NotificationRule, error) {
+	rules, err := listNotificationRules(c.target.asSource(), c.targetOrg, c.targetProject)
+	if err != nil {
+		return nil, err
+	}
+	byId := map[string]*model.NotificationRule{}
+	for _, r := range rules {
+		byId[r.Identifier] = r
+	}
+	return byId, nil
+}
+
+func listNotificationRules(s *SourceRequest, org, project string) ([]*model.NotificationRule, error) {
+
+	rules := []*model.NotificationRule{}
+	for page := 0; ; page++ {
+		resp, err := s.Client.R().
+			SetHeader("x-api-key", s.Token).
+			SetHeader("Content-Type", "application/json").
+			SetHeader("Harness-Account", s.Account).
+			SetPathParam("org", org).
+			SetPathParam("project", project).
+			SetQueryParams(map[string]string{
+				"page":  strconv.Itoa(page),
+				"limit": "100",
+			}).
+			Get(s.Url + NOTIFICATION_RULES_ENDPOINT)
+		if err != nil {
+			return nil, err
+		}
+		if resp.IsError() {
+			return nil, handleErrorResponse(resp)
+		}
+
+		result := []*model.NotificationRule{}
+		err = json.Unmarshal(resp.Body(), &result)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, result...)
+		if len(result) < 100 {
+			return rules, nil
+		}
+	}
+}
+
+func (c NotificationRuleContext) createRule(r *model.NotificationRule) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("Harness-Account", api.Account).
+		SetPathParam("org", c.targetOrg).
+		SetPathParam("project", c.targetProject).
+		SetBody(r).
+		Post(api.Url + NOTIFICATION_RULES_ENDPOINT)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
+
+func (c NotificationRuleContext) updateRule(r *model.NotificationRule) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("Harness-Account", api.Account).
+		SetPathParam("org", c.targetOrg).
+		SetPathParam("project", c.targetProject).
+		SetBody(r).
+		Put(api.Url + NOTIFICATION_RULES_ENDPOINT + "/" + r.Identifier)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleErrorResponse(resp)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	GET_ORGANIZATION    = "/ng/api/organizations/{identifier}"
+	CREATE_ORGANIZATION = "/v1/orgs"
+)
+
+// OrgExists reports whether the org is in the target account
+func (t *TargetRequest) OrgExists(org string) (bool, error) {
+	_, err := getOrganization(t.Client, t.Url, t.Token, t.Account, org)
+	if errors.Is(err, ErrEntityNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func getOrganization(c *resty.Client, url, token, account, org string) (*model.Organization, error) {
+	resp, err := c.R().
+		SetHeader("x-api-key", token).
+		SetHeader("Content-Type", "application/json").
+		SetPathParam("identifier", org).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": account,
+		}).
+		Get(url + GET_ORGANIZATION)
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		if err := handleErrorResponse(resp); err != nil {
+			return nil, err
+		}
+	}
+	result := model.GetOrganizationResponse{}
+	err = json.Unmarshal(resp.Body(), &result)
+	if err != nil {
+		return nil, err
+	}
+	if result.Data == nil || result.Data.Organization == nil {
+		return nil, ErrEntityNotFound
+	}
+
+	return result.Data.Organization, nil
+}
+
+// OrganizationContext creates the target org from the source org
+type OrganizationContext struct {
+	source    *SourceRequest
+	target    *TargetRequest
+	sourceOrg string
+	targetOrg string
+}
+
+func NewOrganizationOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) OrganizationContext {
+	return OrganizationContext{
+		source:    sourceApi,
+		target:    targetApi,
+		sourceOrg: st.SourceOrg,
+		targetOrg: st.TargetOrg,
+	}
+}
+
+// Move copies the name, description and tags of the source org. An org
+// created meanwhile by another move is kept.
+func (c OrganizationContext) Move() error {
+	org, err := getOrganization(c.source.Client, c.source.Url, c.source.Token, c.source.Account, c.sourceOrg)
+	if err != nil {
+		return fmt.Errorf("source org %s: %w", c.sourceOrg, err)
+	}
+
+	newOrg := *org
+	newOrg.Identifier = c.targetOrg
+
+	err = c.target.createOrganization(newOrg)
+	if errors.Is(err, ErrEntityExists) {
+		return nil
+	}
+	return err
+}
+
+func (t *TargetRequest) createOrganization(org model.Organization) error {
+	request := model.CreateOrganizationRequest{
+		Org: &org,
+	}
+	resp, err := t.Client.R().
+		SetHeader("x-api-key", t.Token).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("Harness-Account", t.Account).
+		SetBody(request).
+		Post(t.Url + CREATE_ORGANIZATION)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/Fernando-Dourado/harness-move-project/model"
@@ -15,6 +16,11 @@ type OverrideV2Context struct {
 	sourceProject string
 	targetOrg     string
 	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
 }
 
 func NewOverrideV2Operation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) OverrideV2Context {
@@ -25,6 +31,11 @@ func NewOverrideV2Operation(sourceApi *SourceRequest, targetApi *TargetRequest,
 		sourceProject: st.SourceProject,
 		targetOrg:     st.TargetOrg,
 		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
 	}
 }
 
@@ -49,6 +60,11 @@ func (c OverrideV2Context) Move() error {
 		bar.ChangeMax(bar.GetMax() + len(overrideIds))
 
 		for _, id := range overrideIds {
+			if !c.filter.Match(EntityOverrideV2, id) {
+				bar.Add(1)
+				continue
+			}
+			result := outcome{status: StatusFailed}
 			override, err := c.getOverride(id)
 			if err != nil {
 				failed = append(failed, fmt.Sprintln("Unable to get override type", overrideType, "identifier", id, "-", err.Error()))
@@ -56,10 +72,20 @@ func (c OverrideV2Context) Move() error {
 				override.OrgIdentifier = c.targetOrg
 				override.ProjectIdentifier = c.targetProject
 
-				if err = c.createOverride(override); err != nil {
-					failed = append(failed, fmt.Sprintln("Unable to create override type", override.Type, "identifier", override.Identifier, "-", err.Error()))
+				if err = c.applyMapping(override); err != nil {
+					failed = append(failed, fmt.Sprintln("Unable to map override type", override.Type, "identifier", override.Identifier, "-", err.Error()))
+				} else {
+					result, err = c.state.upsert(c.onConflict, EntityOverrideV2, id, override,
+						func() error { return c.createOverride(override) },
+						func() error { return c.updateOverride(override) },
+						c.compareOverride(override),
+					)
+					if err != nil {
+						failed = append(failed, fmt.Sprintln("Unable to create override type", override.Type, "identifier", override.Identifier, "-", err.Error()))
+					}
 				}
 			}
+			c.report.recordOutcome(EntityOverrideV2, id, id, result, err)
 			bar.Add(1)
 		}
 		bar.Add(1)
@@ -70,6 +96,256 @@ func (c OverrideV2Context) Move() error {
 	return nil
 }
 
+func (c OverrideV2Context) Scan(g *DependencyGraph) error {
+
+	overrideTypes := []model.OverridesV2Type{
+		model.OV2_Global,
+		model.OV2_Service,
+		model.OV2_Infra,
+		model.OV2_ServiceInfra,
+	}
+
+	for _, overrideType := range overrideTypes {
+		overrideIds, err := c.listOverrides(c.sourceOrg, c.sourceProject, overrideType)
+		if err != nil {
+			return err
+		}
+		for _, id := range overrideIds {
+			if !c.filter.Match(EntityOverrideV2, id) {
+				continue
+			}
+			override, err := c.getOverride(id)
+			if err != nil {
+				g.AddUnscanned(EntityOverrideV2, id, id, err)
+				continue
+			}
+			refs, err := extractReferences(override.Yaml)
+			if err != nil {
+				g.AddUnscanned(EntityOverrideV2, id, id, err)
+				continue
+			}
+			env, envFound := newEntityRef(EntityEnvironment, override.EnvironmentRef)
+			if envFound {
+				refs = append(refs, env)
+			}
+			if ref, ok := newEntityRef(EntityService, override.ServiceRef); ok {
+				refs = append(refs, ref)
+			}
+			if len(override.InfraIdentifier) > 0 && envFound {
+				refs = append(refs, EntityRef{
+					Type:       EntityInfrastructure,
+					Scope:      env.Scope,
+					Identifier: env.Identifier + "/" + override.InfraIdentifier,
+				})
+			}
+			g.Add(EntityOverrideV2, id, id, refs)
+		}
+	}
+	return nil
+}
+
+func (c OverrideV2Context) Verify(v *Verification) error {
+
+	overrideTypes := []model.OverridesV2Type{
+		model.OV2_Global,
+		model.OV2_Service,
+		model.OV2_Infra,
+		model.OV2_ServiceInfra,
+	}
+	target := c
+	target.source = c.target.asSource()
+
+	count, targetCount := 0, 0
+	for _, overrideType := range overrideTypes {
+		overrideIds, err := c.listOverrides(c.sourceOrg, c.sourceProject, overrideType)
+		if err != nil {
+			return err
+		}
+		targetIds, err := target.listOverrides(c.targetOrg, c.targetProject, overrideType)
+		if err != nil {
+			return err
+		}
+		targetCount += len(targetIds)
+
+		for _, id := range overrideIds {
+			if !c.filter.Match(EntityOverrideV2, id) {
+				continue
+			}
+			count++
+			override, err := c.getOverride(id)
+			if err == nil {
+				err = c.applyMapping(override)
+			}
+			if err != nil {
+				v.failed(EntityOverrideV2, id, id, err)
+				continue
+			}
+			// THE OVERRIDES RENAMED BY THE MAPPING ARE FOUND BY THEIR REFERENCES
+			if len(override.Identifier) == 0 {
+				if override.Identifier, err = c.findOverride(override); err != nil {
+					v.failed(EntityOverrideV2, id, id, err)
+					continue
+				}
+				if len(override.Identifier) == 0 {
+					v.check(EntityOverrideV2, id, id, func() (string, error) { return "", ErrEntityNotFound })
+					continue
+				}
+			}
+			v.check(EntityOverrideV2, id, id, c.target.verifyWith(EntityOverrideV2, override.Identifier, c.targetOrg, c.targetProject, nil, override.Yaml, false))
+		}
+	}
+	v.count(EntityOverrideV2, count, targetCount)
+	return nil
+}
+
+// Export describes the overrides, the ones renamed by the mapping have no
+// import block since the server computes their identifier
+func (c OverrideV2Context) Export(tf *TerraformExport) error {
+
+	overrideTypes := []model.OverridesV2Type{
+		model.OV2_Global,
+		model.OV2_Service,
+		model.OV2_Infra,
+		model.OV2_ServiceInfra,
+	}
+
+	for _, overrideType := range overrideTypes {
+		overrideIds, err := c.listOverrides(c.sourceOrg, c.sourceProject, overrideType)
+		if err != nil {
+			return err
+		}
+		for _, id := range overrideIds {
+			if !c.filter.Match(EntityOverrideV2, id) {
+				continue
+			}
+			override, err := c.getOverride(id)
+			if err != nil {
+				return err
+			}
+			if err = c.applyMapping(override); err != nil {
+				return fmt.Errorf("override %s: %w", id, err)
+			}
+			// THE IDENTIFIER IS LEFT EMPTY WHEN THE MAPPING CHANGES THE REFERENCES
+			var importId string
+			if len(override.Identifier) > 0 {
+				importId = tf.importId(override.Identifier)
+			}
+			attrs := []tfAttr{
+				tfReference("env_id", EntityEnvironment, override.EnvironmentRef),
+				tfReference("service_id", EntityService, override.ServiceRef),
+			}
+			if len(override.InfraIdentifier) > 0 {
+				attrs = append(attrs, tfInfrastructure("infra_id", override.EnvironmentRef, override.InfraIdentifier))
+			}
+			attrs = append(attrs,
+				tfString("type", string(override.Type)),
+				tfYamlDoc("yaml", override.Yaml),
+			)
+			tf.add(EntityOverrideV2, "", "harness_platform_service_overrides_v2", id, importId, tf.scoped(attrs...)...)
+		}
+	}
+	return nil
+}
+
+// applyMapping renames the references of the override. The identifier is built
+// from them, so it is left for the server to compute when they change.
+func (c OverrideV2Context) applyMapping(override *model.OverridesV2) error {
+	if len(c.mapping) == 0 {
+		return nil
+	}
+	newYaml, err := transformYaml(override.Yaml, c.mapping.transform(EntityOverrideV2))
+	if err != nil {
+		return err
+	}
+	override.Yaml = newYaml
+
+	envRef := c.mapping.refValue(EntityEnvironment, override.EnvironmentRef)
+	serviceRef := c.mapping.refValue(EntityService, override.ServiceRef)
+	infra := c.mapping.Identifier(EntityInfrastructure, override.InfraIdentifier)
+	if envRef != override.EnvironmentRef || serviceRef != override.ServiceRef || infra != override.InfraIdentifier {
+		override.Identifier = ""
+	}
+	override.EnvironmentRef = envRef
+	override.ServiceRef = serviceRef
+	override.InfraIdentifier = infra
+	return nil
+}
+
+// Prune deletes the synced overrides missing in the source. The identifier of
+// an override renamed by the mapping is computed by the server from its
+// references, which are gone with the source override, so those are reported
+// as failed and kept in the state.
+func (c OverrideV2Context) Prune() error {
+
+	overrideTypes := []model.OverridesV2Type{
+		model.OV2_Global,
+		model.OV2_Service,
+		model.OV2_Infra,
+		model.OV2_ServiceInfra,
+	}
+
+	var ids []string
+	for _, overrideType := range overrideTypes {
+		overrideIds, err := c.listOverrides(c.sourceOrg, c.sourceProject, overrideType)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, overrideIds...)
+	}
+	renamed := c.mapping[EntityEnvironment] != nil || c.mapping[EntityService] != nil || c.mapping[EntityInfrastructure] != nil
+	c.state.prune(EntityOverrideV2, ids, c.report, func(sourceId string) error {
+		if renamed {
+			return errors.New("the target identifier is computed by the server, delete the override in the target")
+		}
+		return c.target.deleteSynced(c.mapping, EntityOverrideV2, sourceId, c.targetOrg, c.targetProject)
+	})
+	return nil
+}
+
+// compareOverride returns the compare function of upsert. The identifier is
+// left empty when the mapping changes the references, then the target override
+// is found by its environment, service and infrastructure and its identifier
+// is kept for the update.
+func (c OverrideV2Context) compareOverride(override *model.OverridesV2) func() (string, error) {
+	if len(override.Identifier) > 0 {
+		return c.target.compareWith(EntityOverrideV2, override.Identifier, c.targetOrg, c.targetProject, nil, override.Yaml)
+	}
+	return func() (string, error) {
+		identifier, err := c.findOverride(override)
+		if err != nil {
+			return "", err
+		}
+		if len(identifier) == 0 {
+			return "", ErrEntityNotFound
+		}
+		override.Identifier = identifier
+		return c.target.compareTarget(EntityRef{Type: EntityOverrideV2, Scope: ScopeProject, Identifier: identifier}, c.targetOrg, c.targetProject, nil, override.Yaml)
+	}
+}
+
+// findOverride returns the identifier of the target override of the same type,
+// environment, service and infrastructure, empty when there is none
+func (c OverrideV2Context) findOverride(override *model.OverridesV2) (string, error) {
+	target := c
+	target.source = c.target.asSource()
+	target.sourceOrg, target.sourceProject = c.targetOrg, c.targetProject
+
+	ids, err := target.listOverrides(c.targetOrg, c.targetProject, override.Type)
+	if err != nil {
+		return "", err
+	}
+	for _, id := range ids {
+		o, err := target.getOverride(id)
+		if err != nil {
+			return "", err
+		}
+		if o.EnvironmentRef == override.EnvironmentRef && o.ServiceRef == override.ServiceRef && o.InfraIdentifier == override.InfraIdentifier {
+			return o.Identifier, nil
+		}
+	}
+	return "", nil
+}
+
 func (c OverrideV2Context) listOverrides(org, project string, overrideType model.OverridesV2Type) ([]string, error) {
 	api := c.source
 	resp, err := api.Client.R().
@@ -137,7 +413,7 @@ func (c OverrideV2Context) getOverride(identifier string) (*model.OverridesV2, e
 }
 
 func (c OverrideV2Context) createOverride(override *model.OverridesV2) error {
-	
+
 	api := c.target
 	resp, err := api.Client.R().
 		SetHeader("x-api-key", api.Token).
@@ -150,6 +426,27 @@ func (c OverrideV2Context) createOverride(override *model.OverridesV2) error {
 	if err != nil {
 		return err
 	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
+
+func (c OverrideV2Context) updateOverride(override *model.OverridesV2) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(override).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+		}).
+		Put(api.Url + "/ng/api/serviceOverrides")
+	if err != nil {
+		return err
+	}
 	if resp.IsError() {
 		return handleErrorResponse(resp)
 	}
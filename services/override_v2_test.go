@@ -0,0 +1,88 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareOverride_FindsRenamedOverride(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ng/api/serviceOverrides/v2/list":
+			w.Write([]byte(`{"data":{"content":[{"identifier":"dev_web"},{"identifier":"prod_api"}]}}`))
+		case "/ng/api/serviceOverrides/dev_web":
+			w.Write([]byte(`{"data":{"identifier":"dev_web","environmentRef":"dev","serviceRef":"web","type":"ENV_SERVICE_OVERRIDE","yaml":"overrides:\n  variables: []\n"}}`))
+		case "/ng/api/serviceOverrides/prod_api":
+			w.Write([]byte(`{"data":{"identifier":"prod_api","environmentRef":"prod","serviceRef":"api","type":"ENV_SERVICE_OVERRIDE","yaml":"overrides:\n  variables: []\n"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := OverrideV2Context{
+		target:        &TargetRequest{Client: resty.New(), Url: server.URL},
+		targetOrg:     "default",
+		targetProject: "payments",
+	}
+	override := &model.OverridesV2{EnvironmentRef: "prod", ServiceRef: "api", Type: model.OV2_Service, Yaml: "overrides:\n  variables: []\n"}
+
+	diff, err := c.compareOverride(override)()
+
+	assert.NoError(t, err)
+	assert.Empty(t, diff)
+	// THE UPDATE USES THE IDENTIFIER FOUND IN THE TARGET
+	assert.Equal(t, "prod_api", override.Identifier)
+}
+
+func TestCompareOverride_NotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"content":[]}}`))
+	}))
+	defer server.Close()
+
+	c := OverrideV2Context{target: &TargetRequest{Client: resty.New(), Url: server.URL}}
+	override := &model.OverridesV2{EnvironmentRef: "prod", ServiceRef: "api", Type: model.OV2_Service}
+
+	_, err := c.compareOverride(override)()
+
+	assert.ErrorIs(t, err, ErrEntityNotFound)
+	assert.Empty(t, override.Identifier)
+}
+
+func TestOverrideV2Verify_FindsRenamedOverride(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := r.URL.Query().Get("orgIdentifier") == "target"
+		switch {
+		case r.URL.Path == "/ng/api/serviceOverrides/v2/list" && r.URL.Query().Get("type") != string(model.OV2_Service):
+			w.Write([]byte(`{"data":{"content":[]}}`))
+		case r.URL.Path == "/ng/api/serviceOverrides/v2/list" && target:
+			w.Write([]byte(`{"data":{"content":[{"identifier":"new_prod_api"}]}}`))
+		case r.URL.Path == "/ng/api/serviceOverrides/v2/list":
+			w.Write([]byte(`{"data":{"content":[{"identifier":"prod_api"}]}}`))
+		case r.URL.Path == "/ng/api/serviceOverrides/prod_api":
+			w.Write([]byte(`{"data":{"identifier":"prod_api","environmentRef":"prod","serviceRef":"api","type":"ENV_SERVICE_OVERRIDE","yaml":"overrides:\n  variables: []\n"}}`))
+		case r.URL.Path == "/ng/api/serviceOverrides/new_prod_api":
+			w.Write([]byte(`{"data":{"identifier":"new_prod_api","environmentRef":"new_prod","serviceRef":"api","type":"ENV_SERVICE_OVERRIDE","yaml":"overrides:\n  variables: []\n"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := NewOverrideV2Operation(&SourceRequest{Client: resty.New(), Url: server.URL}, &TargetRequest{Client: resty.New(), Url: server.URL}, &SourceTarget{
+		SourceOrg: "source",
+		TargetOrg: "target",
+		Mapping:   IdentifierMapping{EntityEnvironment: {Prefix: "new_"}},
+	})
+	v := NewVerification()
+
+	assert.NoError(t, c.Verify(v))
+	assert.Len(t, v.Entries, 1)
+	assert.Equal(t, VerifyOk, v.Entries[0].Status)
+}
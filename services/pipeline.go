@@ -11,6 +11,7 @@ import (
 const LIST_PIPELINES = "/pipeline/api/pipelines/list"
 const GET_PIPELINE = "/pipeline/api/pipelines/%s"
 const CREATE_PIPELINE = "/pipeline/api/pipelines/v2"
+const UPDATE_PIPELINE = "/pipeline/api/pipelines/v2/%s"
 
 type PipelineContext struct {
 	source        *SourceRequest
@@ -19,6 +20,12 @@ type PipelineContext struct {
 	sourceProject string
 	targetOrg     string
 	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
+	since         int64
 }
 
 func NewPipelineOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) PipelineContext {
@@ -29,6 +36,12 @@ func NewPipelineOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st
 		sourceProject: st.SourceProject,
 		targetOrg:     st.TargetOrg,
 		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
+		since:         st.ModifiedSince,
 	}
 }
 
@@ -43,14 +56,32 @@ func (c PipelineContext) Move() error {
 	var failed []string
 
 	for _, pipe := range pipelines {
+		if !c.filter.Match(EntityPipeline, pipe.Identifier) {
+			bar.Add(1)
+			continue
+		}
+		if unmodified(c.since, pipe.LastUpdatedAt) {
+			c.report.recordUnmodified(EntityPipeline, pipe.Identifier, pipe.Name)
+			bar.Add(1)
+			continue
+		}
+		result := outcome{status: StatusFailed}
 		pipeData, err := c.getPipeline(c.sourceOrg, c.sourceProject, pipe.Identifier)
 		if err == nil {
-			newYaml := createYaml(pipeData.YAMLPipeline, c.sourceOrg, c.sourceProject, c.targetOrg, c.targetProject)
-			err = c.createPipeline(c.targetOrg, c.targetProject, newYaml)
+			var newYaml string
+			if newYaml, err = createYaml(pipeData.YAMLPipeline, c.targetOrg, c.targetProject, c.mapping.transform(EntityPipeline)); err == nil {
+				targetId := c.mapping.Identifier(EntityPipeline, pipe.Identifier)
+				result, err = c.state.upsert(c.onConflict, EntityPipeline, pipe.Identifier, newYaml,
+					func() error { return c.createPipeline(c.targetOrg, c.targetProject, newYaml) },
+					func() error { return c.updatePipeline(c.targetOrg, c.targetProject, targetId, newYaml) },
+					c.target.compareWith(EntityPipeline, targetId, c.targetOrg, c.targetProject, nil, newYaml),
+				)
+			}
 		}
 		if err != nil {
 			failed = append(failed, fmt.Sprintln(pipe.Name, "-", err.Error()))
 		}
+		c.report.recordOutcome(EntityPipeline, pipe.Identifier, pipe.Name, result, err)
 		bar.Add(1)
 	}
 	bar.Finish()
@@ -59,6 +90,107 @@ func (c PipelineContext) Move() error {
 	return nil
 }
 
+func (c PipelineContext) Scan(g *DependencyGraph) error {
+
+	pipelines, err := c.source.listPipelines(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, pipe := range pipelines {
+		if !c.filter.Match(EntityPipeline, pipe.Identifier) || unmodified(c.since, pipe.LastUpdatedAt) {
+			continue
+		}
+		pipeData, err := c.getPipeline(c.sourceOrg, c.sourceProject, pipe.Identifier)
+		if err != nil {
+			g.AddUnscanned(EntityPipeline, pipe.Identifier, pipe.Name, err)
+			continue
+		}
+		refs, err := extractReferences(pipeData.YAMLPipeline)
+		if err != nil {
+			g.AddUnscanned(EntityPipeline, pipe.Identifier, pipe.Name, err)
+			continue
+		}
+		g.Add(EntityPipeline, pipe.Identifier, pipe.Name, append(refs, projectSettingsRef))
+	}
+	return nil
+}
+
+func (c PipelineContext) Verify(v *Verification) error {
+
+	pipelines, err := c.source.listPipelines(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	targetPipelines, err := c.target.asSource().listPipelines(c.targetOrg, c.targetProject)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, pipe := range pipelines {
+		if !c.filter.Match(EntityPipeline, pipe.Identifier) {
+			continue
+		}
+		count++
+		pipeData, err := c.getPipeline(c.sourceOrg, c.sourceProject, pipe.Identifier)
+		if err != nil {
+			v.failed(EntityPipeline, pipe.Identifier, pipe.Name, err)
+			continue
+		}
+		newYaml, err := createYaml(pipeData.YAMLPipeline, c.targetOrg, c.targetProject, c.mapping.transform(EntityPipeline))
+		if err != nil {
+			v.failed(EntityPipeline, pipe.Identifier, pipe.Name, err)
+			continue
+		}
+		targetId := c.mapping.Identifier(EntityPipeline, pipe.Identifier)
+		v.check(EntityPipeline, pipe.Identifier, pipe.Name, c.target.verifyWith(EntityPipeline, targetId, c.targetOrg, c.targetProject, nil, newYaml, true))
+	}
+	v.count(EntityPipeline, count, len(targetPipelines))
+	return nil
+}
+
+func (c PipelineContext) Export(tf *TerraformExport) error {
+
+	pipelines, err := c.source.listPipelines(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, pipe := range pipelines {
+		if !c.filter.Match(EntityPipeline, pipe.Identifier) {
+			continue
+		}
+		pipeData, err := c.getPipeline(c.sourceOrg, c.sourceProject, pipe.Identifier)
+		if err != nil {
+			return fmt.Errorf("pipeline %s: %w", pipe.Name, err)
+		}
+		newYaml, err := createYaml(pipeData.YAMLPipeline, c.targetOrg, c.targetProject, c.mapping.transform(EntityPipeline))
+		if err != nil {
+			return fmt.Errorf("pipeline %s: %w", pipe.Name, err)
+		}
+		id := c.mapping.Identifier(EntityPipeline, pipe.Identifier)
+		tf.add(EntityPipeline, id, "harness_platform_pipeline", id, tf.importId(id), tf.entity(id, pipe.Name,
+			tfYamlDoc("yaml", newYaml),
+		)...)
+	}
+	return nil
+}
+
+func (c PipelineContext) Prune() error {
+
+	pipelines, err := c.source.listPipelines(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for _, pipe := range pipelines {
+		ids = append(ids, pipe.Identifier)
+	}
+	c.state.prune(EntityPipeline, ids, c.report, func(sourceId string) error {
+		return c.target.deleteSynced(c.mapping, EntityPipeline, sourceId, c.targetOrg, c.targetProject)
+	})
+	return nil
+}
+
 func (s *SourceRequest) listPipelines(org, project string) ([]*model.PipelineListContent, error) {
 
 	resp, err := s.Client.R().
@@ -132,6 +264,29 @@ func (c PipelineContext) createPipeline(org, project, yaml string) error {
 	if err != nil {
 		return err
 	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
+
+func (c PipelineContext) updatePipeline(org, project, pipeIdentifier, yaml string) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/yaml").
+		SetBody(yaml).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+			"orgIdentifier":     org,
+			"projectIdentifier": project,
+		}).
+		Put(api.Url + fmt.Sprintf(UPDATE_PIPELINE, pipeIdentifier))
+	if err != nil {
+		return err
+	}
 	if resp.IsError() {
 		return handleErrorResponse(resp)
 	}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/schollz/progressbar/v3"
+)
+
+// governancePageSize is the largest page of the policy API
+const governancePageSize = 100
+
+type PolicyContext struct {
+	source        *SourceRequest
+	target        *TargetRequest
+	sourceOrg     string
+	sourceProject string
+	targetOrg     string
+	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
+}
+
+func NewPolicyOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) PolicyContext {
+	return PolicyContext{
+		source:        sourceApi,
+		target:        targetApi,
+		sourceOrg:     st.SourceOrg,
+		sourceProject: st.SourceProject,
+		targetOrg:     st.TargetOrg,
+		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
+	}
+}
+
+func (c PolicyContext) Move() error {
+
+	policies, err := listPolicies(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+
+	bar := progressbar.Default(int64(len(policies)), "Policies")
+	var failed []string
+
+	for _, p := range policies {
+		if !c.filter.Match(EntityPolicy, p.Identifier) {
+			bar.Add(1)
+			continue
+		}
+		sourceId := p.Identifier
+		p.Identifier = c.mapping.Identifier(EntityPolicy, p.Identifier)
+
+		result, err := c.state.upsert(c.onConflict, EntityPolicy, sourceId, p,
+			func() error { return c.createPolicy(p) },
+			func() error { return c.updatePolicy(p) },
+			c.target.compareWith(EntityPolicy, p.Identifier, c.targetOrg, c.targetProject, nil, p),
+		)
+		if err != nil {
+			failed = append(failed, fmt.Sprintln(p.Name, "-", err.Error()))
+		}
+		c.report.recordOutcome(EntityPolicy, sourceId, p.Name, result, err)
+		bar.Add(1)
+	}
+	bar.Finish()
+
+	reportFailed(failed, "policies:")
+	return nil
+}
+
+func (c PolicyContext) Scan(g *DependencyGraph) error {
+
+	policies, err := listPolicies(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, p := range policies {
+		if !c.filter.Match(EntityPolicy, p.Identifier) {
+			continue
+		}
+		g.Add(EntityPolicy, p.Identifier, p.Name, nil)
+	}
+	return nil
+}
+
+func (c PolicyContext) Verify(v *Verification) error {
+
+	policies, err := listPolicies(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	targetPolicies, err := listPolicies(c.target.asSource(), c.targetOrg, c.targetProject)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, p := range policies {
+		if !c.filter.Match(EntityPolicy, p.Identifier) {
+			continue
+		}
+		count++
+		sourceId := p.Identifier
+		p.Identifier = c.mapping.Identifier(EntityPolicy, p.Identifier)
+		v.check(EntityPolicy, sourceId, p.Name, c.target.verifyWith(EntityPolicy, p.Identifier, c.targetOrg, c.targetProject, nil, p, false))
+	}
+	v.count(EntityPolicy, count, len(targetPolicies))
+	return nil
+}
+
+// listPolicies returns the policies created in the project
+func listPolicies(s *SourceRequest, org, project string) ([]*model.Policy, error) {
+	policies := []*model.Policy{}
+	err := getGovernancePages(s, "/pm/api/v1/policies", org, project, func(body []byte) (int, error) {
+		var page []*model.Policy
+		if err := json.Unmarshal(body, &page); err != nil {
+			return 0, err
+		}
+		policies = append(policies, page...)
+		return len(page), nil
+	})
+	return policies, err
+}
+
+// getGovernancePages reads every page of a policy API list, the decode
+// function returns the number of items in the page
+func getGovernancePages(s *SourceRequest, path, org, project string, decode func(body []byte) (int, error)) error {
+	for page := 0; ; page++ {
+		resp, err := s.Client.R().
+			SetHeader("x-api-key", s.Token).
+			SetHeader("Content-Type", "application/json").
+			SetQueryParams(map[string]string{
+				"accountIdentifier": s.Account,
+				"orgIdentifier":     org,
+				"projectIdentifier": project,
+				"page":              strconv.Itoa(page),
+				"per_page":          strconv.Itoa(governancePageSize),
+			}).
+			Get(s.Url + path)
+		if err != nil {
+			return err
+		}
+		if resp.IsError() {
+			return handleErrorResponse(resp)
+		}
+		n, err := decode(resp.Body())
+		if err != nil {
+			return err
+		}
+		if n < governancePageSize {
+			return nil
+		}
+	}
+}
+
+func (c PolicyContext) createPolicy(p *model.Policy) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(p).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+			"orgIdentifier":     c.targetOrg,
+			"projectIdentifier": c.targetProject,
+		}).
+		Post(api.Url + "/pm/api/v1/policies")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
+
+func (c PolicyContext) updatePolicy(p *model.Policy) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(&model.UpdatePolicyRequest{Name: p.Name, Rego: p.Rego}).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+			"orgIdentifier":     c.targetOrg,
+			"projectIdentifier": c.targetProject,
+		}).
+		Patch(api.Url + "/pm/api/v1/policies/" + p.Identifier)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleErrorResponse(resp)
+	}
+
+	return nil
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/schollz/progressbar/v3"
+)
+
+type PolicySetContext struct {
+	source        *SourceRequest
+	target        *TargetRequest
+	sourceOrg     string
+	sourceProject string
+	targetOrg     string
+	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
+}
+
+func NewPolicySetOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) PolicySetContext {
+	return PolicySetContext{
+		source:        sourceApi,
+		target:        targetApi,
+		sourceOrg:     st.SourceOrg,
+		sourceProject: st.SourceProject,
+		targetOrg:     st.TargetOrg,
+		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
+	}
+}
+
+func (c PolicySetContext) Move() error {
+
+	sets, err := c.listPolicySets()
+	if err != nil {
+		return err
+	}
+
+	bar := progressbar.Default(int64(len(sets)), "Policy Sets")
+	var failed []string
+
+	for _, ps := range sets {
+		sourceId := ps.Identifier
+		c.toTarget(ps)
+
+		result, err := c.state.upsert(c.onConflict, EntityPolicySet, sourceId, ps,
+			func() error { return c.createPolicySet(ps) },
+			func() error { return c.updatePolicySet(ps) },
+			c.target.compareWith(EntityPolicySet, ps.Identifier, c.targetOrg, c.targetProject, nil, ps),
+		)
+		if err != nil {
+			failed = append(failed, fmt.Sprintln(ps.Name, "-", err.Error()))
+		}
+		c.report.recordOutcome(EntityPolicySet, sourceId, ps.Name, result, err)
+		bar.Add(1)
+	}
+	bar.Finish()
+
+	reportFailed(failed, "policy sets:")
+	return nil
+}
+
+func (c PolicySetContext) Scan(g *DependencyGraph) error {
+
+	sets, err := c.listPolicySets()
+	if err != nil {
+		return err
+	}
+	for _, ps := range sets {
+		var refs []EntityRef
+		for _, p := range ps.Policies {
+			if ref, ok := newEntityRef(EntityPolicy, p.Identifier); ok {
+				refs = append(refs, ref)
+			}
+		}
+		g.Add(EntityPolicySet, ps.Identifier, ps.Name, refs)
+	}
+	return nil
+}
+
+func (c PolicySetContext) Verify(v *Verification) error {
+
+	sets, err := c.listPolicySets()
+	if err != nil {
+		return err
+	}
+	targetSets, err := listPolicySets(c.target.asSource(), c.targetOrg, c.targetProject)
+	if err != nil {
+		return err
+	}
+
+	for _, ps := range sets {
+		sourceId := ps.Identifier
+		c.toTarget(ps)
+		v.check(EntityPolicySet, sourceId, ps.Name, c.target.verifyWith(EntityPolicySet, ps.Identifier, c.targetOrg, c.targetProject, nil, ps, false))
+	}
+	v.count(EntityPolicySet, len(sets), len(targetSets))
+	return nil
+}
+
+// toTarget renames the policy set and its project policies, the org and
+// account policies keep their identifiers
+func (c PolicySetContext) toTarget(ps *model.PolicySet) {
+	ps.Identifier = c.mapping.Identifier(EntityPolicySet, ps.Identifier)
+	for _, p := range ps.Policies {
+		p.Identifier = c.mapping.refValue(EntityPolicy, p.Identifier)
+	}
+}
+
+// listPolicySets returns the selected policy sets with their policies, which
+// are not part of the list response
+func (c PolicySetContext) listPolicySets() ([]*model.PolicySet, error) {
+	sets, err := listPolicySets(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return nil, err
+	}
+	selected := []*model.PolicySet{}
+	for _, ps := range sets {
+		if !c.filter.Match(EntityPolicySet, ps.Identifier) {
+			continue
+		}
+		full, err := c.getPolicySet(ps.Identifier)
+		if err != nil {
+			return nil, fmt.Errorf("policy set %s: %w", ps.Identifier, err)
+		}
+		selected = append(selected, full)
+	}
+	return selected, nil
+}
+
+func listPolicySets(s *SourceRequest, org, project string) ([]*model.PolicySet, error) {
+	sets := []*model.PolicySet{}
+	err := getGovernancePages(s, "/pm/api/v1/policysets", org, project, func(body []byte) (int, error) {
+		var page []*model.PolicySet
+		if err := json.Unmarshal(body, &page); err != nil {
+			return 0, err
+		}
+		sets = append(sets, page...)
+		return len(page), nil
+	})
+	return sets, err
+}
+
+func (c PolicySetContext) getPolicySet(identifier string) (*model.PolicySet, error) {
+
+	api := c.source
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+			"orgIdentifier":     c.sourceOrg,
+			"projectIdentifier": c.sourceProject,
+		}).
+		Get(api.Url + "/pm/api/v1/policysets/" + identifier)
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, handleErrorResponse(resp)
+	}
+
+	result := &model.PolicySet{}
+	if err := json.Unmarshal(resp.Body(), result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c PolicySetContext) createPolicySet(ps *model.PolicySet) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(ps).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+			"orgIdentifier":     c.targetOrg,
+			"projectIdentifier": c.targetProject,
+		}).
+		Post(api.Url + "/pm/api/v1/policysets")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
+
+func (c PolicySetContext) updatePolicySet(ps *model.PolicySet) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(ps).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+			"orgIdentifier":     c.targetOrg,
+			"projectIdentifier": c.targetProject,
+		}).
+		Patch(api.Url + "/pm/api/v1/policysets/" + ps.Identifier)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleErrorResponse(resp)
+	}
+
+	return nil
+}
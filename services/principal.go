@@ -0,0 +1,168 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+)
+
+// PrincipalMapping maps the principals of the source account to the target
+// account. Users are matched by email, so only the emails that change are
+// listed. User groups and service accounts outside the project are matched by
+// identifier.
+type PrincipalMapping struct {
+	Users           map[string]string `yaml:"users,omitempty"`
+	UserGroups      map[string]string `yaml:"userGroups,omitempty"`
+	ServiceAccounts map[string]string `yaml:"serviceAccounts,omitempty"`
+}
+
+// ErrUnresolvedPrincipal is returned for the users not found in the target
+var ErrUnresolvedPrincipal = errors.New("principal not found in target")
+
+// principalResolver finds the target user of a source user. The users are
+// listed once, on the first call.
+type principalResolver struct {
+	source  *SourceRequest
+	target  *TargetRequest
+	mapping PrincipalMapping
+
+	once         sync.Once
+	err          error
+	sourceEmails map[string]string
+	targetUsers  map[string]string
+}
+
+func newPrincipalResolver(source *SourceRequest, target *TargetRequest, mapping PrincipalMapping) *principalResolver {
+	return &principalResolver{
+		source:  source,
+		target:  target,
+		mapping: mapping,
+	}
+}
+
+// sameAccount reports whether the users don't need mapping
+func (r *principalResolver) sameAccount() bool {
+	return r.source.Account == r.target.Account && r.source.Url == r.target.Url && len(r.mapping.Users) == 0
+}
+
+// user returns the target identifier of the source user
+func (r *principalResolver) user(identifier string) (string, error) {
+	if r.sameAccount() {
+		return identifier, nil
+	}
+	r.once.Do(r.load)
+	if r.err != nil {
+		return "", r.err
+	}
+
+	email, found := r.sourceEmails[identifier]
+	if !found {
+		return "", fmt.Errorf("user %s: %w", identifier, ErrUnresolvedPrincipal)
+	}
+	if mapped, found := r.mapping.Users[email]; found {
+		email = mapped
+	}
+	target, found := r.targetUsers[strings.ToLower(email)]
+	if !found {
+		return "", fmt.Errorf("user %s: %w", email, ErrUnresolvedPrincipal)
+	}
+	return target, nil
+}
+
+// principal maps a principal of a role assignment. Project user groups and
+// service accounts follow the identifier mapping.
+func (r *principalResolver) principal(p *model.Principal, mapping IdentifierMapping) (*model.Principal, error) {
+	out := *p
+	switch p.Type {
+	case model.PrincipalUser:
+		id, err := r.user(p.Identifier)
+		if err != nil {
+			return nil, err
+		}
+		out.Identifier = id
+	case model.PrincipalUserGroup:
+		if strings.EqualFold(p.ScopeLevel, string(ScopeProject)) {
+			out.Identifier = mapping.Identifier(EntityUserGroup, p.Identifier)
+		} else if mapped, found := r.mapping.UserGroups[p.Identifier]; found {
+			out.Identifier = mapped
+		}
+	case model.PrincipalServiceAccount:
+		if strings.EqualFold(p.ScopeLevel, string(ScopeProject)) {
+			out.Identifier = mapping.Identifier(EntityServiceAccount, p.Identifier)
+		} else if mapped, found := r.mapping.ServiceAccounts[p.Identifier]; found {
+			out.Identifier = mapped
+		}
+	}
+	return &out, nil
+}
+
+func (r *principalResolver) load() {
+	sourceUsers, err := listUsers(r.source)
+	if err != nil {
+		r.err = fmt.Errorf("listing source users: %w", err)
+		return
+	}
+	targetUsers, err := listUsers(r.target.asSource())
+	if err != nil {
+		r.err = fmt.Errorf("listing target users: %w", err)
+		return
+	}
+
+	r.sourceEmails = map[string]string{}
+	for _, u := range sourceUsers {
+		r.sourceEmails[u.UUID] = u.Email
+	}
+	r.targetUsers = userIds(targetUsers)
+}
+
+// listUserIds returns the identifiers of the account users by lower case email
+func listUserIds(s *SourceRequest) (map[string]string, error) {
+	users, err := listUsers(s)
+	if err != nil {
+		return nil, err
+	}
+	return userIds(users), nil
+}
+
+func userIds(users []*model.User) map[string]string {
+	ids := map[string]string{}
+	for _, u := range users {
+		ids[strings.ToLower(u.Email)] = u.UUID
+	}
+	return ids
+}
+
+func listUsers(s *SourceRequest) ([]*model.User, error) {
+
+	resp, err := s.Client.R().
+		SetHeader("x-api-key", s.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(`{}`).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": s.Account,
+			"pageSize":          "10000",
+		}).
+		Post(s.Url + "/ng/api/user/aggregate")
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, handleErrorResponse(resp)
+	}
+
+	result := model.ListUserResponse{}
+	err = json.Unmarshal(resp.Body(), &result)
+	if err != nil {
+		return nil, err
+	}
+
+	users := []*model.User{}
+	for _, c := range result.Data.Content {
+		users = append(users, c.User)
+	}
+	return users, nil
+}
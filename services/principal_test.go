@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLoadedResolver(mapping PrincipalMapping) *principalResolver {
+	r := newPrincipalResolver(&SourceRequest{Account: "source"}, &TargetRequest{Account: "target"}, mapping)
+	r.once.Do(func() {})
+	r.sourceEmails = map[string]string{"u1": "john@old.com", "u2": "mary@old.com", "u3": "gone@old.com"}
+	r.targetUsers = map[string]string{"john@new.com": "t1", "mary@old.com": "t2"}
+	return r
+}
+
+func TestPrincipalResolverUser(t *testing.T) {
+	r := newLoadedResolver(PrincipalMapping{Users: map[string]string{"john@old.com": "John@New.com"}})
+
+	id, err := r.user("u1")
+	assert.NoError(t, err)
+	assert.Equal(t, "t1", id)
+
+	id, err = r.user("u2")
+	assert.NoError(t, err)
+	assert.Equal(t, "t2", id)
+
+	_, err = r.user("u3")
+	assert.ErrorIs(t, err, ErrUnresolvedPrincipal)
+
+	_, err = r.user("unknown")
+	assert.ErrorIs(t, err, ErrUnresolvedPrincipal)
+}
+
+func TestPrincipalResolverPrincipal(t *testing.T) {
+	r := newLoadedResolver(PrincipalMapping{
+		UserGroups:      map[string]string{"admins": "platform_admins"},
+		ServiceAccounts: map[string]string{"bot": "robot"},
+	})
+	mapping := IdentifierMapping{EntityUserGroup: {Prefix: "legacy_"}}
+
+	p, err := r.principal(&model.Principal{Identifier: "devs", Type: model.PrincipalUserGroup, ScopeLevel: "project"}, mapping)
+	assert.NoError(t, err)
+	assert.Equal(t, "legacy_devs", p.Identifier)
+
+	p, err = r.principal(&model.Principal{Identifier: "admins", Type: model.PrincipalUserGroup, ScopeLevel: "account"}, mapping)
+	assert.NoError(t, err)
+	assert.Equal(t, "platform_admins", p.Identifier)
+
+	p, err = r.principal(&model.Principal{Identifier: "bot", Type: model.PrincipalServiceAccount, ScopeLevel: "account"}, mapping)
+	assert.NoError(t, err)
+	assert.Equal(t, "robot", p.Identifier)
+
+	_, err = r.principal(&model.Principal{Identifier: "u3", Type: model.PrincipalUser}, mapping)
+	assert.ErrorIs(t, err, ErrUnresolvedPrincipal)
+}
+
+func TestPrincipalResolverSameAccount(t *testing.T) {
+	r := newPrincipalResolver(&SourceRequest{Account: "acc"}, &TargetRequest{Account: "acc"}, PrincipalMapping{})
+
+	id, err := r.user("u1")
+	assert.NoError(t, err)
+	assert.Equal(t, "u1", id)
+}
@@ -2,7 +2,9 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/Fernando-Dourado/harness-move-project/model"
 	"github.com/go-resty/resty/v2"
@@ -65,6 +67,41 @@ func getProject(c *resty.Client, url, token, account, org, project string) (*mod
 	return &result, nil
 }
 
+const (
+	projectAdminRole          = "_project_admin"
+	projectAdminResourceGroup = "_all_project_level_resources"
+)
+
+// projectAdminTypes are the prefixes of the project admins
+var projectAdminTypes = map[string]model.PrincipalType{
+	"user":           model.PrincipalUser,
+	"usergroup":      model.PrincipalUserGroup,
+	"serviceaccount": model.PrincipalServiceAccount,
+}
+
+// principalScopeLevels are the scope levels of the role assignment principals
+var principalScopeLevels = map[Scope]string{
+	ScopeAccount: "account",
+	ScopeOrg:     "organization",
+	ScopeProject: "project",
+}
+
+// ParseProjectAdmin reads a project admin as "user:<email>",
+// "usergroup:<id>" or "serviceaccount:<id>". Groups and service accounts
+// outside the project use the "org." or "account." prefix.
+func ParseProjectAdmin(value string) (*model.Principal, error) {
+	kind, id, _ := strings.Cut(strings.TrimSpace(value), ":")
+	t, found := projectAdminTypes[strings.ToLower(kind)]
+	ref, ok := newEntityRef(EntityUserGroup, id)
+	if !found || !ok {
+		return nil, fmt.Errorf("invalid project admin %s, use user:<email>, usergroup:<id> or serviceaccount:<id>", value)
+	}
+	if t == model.PrincipalUser {
+		return &model.Principal{Identifier: strings.TrimSpace(id), Type: t}, nil
+	}
+	return &model.Principal{Identifier: ref.Identifier, Type: t, ScopeLevel: principalScopeLevels[ref.Scope]}, nil
+}
+
 type ProjectContext struct {
 	source        *SourceRequest
 	target        *TargetRequest
@@ -72,6 +109,7 @@ type ProjectContext struct {
 	sourceProject string
 	targetOrg     string
 	targetProject string
+	admins        []string
 }
 
 func NewProjectOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) ProjectContext {
@@ -82,6 +120,7 @@ func NewProjectOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st
 		sourceProject: st.SourceProject,
 		targetOrg:     st.TargetOrg,
 		targetProject: st.TargetProject,
+		admins:        st.ProjectAdmins,
 	}
 }
 
@@ -94,19 +133,70 @@ func (c ProjectContext) Move() error {
 		return fmt.Errorf("invalid response data for project %s and org %s", c.sourceProject, c.sourceOrg)
 	}
 
-	newProject := model.Project{
-		OrgIdentifier: c.targetOrg,
-		Identifier:    c.targetProject,
-		Name:          response.Data.Project.Name,
-		Description:   response.Data.Project.Description,
-		Color:         response.Data.Project.Color,
-	}
+	newProject := *response.Data.Project
+	newProject.OrgIdentifier = c.targetOrg
+	newProject.Identifier = c.targetProject
 
 	err = c.target.createProject(newProject)
 	if err != nil {
 		return err
 	}
 
+	return c.assignAdmins()
+}
+
+func (c ProjectContext) Export(tf *TerraformExport) error {
+	response, err := getProject(c.source.Client, c.source.Url, c.source.Token, c.source.Account, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	if response.Data == nil || response.Data.Project == nil {
+		return fmt.Errorf("invalid response data for project %s and org %s", c.sourceProject, c.sourceOrg)
+	}
+
+	p := response.Data.Project
+	tf.add(tfProject, c.targetProject, "harness_platform_project", "this", c.targetOrg+"/"+c.targetProject, tfOptional(
+		tfString("identifier", c.targetProject),
+		tfString("name", p.Name),
+		tfString("org_id", c.targetOrg),
+		tfString("description", p.Description),
+		tfString("color", p.Color),
+		tfTags(p.Tags),
+	)...)
+	return nil
+}
+
+// assignAdmins gives the project admin role to the configured principals, the
+// users are found by email in the target account
+func (c ProjectContext) assignAdmins() error {
+	var targetUsers map[string]string
+	for _, admin := range c.admins {
+		principal, err := ParseProjectAdmin(admin)
+		if err != nil {
+			return err
+		}
+		if principal.Type == model.PrincipalUser {
+			if targetUsers == nil {
+				if targetUsers, err = listUserIds(c.target.asSource()); err != nil {
+					return fmt.Errorf("listing target users: %w", err)
+				}
+			}
+			id, found := targetUsers[strings.ToLower(principal.Identifier)]
+			if !found {
+				return fmt.Errorf("project admin %s: %w", admin, ErrUnresolvedPrincipal)
+			}
+			principal.Identifier = id
+		}
+
+		ra := &model.RoleAssignment{
+			RoleIdentifier:          projectAdminRole,
+			ResourceGroupIdentifier: projectAdminResourceGroup,
+			Principal:               principal,
+		}
+		if err := createRoleAssignment(c.target, c.targetOrg, c.targetProject, ra); err != nil && !errors.Is(err, ErrEntityExists) {
+			return fmt.Errorf("project admin %s: %w", admin, err)
+		}
+	}
 	return nil
 }
 
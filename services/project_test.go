@@ -0,0 +1,27 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProjectAdmin(t *testing.T) {
+	user, err := ParseProjectAdmin("user:John@Company.com")
+	assert.NoError(t, err)
+	assert.Equal(t, &model.Principal{Identifier: "John@Company.com", Type: model.PrincipalUser}, user)
+
+	group, err := ParseProjectAdmin("usergroup:account.platform")
+	assert.NoError(t, err)
+	assert.Equal(t, &model.Principal{Identifier: "platform", Type: model.PrincipalUserGroup, ScopeLevel: "account"}, group)
+
+	sa, err := ParseProjectAdmin(" serviceaccount:org.deployer ")
+	assert.NoError(t, err)
+	assert.Equal(t, &model.Principal{Identifier: "deployer", Type: model.PrincipalServiceAccount, ScopeLevel: "organization"}, sa)
+
+	_, err = ParseProjectAdmin("admin:john")
+	assert.Error(t, err)
+	_, err = ParseProjectAdmin("user:")
+	assert.Error(t, err)
+}
@@ -0,0 +1,357 @@
+package services
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/harness/harness-go-sdk/harness/nextgen"
+	"gopkg.in/yaml.v3"
+)
+
+type EntityType string
+
+const (
+	EntityVariable            EntityType = "variable"
+	EntitySecret              EntityType = "secret"
+	EntityConnector           EntityType = "connector"
+	EntityFileStore           EntityType = "filestore"
+	EntityEnvironment         EntityType = "environment"
+	EntityInfrastructure      EntityType = "infrastructure"
+	EntityService             EntityType = "service"
+	EntityOverrideV1          EntityType = "override_v1"
+	EntityOverrideV2          EntityType = "override_v2"
+	EntityTemplate            EntityType = "template"
+	EntityPipeline            EntityType = "pipeline"
+	EntityInputset            EntityType = "inputset"
+	EntityRole                EntityType = "role"
+	EntityResourceGroup       EntityType = "resourcegroup"
+	EntityUserGroup           EntityType = "usergroup"
+	EntityRoleAssignment      EntityType = "roleassignment"
+	EntityServiceAccount      EntityType = "serviceaccount"
+	EntityPolicy              EntityType = "policy"
+	EntityPolicySet           EntityType = "policyset"
+	EntityFreeze              EntityType = "freeze"
+	EntityEnvironmentGroup    EntityType = "environmentgroup"
+	EntityMonitoredService    EntityType = "monitoredservice"
+	EntitySLO                 EntityType = "slo"
+	EntityTargetGroup         EntityType = "targetgroup"
+	EntityFeatureFlag         EntityType = "featureflag"
+	EntitySetting             EntityType = "setting"
+	EntityNotificationChannel EntityType = "notificationchannel"
+	EntityNotificationRule    EntityType = "notificationrule"
+)
+
+var entityTypes = map[EntityType]bool{
+	EntityVariable:            true,
+	EntitySecret:              true,
+	EntityConnector:           true,
+	EntityFileStore:           true,
+	EntityEnvironment:         true,
+	EntityInfrastructure:      true,
+	EntityService:             true,
+	EntityOverrideV1:          true,
+	EntityOverrideV2:          true,
+	EntityTemplate:            true,
+	EntityPipeline:            true,
+	EntityInputset:            true,
+	EntityRole:                true,
+	EntityResourceGroup:       true,
+	EntityUserGroup:           true,
+	EntityRoleAssignment:      true,
+	EntityServiceAccount:      true,
+	EntityPolicy:              true,
+	EntityPolicySet:           true,
+	EntityFreeze:              true,
+	EntityEnvironmentGroup:    true,
+	EntityMonitoredService:    true,
+	EntitySLO:                 true,
+	EntityTargetGroup:         true,
+	EntityFeatureFlag:         true,
+	EntitySetting:             true,
+	EntityNotificationChannel: true,
+	EntityNotificationRule:    true,
+}
+
+type Scope string
+
+const (
+	ScopeProject Scope = "project"
+	ScopeOrg     Scope = "org"
+	ScopeAccount Scope = "account"
+)
+
+// EntityRef identifies the entity a YAML field points at. Infrastructure and
+// target group identifiers are qualified by their environment as
+// "<env>/<identifier>".
+type EntityRef struct {
+	Type       EntityType
+	Scope      Scope
+	Identifier string
+}
+
+func (r EntityRef) String() string {
+	return string(r.Type) + ":" + r.value()
+}
+
+// value returns the reference as written in YAML, with the scope prefix
+func (r EntityRef) value() string {
+	if r.Scope == ScopeProject {
+		return r.Identifier
+	}
+	return string(r.Scope) + "." + r.Identifier
+}
+
+var refKeys = map[string]EntityType{
+	"connectorRef":             EntityConnector,
+	"templateRef":              EntityTemplate,
+	"serviceRef":               EntityService,
+	"environmentRef":           EntityEnvironment,
+	"envGroupRef":              EntityEnvironmentGroup,
+	"monitoredServiceRef":      EntityMonitoredService,
+	"serviceLevelObjectiveRef": EntitySLO,
+}
+
+// freezeEntityTypes are the freeze rule types holding project entities
+var freezeEntityTypes = map[string]EntityType{
+	"Service":     EntityService,
+	"Environment": EntityEnvironment,
+	"Pipeline":    EntityPipeline,
+}
+
+// fileStoreRoot identifies the project file store in the dependency graph
+const fileStoreRoot = "Root"
+
+var secretExpression = regexp.MustCompile(`<\+secrets\.getValue\(\s*["']([^"']+)["']\s*\)>`)
+
+// newEntityRef splits the "org." and "account." prefixes from a reference value.
+// Runtime inputs and expressions are not references and return false.
+func newEntityRef(t EntityType, value string) (EntityRef, bool) {
+	value = strings.TrimSpace(value)
+	if len(value) == 0 || strings.HasPrefix(value, "<+") {
+		return EntityRef{}, false
+	}
+	if strings.HasPrefix(value, "account.") {
+		return EntityRef{Type: t, Scope: ScopeAccount, Identifier: strings.TrimPrefix(value, "account.")}, true
+	}
+	if strings.HasPrefix(value, "org.") {
+		return EntityRef{Type: t, Scope: ScopeOrg, Identifier: strings.TrimPrefix(value, "org.")}, true
+	}
+	return EntityRef{Type: t, Scope: ScopeProject, Identifier: value}, true
+}
+
+// extractReferences returns every entity referenced by the YAML document
+func extractReferences(doc string) ([]EntityRef, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(doc), &root); err != nil {
+		return nil, err
+	}
+	refs := newRefSet()
+	visitReferences(&root, func(ref EntityRef, _ *yaml.Node) {
+		refs.add(ref)
+	})
+	for _, ref := range extractExpressionReferences(doc) {
+		refs.add(ref)
+	}
+	return refs.list, nil
+}
+
+// extractExpressionReferences returns the secrets used by <+secrets.getValue()>
+// expressions, which can appear in any text value
+func extractExpressionReferences(value string) []EntityRef {
+	refs := newRefSet()
+	for _, m := range secretExpression.FindAllStringSubmatch(value, -1) {
+		if ref, ok := newEntityRef(EntitySecret, m[1]); ok {
+			refs.add(ref)
+		}
+	}
+	return refs.list
+}
+
+// extractConnectorReferences returns the secrets and connectors used by the
+// connector spec
+func extractConnectorReferences(conn *nextgen.ConnectorInfo) ([]EntityRef, error) {
+	root, err := connectorNode(conn)
+	if err != nil {
+		return nil, err
+	}
+	refs := newRefSet()
+	visitConnectorReferences(root, func(ref EntityRef, _ *yaml.Node) {
+		refs.add(ref)
+	})
+	return refs.list, nil
+}
+
+// connectorNode converts the connector to a YAML tree
+func connectorNode(conn *nextgen.ConnectorInfo) (*yaml.Node, error) {
+	return jsonNode(conn)
+}
+
+// jsonNode converts a value encoded as JSON to a YAML tree, JSON being valid
+// YAML
+func jsonNode(v interface{}) (*yaml.Node, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(b, &root); err != nil {
+		return nil, err
+	}
+	return &root, nil
+}
+
+// extractEntityReferences returns every entity referenced by an entity
+// encoded as JSON
+func extractEntityReferences(v interface{}) ([]EntityRef, error) {
+	root, err := jsonNode(v)
+	if err != nil {
+		return nil, err
+	}
+	refs := newRefSet()
+	visitReferences(root, func(ref EntityRef, _ *yaml.Node) {
+		refs.add(ref)
+	})
+	return refs.list, nil
+}
+
+// visitConnectorReferences calls fn for every "*Ref" field of the connector.
+// They all hold secrets, except connectorRef.
+func visitConnectorReferences(root *yaml.Node, fn func(ref EntityRef, value *yaml.Node)) {
+	walkMappings(root, func(key, value *yaml.Node) {
+		if value.Kind != yaml.ScalarNode || !strings.HasSuffix(key.Value, "Ref") {
+			return
+		}
+		t := EntitySecret
+		if key.Value == "connectorRef" {
+			t = EntityConnector
+		}
+		if ref, ok := newEntityRef(t, value.Value); ok {
+			fn(ref, value)
+		}
+	})
+}
+
+// visitReferences calls fn with every reference and the scalar node holding it.
+// Secret expressions are not visited, they live inside text values.
+func visitReferences(root *yaml.Node, fn func(ref EntityRef, value *yaml.Node)) {
+	walkMappingNodes(root, func(m *yaml.Node) {
+		var envRef *EntityRef
+		if v := mappingValue(m, "environmentRef"); v != nil && v.Kind == yaml.ScalarNode {
+			if ref, ok := newEntityRef(EntityEnvironment, v.Value); ok {
+				envRef = &ref
+			}
+		}
+		if ref, ok := fileStoreReference(m); ok {
+			fn(ref, m)
+		}
+		// FREEZE RULES LIST THE IDENTIFIERS OF THEIR ENTITIES BY TYPE
+		if t := mappingValue(m, "type"); t != nil && len(freezeEntityTypes[t.Value]) > 0 {
+			if ids := mappingValue(m, "entityRefs"); ids != nil && ids.Kind == yaml.SequenceNode {
+				for _, id := range ids.Content {
+					if ref, ok := newEntityRef(freezeEntityTypes[t.Value], id.Value); ok && id.Kind == yaml.ScalarNode {
+						fn(ref, id)
+					}
+				}
+			}
+		}
+		for i := 0; i+1 < len(m.Content); i += 2 {
+			key, value := m.Content[i], m.Content[i+1]
+			if t, found := refKeys[key.Value]; found && value.Kind == yaml.ScalarNode {
+				if ref, ok := newEntityRef(t, value.Value); ok {
+					fn(ref, value)
+				}
+			}
+			// ENVIRONMENT GROUPS AND MONITORED SERVICES LIST THEIR ENVIRONMENTS
+			if (key.Value == "envIdentifiers" || key.Value == "environmentRefList") && value.Kind == yaml.SequenceNode {
+				for _, id := range value.Content {
+					if ref, ok := newEntityRef(EntityEnvironment, id.Value); ok && id.Kind == yaml.ScalarNode {
+						fn(ref, id)
+					}
+				}
+			}
+			// INFRASTRUCTURES ARE ONLY UNIQUE INSIDE THEIR ENVIRONMENT
+			if key.Value == "infrastructureDefinitions" && value.Kind == yaml.SequenceNode && envRef != nil {
+				for _, item := range value.Content {
+					id := mappingValue(item, "identifier")
+					if id == nil || id.Kind != yaml.ScalarNode {
+						continue
+					}
+					if _, ok := newEntityRef(EntityInfrastructure, id.Value); ok {
+						fn(EntityRef{
+							Type:       EntityInfrastructure,
+							Scope:      envRef.Scope,
+							Identifier: envRef.Identifier + "/" + id.Value,
+						}, id)
+					}
+				}
+			}
+		}
+	})
+}
+
+// fileStoreReference detects a Harness store using project files, like
+// "store: {type: Harness, spec: {files: [/path]}}"
+func fileStoreReference(m *yaml.Node) (EntityRef, bool) {
+	t := mappingValue(m, "type")
+	if t == nil || t.Value != "Harness" {
+		return EntityRef{}, false
+	}
+	var project bool
+	walkScalars(mappingValue(m, "spec"), func(n *yaml.Node) {
+		if strings.HasPrefix(n.Value, "/") {
+			project = true
+		}
+	})
+	return EntityRef{Type: EntityFileStore, Scope: ScopeProject, Identifier: fileStoreRoot}, project
+}
+
+// walkMappingNodes calls fn for every mapping node in the tree
+func walkMappingNodes(n *yaml.Node, fn func(m *yaml.Node)) {
+	if n == nil {
+		return
+	}
+	if n.Kind == yaml.MappingNode {
+		fn(n)
+	}
+	for _, c := range n.Content {
+		walkMappingNodes(c, fn)
+	}
+}
+
+// walkMappings calls fn for every key/value pair in the tree
+func walkMappings(n *yaml.Node, fn func(key, value *yaml.Node)) {
+	walkMappingNodes(n, func(m *yaml.Node) {
+		for i := 0; i+1 < len(m.Content); i += 2 {
+			fn(m.Content[i], m.Content[i+1])
+		}
+	})
+}
+
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+type refSet struct {
+	seen map[EntityRef]bool
+	list []EntityRef
+}
+
+func newRefSet() *refSet {
+	return &refSet{seen: map[EntityRef]bool{}}
+}
+
+func (s *refSet) add(ref EntityRef) {
+	if !s.seen[ref] {
+		s.seen[ref] = true
+		s.list = append(s.list, ref)
+	}
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+const TEST_REFERENCES_PIPELINE_YAML = "pipeline:\n  name: deploy\n  identifier: deploy\n  projectIdentifier: FernandoD\n  orgIdentifier: default\n  stages:\n    - stage:\n        name: build\n        identifier: build\n        template:\n          templateRef: org.build_stage\n          versionLabel: v1\n    - stage:\n        name: deploy\n        identifier: deploy\n        type: Deployment\n        spec:\n          service:\n            serviceRef: api\n          environment:\n            environmentRef: prod\n            infrastructureDefinitions:\n              - identifier: k8s\n          execution:\n            steps:\n              - step:\n                  type: ShellScript\n                  identifier: script\n                  spec:\n                    connectorRef: <+input>\n                    source:\n                      type: Inline\n                      spec:\n                        script: echo <+secrets.getValue(\"account.token\")>\n"
+
+func TestExtractReferences(t *testing.T) {
+	refs, err := extractReferences(TEST_REFERENCES_PIPELINE_YAML)
+
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []EntityRef{
+		{Type: EntityTemplate, Scope: ScopeOrg, Identifier: "build_stage"},
+		{Type: EntityService, Scope: ScopeProject, Identifier: "api"},
+		{Type: EntityEnvironment, Scope: ScopeProject, Identifier: "prod"},
+		{Type: EntityInfrastructure, Scope: ScopeProject, Identifier: "prod/k8s"},
+		{Type: EntitySecret, Scope: ScopeAccount, Identifier: "token"},
+	}, refs)
+}
+
+func TestTypeOrder_DependenciesFirst(t *testing.T) {
+	g := NewDependencyGraph()
+	g.Add(EntityPipeline, "deploy", "deploy", []EntityRef{{Type: EntityTemplate, Scope: ScopeProject, Identifier: "stage"}})
+	g.Add(EntityTemplate, "stage", "stage", []EntityRef{{Type: EntityService, Scope: ScopeProject, Identifier: "api"}})
+	g.Add(EntityService, "api", "api", nil)
+
+	order, err := g.TypeOrder([]EntityType{EntityPipeline, EntityTemplate, EntityVariable, EntityService})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []EntityType{EntityService, EntityTemplate, EntityPipeline, EntityVariable}, order)
+}
+
+func TestEntityOrder_Cycle(t *testing.T) {
+	g := NewDependencyGraph()
+	g.Add(EntityTemplate, "a", "a", []EntityRef{{Type: EntityTemplate, Scope: ScopeProject, Identifier: "b"}})
+	g.Add(EntityTemplate, "b", "b", []EntityRef{{Type: EntityTemplate, Scope: ScopeProject, Identifier: "a"}})
+
+	order, err := g.EntityOrder(EntityTemplate)
+
+	assert.Error(t, err)
+	assert.Equal(t, []string{"a", "b"}, order)
+}
+
+const TEST_FREEZE_YAML = "freeze:\n  name: prod freeze\n  identifier: prod_freeze\n  status: Enabled\n  orgIdentifier: default\n  projectIdentifier: FernandoD\n  entityConfigs:\n    - name: rule\n      entities:\n        - type: Service\n          filterType: Equals\n          entityRefs:\n            - api\n            - web\n        - type: Environment\n          filterType: Equals\n          entityRefs:\n            - org.prod\n        - type: EnvType\n          filterType: Equals\n          entityRefs:\n            - Production\n"
+
+func TestExtractReferences_FreezeRules(t *testing.T) {
+	refs, err := extractReferences(TEST_FREEZE_YAML)
+
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []EntityRef{
+		{Type: EntityService, Scope: ScopeProject, Identifier: "api"},
+		{Type: EntityService, Scope: ScopeProject, Identifier: "web"},
+		{Type: EntityEnvironment, Scope: ScopeOrg, Identifier: "prod"},
+	}, refs)
+}
+
+func TestExtractReferences_EnvironmentGroup(t *testing.T) {
+	refs, err := extractReferences("environmentGroup:\n  name: all\n  identifier: all\n  envIdentifiers:\n    - dev\n    - org.prod\n")
+
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []EntityRef{
+		{Type: EntityEnvironment, Scope: ScopeProject, Identifier: "dev"},
+		{Type: EntityEnvironment, Scope: ScopeOrg, Identifier: "prod"},
+	}, refs)
+}
+
+func TestServiceScan_KeepsUnreadableEntities(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"SUCCESS","data":{"content":[
+  {"service":{"identifier":"api","name":"api","yaml":"service:\n  identifier: api\n  serviceDefinition:\n    spec:\n      connectorRef: github\n"}},
+  {"service":{"identifier":"broken","name":"broken","yaml":"service: [\n"}}
+]}}`))
+	}))
+	defer server.Close()
+
+	c := NewServiceOperation(&SourceRequest{Client: resty.New(), Url: server.URL}, &TargetRequest{}, &SourceTarget{})
+	g := NewDependencyGraph()
+
+	assert.NoError(t, c.Scan(g))
+	assert.True(t, g.Has(EntityRef{Type: EntityService, Scope: ScopeProject, Identifier: "api"}))
+	// THE BROKEN SERVICE IS STILL COPIED, ITS OPERATION REPORTS THE ERROR
+	assert.True(t, g.Has(EntityRef{Type: EntityService, Scope: ScopeProject, Identifier: "broken"}))
+	assert.Len(t, g.Unscanned(), 1)
+	assert.Equal(t, "broken", g.Unscanned()[0].Ref.Identifier)
+}
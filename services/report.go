@@ -0,0 +1,149 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+type ReportStatus string
+
+const (
+	StatusCreated ReportStatus = "created"
+	StatusUpdated ReportStatus = "updated"
+	StatusSkipped ReportStatus = "skipped"
+	// StatusConflict is an existing target entity that differs from the source
+	StatusConflict ReportStatus = "conflict"
+	// StatusCollision is a target entity using the same name under another
+	// identifier
+	StatusCollision ReportStatus = "collision"
+	StatusFailed    ReportStatus = "failed"
+	// StatusUnchanged is an entity whose content didn't change since the last
+	// successful sync, nothing is sent to the target
+	StatusUnchanged ReportStatus = "unchanged"
+	// StatusDeleted is a synced target entity deleted because its source
+	// entity was deleted
+	StatusDeleted ReportStatus = "deleted"
+)
+
+type ReportEntry struct {
+	Type       EntityType   `json:"type"`
+	Identifier string       `json:"identifier"`
+	Name       string       `json:"name,omitempty"`
+	Status     ReportStatus `json:"status"`
+	Message    string       `json:"message,omitempty"`
+	Diff       string       `json:"diff,omitempty"`
+}
+
+// Report collects the outcome of every entity handled by the operations. It is
+// safe for concurrent use and a nil report ignores the records.
+type Report struct {
+	mu           sync.Mutex
+	Entries      []ReportEntry `json:"entries"`
+	Verification *Verification `json:"verification,omitempty"`
+}
+
+func NewReport() *Report {
+	return &Report{Entries: []ReportEntry{}}
+}
+
+func (r *Report) add(entry ReportEntry) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Entries = append(r.Entries, entry)
+}
+
+// record adds the outcome of creating one entity
+func (r *Report) record(t EntityType, identifier, name string, err error) {
+	status := StatusCreated
+	if err != nil {
+		status = StatusFailed
+	}
+	r.recordOutcome(t, identifier, name, outcome{status: status}, err)
+}
+
+// recordOutcome adds the outcome of one entity, the error is its message. The
+// content hash of the synced entities is kept.
+func (r *Report) recordOutcome(t EntityType, identifier, name string, o outcome, err error) {
+	if err == nil && o.commit != nil && o.synced() {
+		o.commit()
+	}
+	entry := ReportEntry{
+		Type:       t,
+		Identifier: identifier,
+		Name:       name,
+		Status:     o.status,
+		Message:    o.message,
+		Diff:       o.diff,
+	}
+	if err != nil {
+		entry.Message = removeNewLine(err.Error())
+	}
+	r.add(entry)
+}
+
+// Summary counts the entries by entity type and status
+func (r *Report) Summary() map[EntityType]map[ReportStatus]int {
+	summary := map[EntityType]map[ReportStatus]int{}
+	if r == nil {
+		return summary
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.Entries {
+		if summary[e.Type] == nil {
+			summary[e.Type] = map[ReportStatus]int{}
+		}
+		summary[e.Type][e.Status]++
+	}
+	return summary
+}
+
+// Print writes the summary to the console
+func (r *Report) Print() {
+	summary := r.Summary()
+	var types []string
+	for t := range summary {
+		types = append(types, string(t))
+	}
+	sort.Strings(types)
+
+	for _, t := range types {
+		var counts []string
+		var statuses []string
+		for s := range summary[EntityType(t)] {
+			statuses = append(statuses, string(s))
+		}
+		sort.Strings(statuses)
+		for _, s := range statuses {
+			counts = append(counts, fmt.Sprintf("%s %d", s, summary[EntityType(t)][ReportStatus(s)]))
+		}
+		line := fmt.Sprintf("%-16s %s", t, strings.Join(counts, ", "))
+		if summary[EntityType(t)][StatusFailed] > 0 || summary[EntityType(t)][StatusCollision] > 0 {
+			fmt.Println(color.RedString(line))
+		} else if summary[EntityType(t)][StatusConflict] > 0 {
+			fmt.Println(color.YellowString(line))
+		} else {
+			fmt.Println(line)
+		}
+	}
+}
+
+// Write saves the report as JSON
+func (r *Report) Write(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/schollz/progressbar/v3"
+)
+
+// resourceTypes are the resource group types whose identifiers point at
+// entities copied by the tool
+var resourceTypes = map[string]EntityType{
+	"SECRET":               EntitySecret,
+	"CONNECTOR":            EntityConnector,
+	"ENVIRONMENT":          EntityEnvironment,
+	"SERVICE":              EntityService,
+	"TEMPLATE":             EntityTemplate,
+	"PIPELINE":             EntityPipeline,
+	"VARIABLE":             EntityVariable,
+	"USERGROUP":            EntityUserGroup,
+	"ROLE":                 EntityRole,
+	"ENVIRONMENT_GROUP":    EntityEnvironmentGroup,
+	"INFRASTRUCTURE":       "",
+	"FILE":                 "",
+	"DEPLOYMENTFREEZE":     EntityFreeze,
+	"GOVERNANCE_POLICY":    EntityPolicy,
+	"GOVERNANCE_POLICYSET": EntityPolicySet,
+}
+
+type ResourceGroupContext struct {
+	source        *SourceRequest
+	target        *TargetRequest
+	sourceOrg     string
+	sourceProject string
+	targetOrg     string
+	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
+}
+
+func NewResourceGroupOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) ResourceGroupContext {
+	return ResourceGroupContext{
+		source:        sourceApi,
+		target:        targetApi,
+		sourceOrg:     st.SourceOrg,
+		sourceProject: st.SourceProject,
+		targetOrg:     st.TargetOrg,
+		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
+	}
+}
+
+func (c ResourceGroupContext) Move() error {
+
+	groups, err := listResourceGroups(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+
+	bar := progressbar.Default(int64(len(groups)), "Resource Groups")
+	var failed []string
+
+	for _, rg := range groups {
+		if !c.filter.Match(EntityResourceGroup, rg.Identifier) {
+			bar.Add(1)
+			continue
+		}
+		sourceId := rg.Identifier
+		c.toTarget(rg)
+
+		request := &model.CreateResourceGroupRequest{
+			ResourceGroup: rg,
+		}
+		result, err := c.state.upsert(c.onConflict, EntityResourceGroup, sourceId, request,
+			func() error { return c.createResourceGroup(request) },
+			func() error { return c.updateResourceGroup(request) },
+			c.target.compareWith(EntityResourceGroup, rg.Identifier, c.targetOrg, c.targetProject, nil, rg),
+		)
+		if err != nil {
+			failed = append(failed, fmt.Sprintln(rg.Name, "-", err.Error()))
+		}
+		c.report.recordOutcome(EntityResourceGroup, sourceId, rg.Name, result, err)
+		bar.Add(1)
+	}
+	bar.Finish()
+
+	reportFailed(failed, "resource groups:")
+	return nil
+}
+
+func (c ResourceGroupContext) Scan(g *DependencyGraph) error {
+
+	groups, err := listResourceGroups(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, rg := range groups {
+		if !c.filter.Match(EntityResourceGroup, rg.Identifier) {
+			continue
+		}
+		var refs []EntityRef
+		if rg.ResourceFilter != nil {
+			for _, r := range rg.ResourceFilter.Resources {
+				if t := resourceTypes[r.ResourceType]; len(t) > 0 {
+					for _, id := range r.Identifiers {
+						refs = append(refs, EntityRef{Type: t, Scope: ScopeProject, Identifier: id})
+					}
+				}
+			}
+		}
+		g.Add(EntityResourceGroup, rg.Identifier, rg.Name, refs)
+	}
+	return nil
+}
+
+func (c ResourceGroupContext) Verify(v *Verification) error {
+
+	groups, err := listResourceGroups(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	targetGroups, err := listResourceGroups(c.target.asSource(), c.targetOrg, c.targetProject)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, rg := range groups {
+		if !c.filter.Match(EntityResourceGroup, rg.Identifier) {
+			continue
+		}
+		count++
+		sourceId := rg.Identifier
+		c.toTarget(rg)
+		v.check(EntityResourceGroup, sourceId, rg.Name, c.target.verifyWith(EntityResourceGroup, rg.Identifier, c.targetOrg, c.targetProject, nil, rg, false))
+	}
+	v.count(EntityResourceGroup, count, len(targetGroups))
+	return nil
+}
+
+// toTarget moves the resource group and its scopes to the target project and
+// renames the selected resources
+func (c ResourceGroupContext) toTarget(rg *model.ResourceGroup) {
+	rg.Identifier = c.mapping.Identifier(EntityResourceGroup, rg.Identifier)
+	rg.AccountIdentifier = c.target.Account
+	rg.OrgIdentifier = c.targetOrg
+	rg.ProjectIdentifier = c.targetProject
+	for _, s := range rg.IncludedScopes {
+		s.AccountIdentifier = c.target.Account
+		if len(s.OrgIdentifier) > 0 {
+			s.OrgIdentifier = c.targetOrg
+		}
+		if len(s.ProjectIdentifier) > 0 {
+			s.ProjectIdentifier = c.targetProject
+		}
+	}
+	if rg.ResourceFilter == nil {
+		return
+	}
+	for _, r := range rg.ResourceFilter.Resources {
+		if t := resourceTypes[r.ResourceType]; len(t) > 0 {
+			for i, id := range r.Identifiers {
+				r.Identifiers[i] = c.mapping.Identifier(t, id)
+			}
+		}
+	}
+}
+
+// listResourceGroups returns the custom resource groups of the project
+func listResourceGroups(s *SourceRequest, org, project string) ([]*model.ResourceGroup, error) {
+
+	resp, err := s.Client.R().
+		SetHeader("x-api-key", s.Token).
+		SetHeader("Content-Type", "application/json").
+		SetQueryParams(map[string]string{
+			"accountIdentifier": s.Account,
+			"orgIdentifier":     org,
+			"projectIdentifier": project,
+			"pageSize":          "1000",
+		}).
+		Get(s.Url + "/resourcegroup/api/v2/resourcegroup")
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, handleErrorResponse(resp)
+	}
+
+	result := model.ListResourceGroupResponse{}
+	err = json.Unmarshal(resp.Body(), &result)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []*model.ResourceGroup{}
+	for _, c := range result.Data.Content {
+		if !c.HarnessManaged && !isBuiltIn(c.ResourceGroup.Identifier) {
+			groups = append(groups, c.ResourceGroup)
+		}
+	}
+	return groups, nil
+}
+
+func (c ResourceGroupContext) createResourceGroup(rg *model.CreateResourceGroupRequest) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(rg).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+			"orgIdentifier":     c.targetOrg,
+			"projectIdentifier": c.targetProject,
+		}).
+		Post(api.Url + "/resourcegroup/api/v2/resourcegroup")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
+
+func (c ResourceGroupContext) updateResourceGroup(rg *model.CreateResourceGroupRequest) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(rg).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+			"orgIdentifier":     c.targetOrg,
+			"projectIdentifier": c.targetProject,
+		}).
+		Put(api.Url + "/resourcegroup/api/v2/resourcegroup/" + rg.ResourceGroup.Identifier)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleErrorResponse(resp)
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/schollz/progressbar/v3"
+)
+
+type RoleContext struct {
+	source        *SourceRequest
+	target        *TargetRequest
+	sourceOrg     string
+	sourceProject string
+	targetOrg     string
+	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
+}
+
+func NewRoleOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) RoleContext {
+	return RoleContext{
+		source:        sourceApi,
+		target:        targetApi,
+		sourceOrg:     st.SourceOrg,
+		sourceProject: st.SourceProject,
+		targetOrg:     st.TargetOrg,
+		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
+	}
+}
+
+func (c RoleContext) Move() error {
+
+	roles, err := listRoles(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+
+	bar := progressbar.Default(int64(len(roles)), "Roles")
+	var failed []string
+
+	for _, r := range roles {
+		if !c.filter.Match(EntityRole, r.Identifier) {
+			bar.Add(1)
+			continue
+		}
+		sourceId := r.Identifier
+		r.Identifier = c.mapping.Identifier(EntityRole, r.Identifier)
+
+		result, err := c.state.upsert(c.onConflict, EntityRole, sourceId, r,
+			func() error { return c.createRole(r) },
+			func() error { return c.updateRole(r) },
+			c.target.compareWith(EntityRole, r.Identifier, c.targetOrg, c.targetProject, nil, r),
+		)
+		if err != nil {
+			failed = append(failed, fmt.Sprintln(r.Name, "-", err.Error()))
+		}
+		c.report.recordOutcome(EntityRole, sourceId, r.Name, result, err)
+		bar.Add(1)
+	}
+	bar.Finish()
+
+	reportFailed(failed, "roles:")
+	return nil
+}
+
+func (c RoleContext) Scan(g *DependencyGraph) error {
+
+	roles, err := listRoles(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, r := range roles {
+		if !c.filter.Match(EntityRole, r.Identifier) {
+			continue
+		}
+		g.Add(EntityRole, r.Identifier, r.Name, nil)
+	}
+	return nil
+}
+
+func (c RoleContext) Verify(v *Verification) error {
+
+	roles, err := listRoles(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	targetRoles, err := listRoles(c.target.asSource(), c.targetOrg, c.targetProject)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, r := range roles {
+		if !c.filter.Match(EntityRole, r.Identifier) {
+			continue
+		}
+		count++
+		sourceId := r.Identifier
+		r.Identifier = c.mapping.Identifier(EntityRole, r.Identifier)
+		v.check(EntityRole, sourceId, r.Name, c.target.verifyWith(EntityRole, r.Identifier, c.targetOrg, c.targetProject, nil, r, false))
+	}
+	v.count(EntityRole, count, len(targetRoles))
+	return nil
+}
+
+// isBuiltIn reports whether the role or resource group is managed by Harness,
+// their identifiers start with an underscore
+func isBuiltIn(identifier string) bool {
+	return strings.HasPrefix(identifier, "_")
+}
+
+// listRoles returns the custom roles of the project
+func listRoles(s *SourceRequest, org, project string) ([]*model.Role, error) {
+
+	resp, err := s.Client.R().
+		SetHeader("x-api-key", s.Token).
+		SetHeader("Content-Type", "application/json").
+		SetQueryParams(map[string]string{
+			"accountIdentifier": s.Account,
+			"orgIdentifier":     org,
+			"projectIdentifier": project,
+			"pageSize":          "1000",
+		}).
+		Get(s.Url + "/authz/api/roles")
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, handleErrorResponse(resp)
+	}
+
+	result := model.ListRoleResponse{}
+	err = json.Unmarshal(resp.Body(), &result)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := []*model.Role{}
+	for _, c := range result.Data.Content {
+		if !c.HarnessManaged && !isBuiltIn(c.Role.Identifier) {
+			roles = append(roles, c.Role)
+		}
+	}
+	return roles, nil
+}
+
+func (c RoleContext) createRole(role *model.Role) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(role).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+			"orgIdentifier":     c.targetOrg,
+			"projectIdentifier": c.targetProject,
+		}).
+		Post(api.Url + "/authz/api/roles")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
+
+func (c RoleContext) updateRole(role *model.Role) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(role).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+			"orgIdentifier":     c.targetOrg,
+			"projectIdentifier": c.targetProject,
+		}).
+		Put(api.Url + "/authz/api/roles/" + role.Identifier)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleErrorResponse(resp)
+	}
+
+	return nil
+}
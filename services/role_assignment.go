@@ -0,0 +1,277 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/schollz/progressbar/v3"
+)
+
+type RoleAssignmentContext struct {
+	source        *SourceRequest
+	target        *TargetRequest
+	sourceOrg     string
+	sourceProject string
+	targetOrg     string
+	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
+	principals    *principalResolver
+}
+
+func NewRoleAssignmentOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) RoleAssignmentContext {
+	return RoleAssignmentContext{
+		source:        sourceApi,
+		target:        targetApi,
+		sourceOrg:     st.SourceOrg,
+		sourceProject: st.SourceProject,
+		targetOrg:     st.TargetOrg,
+		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
+		principals:    st.principalResolver(sourceApi, targetApi),
+	}
+}
+
+func (c RoleAssignmentContext) Move() error {
+
+	assignments, err := c.listAssignments(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+
+	bar := progressbar.Default(int64(len(assignments)), "Role Assignments")
+	var failed []string
+
+	for _, ra := range assignments {
+		if !c.filter.Match(EntityRoleAssignment, ra.Identifier) {
+			bar.Add(1)
+			continue
+		}
+		if err := c.copyAssignment(ra); err != nil {
+			failed = append(failed, fmt.Sprintln(roleAssignmentName(ra), "-", err.Error()))
+		}
+		bar.Add(1)
+	}
+	bar.Finish()
+
+	reportFailed(failed, "role assignments:")
+	return nil
+}
+
+// copyAssignment creates the assignment in the target and records the
+// outcome
+func (c RoleAssignmentContext) copyAssignment(ra *model.RoleAssignment) error {
+	sourceId := ra.Identifier
+	name := roleAssignmentName(ra)
+	if err := c.toTarget(ra); err != nil {
+		c.report.record(EntityRoleAssignment, sourceId, name, err)
+		return err
+	}
+
+	// an assignment with the same role, resource group and principal is the
+	// same assignment
+	result, err := c.state.upsert(c.onConflict, EntityRoleAssignment, sourceId, ra,
+		func() error { return createRoleAssignment(c.target, c.targetOrg, c.targetProject, ra) },
+		nil,
+		func() (string, error) { return "", nil },
+	)
+	c.report.recordOutcome(EntityRoleAssignment, sourceId, name, result, err)
+	return err
+}
+
+func (c RoleAssignmentContext) Scan(g *DependencyGraph) error {
+
+	assignments, err := c.listAssignments(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, ra := range assignments {
+		if !c.filter.Match(EntityRoleAssignment, ra.Identifier) {
+			continue
+		}
+		g.Add(EntityRoleAssignment, ra.Identifier, roleAssignmentName(ra), roleAssignmentRefs(ra))
+	}
+	return nil
+}
+
+func (c RoleAssignmentContext) Verify(v *Verification) error {
+
+	assignments, err := c.listAssignments(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	targetAssignments, err := c.listAssignments(c.target.asSource(), c.targetOrg, c.targetProject)
+	if err != nil {
+		return err
+	}
+
+	var selected []*model.RoleAssignment
+	for _, ra := range assignments {
+		if c.filter.Match(EntityRoleAssignment, ra.Identifier) {
+			selected = append(selected, ra)
+		}
+	}
+	c.verifyAssignments(v, selected, targetAssignments)
+	v.count(EntityRoleAssignment, len(selected), len(targetAssignments))
+	return nil
+}
+
+// verifyAssignments checks that every source assignment has one in the
+// target with the same role, resource group and principal
+func (c RoleAssignmentContext) verifyAssignments(v *Verification, assignments, targetAssignments []*model.RoleAssignment) {
+	existing := map[string]bool{}
+	for _, ra := range targetAssignments {
+		existing[roleAssignmentKey(ra)] = true
+	}
+	for _, ra := range assignments {
+		sourceId := ra.Identifier
+		name := roleAssignmentName(ra)
+		if err := c.toTarget(ra); err != nil {
+			v.failed(EntityRoleAssignment, sourceId, name, err)
+			continue
+		}
+		key := roleAssignmentKey(ra)
+		v.check(EntityRoleAssignment, sourceId, name, func() (string, error) {
+			if !existing[key] {
+				return "", ErrEntityNotFound
+			}
+			return "", nil
+		})
+	}
+}
+
+// listAssignments lists the assignments copied by this step, the ones of the
+// project service accounts are copied with the service accounts
+func (c RoleAssignmentContext) listAssignments(s *SourceRequest, org, project string) ([]*model.RoleAssignment, error) {
+	assignments, err := listRoleAssignments(s, org, project)
+	if err != nil {
+		return nil, err
+	}
+	result := []*model.RoleAssignment{}
+	for _, ra := range assignments {
+		if !isProjectServiceAccount(ra.Principal) {
+			result = append(result, ra)
+		}
+	}
+	return result, nil
+}
+
+// toTarget maps the role, the resource group and the principal of the
+// assignment. The identifier is left to the target.
+func (c RoleAssignmentContext) toTarget(ra *model.RoleAssignment) error {
+	principal, err := c.principals.principal(ra.Principal, c.mapping)
+	if err != nil {
+		return err
+	}
+	ra.Principal = principal
+	ra.Identifier = ""
+	if !isBuiltIn(ra.RoleIdentifier) {
+		ra.RoleIdentifier = c.mapping.Identifier(EntityRole, ra.RoleIdentifier)
+	}
+	if !isBuiltIn(ra.ResourceGroupIdentifier) {
+		ra.ResourceGroupIdentifier = c.mapping.Identifier(EntityResourceGroup, ra.ResourceGroupIdentifier)
+	}
+	return nil
+}
+
+// roleAssignmentRefs returns the custom role, resource group and project
+// principal used by the assignment
+func roleAssignmentRefs(ra *model.RoleAssignment) []EntityRef {
+	var refs []EntityRef
+	if !isBuiltIn(ra.RoleIdentifier) {
+		refs = append(refs, EntityRef{Type: EntityRole, Scope: ScopeProject, Identifier: ra.RoleIdentifier})
+	}
+	if !isBuiltIn(ra.ResourceGroupIdentifier) {
+		refs = append(refs, EntityRef{Type: EntityResourceGroup, Scope: ScopeProject, Identifier: ra.ResourceGroupIdentifier})
+	}
+	if p := ra.Principal; p != nil && p.Type == model.PrincipalUserGroup && strings.EqualFold(p.ScopeLevel, string(ScopeProject)) {
+		refs = append(refs, EntityRef{Type: EntityUserGroup, Scope: ScopeProject, Identifier: p.Identifier})
+	}
+	return refs
+}
+
+func isProjectServiceAccount(p *model.Principal) bool {
+	return p != nil && p.Type == model.PrincipalServiceAccount && strings.EqualFold(p.ScopeLevel, string(ScopeProject))
+}
+
+func roleAssignmentName(ra *model.RoleAssignment) string {
+	if ra.Principal == nil {
+		return ra.RoleIdentifier
+	}
+	return fmt.Sprintf("%s %s: %s on %s", ra.Principal.Type, ra.Principal.Identifier, ra.RoleIdentifier, ra.ResourceGroupIdentifier)
+}
+
+func roleAssignmentKey(ra *model.RoleAssignment) string {
+	key := ra.RoleIdentifier + "/" + ra.ResourceGroupIdentifier
+	if ra.Principal != nil {
+		key += "/" + string(ra.Principal.Type) + "/" + ra.Principal.Identifier
+	}
+	return key
+}
+
+// listRoleAssignments returns the role assignments of the project not managed
+// by Harness
+func listRoleAssignments(s *SourceRequest, org, project string) ([]*model.RoleAssignment, error) {
+
+	resp, err := s.Client.R().
+		SetHeader("x-api-key", s.Token).
+		SetHeader("Content-Type", "application/json").
+		SetQueryParams(map[string]string{
+			"accountIdentifier": s.Account,
+			"orgIdentifier":     org,
+			"projectIdentifier": project,
+			"pageSize":          "1000",
+		}).
+		Get(s.Url + "/authz/api/roleassignments")
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, handleErrorResponse(resp)
+	}
+
+	result := model.ListRoleAssignmentResponse{}
+	err = json.Unmarshal(resp.Body(), &result)
+	if err != nil {
+		return nil, err
+	}
+
+	assignments := []*model.RoleAssignment{}
+	for _, c := range result.Data.Content {
+		if !c.HarnessManaged && !c.RoleAssignment.Managed {
+			assignments = append(assignments, c.RoleAssignment)
+		}
+	}
+	return assignments, nil
+}
+
+func createRoleAssignment(t *TargetRequest, org, project string, ra *model.RoleAssignment) error {
+
+	resp, err := t.Client.R().
+		SetHeader("x-api-key", t.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(ra).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": t.Account,
+			"orgIdentifier":     org,
+			"projectIdentifier": project,
+		}).
+		Post(t.Url + "/authz/api/roleassignments")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
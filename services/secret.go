@@ -18,6 +18,11 @@ type SecretContext struct {
 	sourceProject string
 	targetOrg     string
 	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
 }
 
 func NewSecretOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) SecretContext {
@@ -28,6 +33,11 @@ func NewSecretOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *
 		sourceProject: st.SourceProject,
 		targetOrg:     st.TargetOrg,
 		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
 	}
 }
 
@@ -52,13 +62,31 @@ func (sc SecretContext) Move() error {
 	bar := progressbar.Default(int64(len(secrets)), "Secrets")
 	var failed []string
 	for _, secret := range secrets {
+		if !sc.filter.Match(EntitySecret, secret.Identifier) {
+			bar.Add(1)
+			continue
+		}
+		if reused, found := sc.mapping.Reused(EntitySecret, secret.Identifier); found {
+			sc.report.recordReused(EntitySecret, secret.Identifier, secret.Name, reused)
+			bar.Add(1)
+			continue
+		}
+		sourceId := secret.Identifier
+		secret.Identifier = sc.mapping.Identifier(EntitySecret, secret.Identifier)
 		secret.OrgIdentifier = sc.targetOrg
 		secret.ProjectIdentifier = sc.targetProject
+		sc.mapSecretManager(secret)
 
-		err = sc.createSecret(secret)
+		// THE SOURCE VALUES ARE NOT READABLE, SO EXISTING SECRETS ARE NEVER UPDATED
+		result, err := sc.state.upsert(sc.onConflict, EntitySecret, sourceId, secret,
+			func() error { return sc.createSecret(secret) },
+			nil,
+			sc.target.compareWith(EntitySecret, secret.Identifier, sc.targetOrg, sc.targetProject, nil, secret),
+		)
 		if err != nil {
 			failed = append(failed, fmt.Sprintln(secret.Name, "-", err.Error()))
 		}
+		sc.report.recordOutcome(EntitySecret, sourceId, secret.Name, result, err)
 
 		bar.Add(1)
 	}
@@ -68,6 +96,154 @@ func (sc SecretContext) Move() error {
 	return nil
 }
 
+func (sc SecretContext) Scan(g *DependencyGraph) error {
+
+	secrets, err := sc.listSecrets(sc.sourceOrg, sc.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, secret := range secrets {
+		if !sc.filter.Match(EntitySecret, secret.Identifier) || sc.mapping.reused(EntitySecret, secret.Identifier) {
+			continue
+		}
+		g.Add(EntitySecret, secret.Identifier, secret.Name, secretReferences(secret))
+	}
+	return nil
+}
+
+func (sc SecretContext) Verify(v *Verification) error {
+
+	secrets, err := sc.listSecrets(sc.sourceOrg, sc.sourceProject)
+	if err != nil {
+		return err
+	}
+	target := sc
+	target.source = sc.target.asSource()
+	targetSecrets, err := target.listSecrets(sc.targetOrg, sc.targetProject)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, secret := range secrets {
+		if !sc.filter.Match(EntitySecret, secret.Identifier) || sc.mapping.reused(EntitySecret, secret.Identifier) {
+			continue
+		}
+		count++
+		sourceId := secret.Identifier
+		secret.Identifier = sc.mapping.Identifier(EntitySecret, secret.Identifier)
+		secret.OrgIdentifier = sc.targetOrg
+		secret.ProjectIdentifier = sc.targetProject
+		v.check(EntitySecret, sourceId, secret.Name, sc.target.verifyWith(EntitySecret, secret.Identifier, sc.targetOrg, sc.targetProject, nil, secret, false))
+	}
+	v.count(EntitySecret, count, len(targetSecrets))
+	return nil
+}
+
+// Export describes text and file secrets, their values are inputs of the
+// configuration since the source values are not readable
+func (sc SecretContext) Export(tf *TerraformExport) error {
+
+	secrets, err := sc.listSecrets(sc.sourceOrg, sc.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, secret := range secrets {
+		if !sc.filter.Match(EntitySecret, secret.Identifier) || sc.mapping.reused(EntitySecret, secret.Identifier) {
+			continue
+		}
+		id := sc.mapping.Identifier(EntitySecret, secret.Identifier)
+		sc.mapSecretManager(secret)
+		switch {
+		case secret.Type_ == nextgen.SecretTypes.SecretText && secret.Text != nil:
+			value := tfExpression("value", tf.variable("secret_"+id, "Value of the secret "+secret.Name, true))
+			// REFERENCES TO AN EXTERNAL SECRET MANAGER ARE NOT SENSITIVE
+			if secret.Text.ValueType == nextgen.SecretTextValueTypes.Reference {
+				value = tfString("value", secret.Text.Value)
+			}
+			tf.add(EntitySecret, id, "harness_platform_secret_text", id, tf.importId(id), tf.entity(id, secret.Name,
+				tfString("description", secret.Description),
+				tfTags(secret.Tags),
+				tfReference("secret_manager_identifier", EntityConnector, secret.Text.SecretManagerIdentifier),
+				tfString("value_type", string(secret.Text.ValueType)),
+				value,
+			)...)
+		case secret.Type_ == nextgen.SecretTypes.SecretFile && secret.File != nil:
+			tf.add(EntitySecret, id, "harness_platform_secret_file", id, tf.importId(id), tf.entity(id, secret.Name,
+				tfString("description", secret.Description),
+				tfTags(secret.Tags),
+				tfReference("secret_manager_identifier", EntityConnector, secret.File.SecretManagerIdentifier),
+				tfExpression("file_path", tf.variable("secret_file_"+id, "Path of the file of the secret "+secret.Name, false)),
+			)...)
+		default:
+			tf.unsupported(EntitySecret, secret.Name, fmt.Sprintf("secret type %s is not supported", secret.Type_))
+		}
+	}
+	return nil
+}
+
+func (sc SecretContext) Prune() error {
+
+	secrets, err := sc.listSecrets(sc.sourceOrg, sc.sourceProject)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for _, secret := range secrets {
+		ids = append(ids, secret.Identifier)
+	}
+	sc.state.prune(EntitySecret, ids, sc.report, func(sourceId string) error {
+		return sc.target.deleteSynced(sc.mapping, EntitySecret, sourceId, sc.targetOrg, sc.targetProject)
+	})
+	return nil
+}
+
+// harnessSecretManager is the built-in secret manager of every scope, it is
+// never copied nor renamed
+const harnessSecretManager = "harnessSecretManager"
+
+// secretManagerRef returns the connector storing the secret, false for the
+// built-in secret manager
+func secretManagerRef(value string) (EntityRef, bool) {
+	ref, ok := newEntityRef(EntityConnector, value)
+	if !ok || ref.Identifier == harnessSecretManager {
+		return EntityRef{}, false
+	}
+	return ref, true
+}
+
+// secretReferences returns the secret manager storing the secret
+func secretReferences(secret *nextgen.Secret) []EntityRef {
+	var refs []EntityRef
+	if secret.Text != nil {
+		if ref, ok := secretManagerRef(secret.Text.SecretManagerIdentifier); ok {
+			refs = append(refs, ref)
+		}
+	}
+	if secret.File != nil {
+		if ref, ok := secretManagerRef(secret.File.SecretManagerIdentifier); ok {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// mapSecretManager points the secret to the renamed or reused secret manager
+func (sc SecretContext) mapSecretManager(secret *nextgen.Secret) {
+	mapped := func(value string) string {
+		if _, ok := secretManagerRef(value); !ok {
+			return value
+		}
+		return sc.mapping.refValue(EntityConnector, value)
+	}
+	if secret.Text != nil {
+		secret.Text.SecretManagerIdentifier = mapped(secret.Text.SecretManagerIdentifier)
+	}
+	if secret.File != nil {
+		secret.File.SecretManagerIdentifier = mapped(secret.File.SecretManagerIdentifier)
+	}
+}
+
 func (sc SecretContext) listSecrets(org string, project string) ([]*nextgen.Secret, error) {
 
 	api := sc.source
@@ -145,7 +321,7 @@ func (sc SecretContext) createSecretText(secret *nextgen.Secret) error {
 		return err
 	}
 	if resp.IsError() {
-		return handleErrorResponse(resp)
+		return handleCreateResponse(resp)
 	}
 
 	return nil
@@ -180,7 +356,7 @@ func (sc SecretContext) createSecretFile(secret *nextgen.Secret) error {
 		return err
 	}
 	if resp.IsError() {
-		return handleErrorResponse(resp)
+		return handleCreateResponse(resp)
 	}
 
 	return nil
@@ -207,7 +383,7 @@ func (sc SecretContext) createSecretSSHKey(secret *nextgen.Secret) error {
 		return err
 	}
 	if resp.IsError() {
-		return handleErrorResponse(resp)
+		return handleCreateResponse(resp)
 	}
 
 	return nil
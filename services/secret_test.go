@@ -0,0 +1,57 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/go-resty/resty/v2"
+	"github.com/harness/harness-go-sdk/harness/nextgen"
+	"github.com/stretchr/testify/assert"
+)
+
+const TEST_SECRETS_LIST = `{"status":"SUCCESS","data":{"content":[
+  {"secret":{"type":"SecretText","name":"token","identifier":"token","spec":{"secretManagerIdentifier":"vault","valueType":"Inline"}}},
+  {"secret":{"type":"SecretText","name":"key","identifier":"key","spec":{"secretManagerIdentifier":"harnessSecretManager","valueType":"Inline"}}}
+]}}`
+
+func TestSecretMove_RenamesSecretManager(t *testing.T) {
+	var created []model.CreateSecretRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ng/api/v2/secrets/list/secrets":
+			w.Write([]byte(TEST_SECRETS_LIST))
+		case "/ng/api/v2/secrets":
+			request := model.CreateSecretRequest{}
+			json.NewDecoder(r.Body).Decode(&request)
+			created = append(created, request)
+			w.Write([]byte(`{"status":"SUCCESS"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sc := NewSecretOperation(&SourceRequest{Client: resty.New(), Url: server.URL}, &TargetRequest{Client: resty.New(), Url: server.URL}, &SourceTarget{
+		Mapping: IdentifierMapping{EntityConnector: {Prefix: "legacy_"}},
+		Report:  NewReport(),
+	})
+
+	assert.NoError(t, sc.Move())
+	assert.Len(t, created, 2)
+	assert.Equal(t, "legacy_vault", created[0].Secret.Text.SecretManagerIdentifier)
+	// THE BUILT-IN SECRET MANAGER IS NEVER RENAMED
+	assert.Equal(t, "harnessSecretManager", created[1].Secret.Text.SecretManagerIdentifier)
+}
+
+func TestSecretReferences(t *testing.T) {
+	vault := &nextgen.Secret{Text: &nextgen.SecretTextSpec{SecretManagerIdentifier: "vault"}}
+	orgVault := &nextgen.Secret{File: &nextgen.SecretFileSpe{SecretManagerIdentifier: "org.vault"}}
+	builtIn := &nextgen.Secret{Text: &nextgen.SecretTextSpec{SecretManagerIdentifier: "account.harnessSecretManager"}}
+
+	assert.Equal(t, []EntityRef{{Type: EntityConnector, Scope: ScopeProject, Identifier: "vault"}}, secretReferences(vault))
+	assert.Equal(t, []EntityRef{{Type: EntityConnector, Scope: ScopeOrg, Identifier: "vault"}}, secretReferences(orgVault))
+	assert.Empty(t, secretReferences(builtIn))
+}
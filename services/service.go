@@ -10,6 +10,7 @@ import (
 
 const LIST_SERVICES = "/ng/api/servicesV2"
 const CREATE_SERVICES = "/ng/api/servicesV2"
+const UPDATE_SERVICES = "/ng/api/servicesV2"
 
 type ServiceContext struct {
 	source        *SourceRequest
@@ -18,6 +19,12 @@ type ServiceContext struct {
 	sourceProject string
 	targetOrg     string
 	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
+	since         int64
 }
 
 func NewServiceOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) ServiceContext {
@@ -28,6 +35,12 @@ func NewServiceOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st
 		sourceProject: st.SourceProject,
 		targetOrg:     st.TargetOrg,
 		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
+		since:         st.ModifiedSince,
 	}
 }
 
@@ -42,18 +55,39 @@ func (c ServiceContext) Move() error {
 	var failed []string
 
 	for _, s := range services {
-		newYaml := createYaml(s.Service.Yaml, c.sourceOrg, c.sourceProject, c.targetOrg, c.targetProject)
+		if !c.filter.Match(EntityService, s.Service.Identifier) {
+			bar.Add(1)
+			continue
+		}
+		if unmodified(c.since, s.LastModifiedAt) {
+			c.report.recordUnmodified(EntityService, s.Service.Identifier, s.Service.Name)
+			bar.Add(1)
+			continue
+		}
+		newYaml, err := createYaml(s.Service.Yaml, c.targetOrg, c.targetProject, c.mapping.transform(EntityService))
+		if err != nil {
+			failed = append(failed, fmt.Sprintln(s.Service.Name, "-", err.Error()))
+			c.report.record(EntityService, s.Service.Identifier, s.Service.Name, err)
+			bar.Add(1)
+			continue
+		}
 		service := &model.CreateServiceRequest{
 			OrgIdentifier:     c.targetOrg,
 			ProjectIdentifier: c.targetProject,
-			Identifier:        s.Service.Identifier,
+			Identifier:        c.mapping.Identifier(EntityService, s.Service.Identifier),
 			Name:              s.Service.Name,
 			Description:       s.Service.Description,
 			Yaml:              newYaml,
 		}
-		if err := createService(c.target, service); err != nil {
+		result, err := c.state.upsert(c.onConflict, EntityService, s.Service.Identifier, service,
+			func() error { return createService(c.target, service) },
+			func() error { return updateService(c.target, service) },
+			c.target.compareWith(EntityService, service.Identifier, c.targetOrg, c.targetProject, nil, newYaml),
+		)
+		if err != nil {
 			failed = append(failed, fmt.Sprintln(s.Service.Name, "-", err.Error()))
 		}
+		c.report.recordOutcome(EntityService, s.Service.Identifier, s.Service.Name, result, err)
 		bar.Add(1)
 	}
 	bar.Finish()
@@ -62,6 +96,98 @@ func (c ServiceContext) Move() error {
 	return nil
 }
 
+func (c ServiceContext) Scan(g *DependencyGraph) error {
+
+	services, err := listServices(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, s := range services {
+		if !c.filter.Match(EntityService, s.Service.Identifier) || unmodified(c.since, s.LastModifiedAt) {
+			continue
+		}
+		refs, err := extractReferences(s.Service.Yaml)
+		if err != nil {
+			g.AddUnscanned(EntityService, s.Service.Identifier, s.Service.Name, err)
+			continue
+		}
+		g.Add(EntityService, s.Service.Identifier, s.Service.Name, refs)
+	}
+	return nil
+}
+
+func (c ServiceContext) Verify(v *Verification) error {
+
+	services, err := listServices(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	targetServices, err := listServices(c.target.asSource(), c.targetOrg, c.targetProject)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, s := range services {
+		if !c.filter.Match(EntityService, s.Service.Identifier) {
+			continue
+		}
+		count++
+		newYaml, err := createYaml(s.Service.Yaml, c.targetOrg, c.targetProject, c.mapping.transform(EntityService))
+		if err != nil {
+			v.failed(EntityService, s.Service.Identifier, s.Service.Name, err)
+			continue
+		}
+		targetId := c.mapping.Identifier(EntityService, s.Service.Identifier)
+		v.check(EntityService, s.Service.Identifier, s.Service.Name, c.target.verifyWith(EntityService, targetId, c.targetOrg, c.targetProject, nil, newYaml, false))
+	}
+	v.count(EntityService, count, len(targetServices))
+	return nil
+}
+
+func (c ServiceContext) Export(tf *TerraformExport) error {
+
+	services, err := listServices(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, s := range services {
+		if !c.filter.Match(EntityService, s.Service.Identifier) {
+			continue
+		}
+		newYaml, err := createYaml(s.Service.Yaml, c.targetOrg, c.targetProject, c.mapping.transform(EntityService))
+		if err != nil {
+			return fmt.Errorf("service %s: %w", s.Service.Name, err)
+		}
+		var description string
+		if s.Service.Description != nil {
+			description = *s.Service.Description
+		}
+		id := c.mapping.Identifier(EntityService, s.Service.Identifier)
+		tf.add(EntityService, id, "harness_platform_service", id, tf.importId(id), tf.entity(id, s.Service.Name,
+			tfString("description", description),
+			tfYamlDoc("yaml", newYaml),
+		)...)
+	}
+	return nil
+}
+
+func (c ServiceContext) Prune() error {
+
+	services, err := listServices(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for _, s := range services {
+		ids = append(ids, s.Service.Identifier)
+	}
+	c.state.prune(EntityService, ids, c.report, func(sourceId string) error {
+		return c.target.deleteSynced(c.mapping, EntityService, sourceId, c.targetOrg, c.targetProject)
+	})
+	return nil
+}
+
 func listServices(s *SourceRequest, org, project string) ([]*model.ServiceListContent, error) {
 
 	resp, err := s.Client.R().
@@ -103,6 +229,26 @@ func createService(t *TargetRequest, service *model.CreateServiceRequest) error
 	if err != nil {
 		return err
 	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
+
+func updateService(t *TargetRequest, service *model.CreateServiceRequest) error {
+
+	resp, err := t.Client.R().
+		SetHeader("x-api-key", t.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(service).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": t.Account,
+		}).
+		Put(t.Url + UPDATE_SERVICES)
+	if err != nil {
+		return err
+	}
 	if resp.IsError() {
 		return handleErrorResponse(resp)
 	}
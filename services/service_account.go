@@ -0,0 +1,419 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/schollz/progressbar/v3"
+)
+
+const apiKeyTypeServiceAccount = "SERVICE_ACCOUNT"
+
+// MintedToken is a token created in the target for a copied API key
+type MintedToken struct {
+	ServiceAccount string `json:"serviceAccount"`
+	ApiKey         string `json:"apiKey"`
+	Identifier     string `json:"identifier"`
+	Token          string `json:"token"`
+}
+
+// TokenFile collects the tokens minted during the move. It is safe for
+// concurrent use.
+type TokenFile struct {
+	mu     sync.Mutex
+	Tokens []MintedToken `json:"tokens"`
+}
+
+func NewTokenFile() *TokenFile {
+	return &TokenFile{Tokens: []MintedToken{}}
+}
+
+func (f *TokenFile) add(token MintedToken) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Tokens = append(f.Tokens, token)
+}
+
+// Write saves the tokens as JSON, readable only by the owner
+func (f *TokenFile) Write(path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0600)
+}
+
+type ServiceAccountContext struct {
+	source        *SourceRequest
+	target        *TargetRequest
+	sourceOrg     string
+	sourceProject string
+	targetOrg     string
+	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
+	tokens        *TokenFile
+	assignments   RoleAssignmentContext
+}
+
+func NewServiceAccountOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) ServiceAccountContext {
+	return ServiceAccountContext{
+		source:        sourceApi,
+		target:        targetApi,
+		sourceOrg:     st.SourceOrg,
+		sourceProject: st.SourceProject,
+		targetOrg:     st.TargetOrg,
+		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
+		tokens:        st.Tokens,
+		assignments:   NewRoleAssignmentOperation(sourceApi, targetApi, st),
+	}
+}
+
+func (c ServiceAccountContext) Move() error {
+
+	accounts, err := listServiceAccounts(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	assignments, err := listRoleAssignments(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+
+	bar := progressbar.Default(int64(len(accounts)), "Service Accounts")
+	var failed []string
+
+	for _, sa := range accounts {
+		if !c.filter.Match(EntityServiceAccount, sa.Identifier) {
+			bar.Add(1)
+			continue
+		}
+		sourceId := sa.Identifier
+		c.toTarget(sa)
+
+		result, err := c.state.upsert(c.onConflict, EntityServiceAccount, sourceId, sa,
+			func() error { return c.createServiceAccount(sa) },
+			func() error { return c.updateServiceAccount(sa) },
+			c.target.compareWith(EntityServiceAccount, sa.Identifier, c.targetOrg, c.targetProject, nil, sa),
+		)
+		// THE API KEYS ARE COPIED ONLY WHEN THE SERVICE ACCOUNT IS WRITTEN
+		if err == nil && (result.status == StatusCreated || result.status == StatusUpdated) {
+			err = c.copyApiKeys(sourceId, sa.Identifier)
+			if err != nil {
+				result.status = StatusFailed
+			}
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintln(sa.Name, "-", err.Error()))
+		}
+		c.report.recordOutcome(EntityServiceAccount, sourceId, sa.Name, result, err)
+
+		for _, ra := range serviceAccountAssignments(assignments, sourceId) {
+			if err := c.assignments.copyAssignment(ra); err != nil {
+				failed = append(failed, fmt.Sprintln(sa.Name, roleAssignmentName(ra), "-", err.Error()))
+			}
+		}
+		bar.Add(1)
+	}
+	bar.Finish()
+
+	reportFailed(failed, "service accounts:")
+	return nil
+}
+
+func (c ServiceAccountContext) Scan(g *DependencyGraph) error {
+
+	accounts, err := listServiceAccounts(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	assignments, err := listRoleAssignments(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, sa := range accounts {
+		if !c.filter.Match(EntityServiceAccount, sa.Identifier) {
+			continue
+		}
+		var refs []EntityRef
+		for _, ra := range serviceAccountAssignments(assignments, sa.Identifier) {
+			refs = append(refs, roleAssignmentRefs(ra)...)
+		}
+		g.Add(EntityServiceAccount, sa.Identifier, sa.Name, refs)
+	}
+	return nil
+}
+
+func (c ServiceAccountContext) Verify(v *Verification) error {
+
+	accounts, err := listServiceAccounts(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	assignments, err := listRoleAssignments(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	targetAccounts, err := listServiceAccounts(c.target.asSource(), c.targetOrg, c.targetProject)
+	if err != nil {
+		return err
+	}
+	targetAssignments, err := listRoleAssignments(c.target.asSource(), c.targetOrg, c.targetProject)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, sa := range accounts {
+		if !c.filter.Match(EntityServiceAccount, sa.Identifier) {
+			continue
+		}
+		count++
+		sourceId := sa.Identifier
+		c.toTarget(sa)
+		v.check(EntityServiceAccount, sourceId, sa.Name, c.target.verifyWith(EntityServiceAccount, sa.Identifier, c.targetOrg, c.targetProject, nil, sa, false))
+		c.assignments.verifyAssignments(v, serviceAccountAssignments(assignments, sourceId), targetAssignments)
+	}
+	v.count(EntityServiceAccount, count, len(targetAccounts))
+	return nil
+}
+
+// toTarget moves the service account to the target project. The email is
+// derived from the identifier, so it follows the renames.
+func (c ServiceAccountContext) toTarget(sa *model.ServiceAccount) {
+	sourceId := sa.Identifier
+	sa.Identifier = c.mapping.Identifier(EntityServiceAccount, sa.Identifier)
+	sa.AccountIdentifier = c.target.Account
+	sa.OrgIdentifier = c.targetOrg
+	sa.ProjectIdentifier = c.targetProject
+	if local, domain, found := strings.Cut(sa.Email, "@"); found && strings.EqualFold(local, sourceId) {
+		sa.Email = strings.ToLower(sa.Identifier) + "@" + domain
+	}
+}
+
+// copyApiKeys creates the API keys of the service account in the target. The
+// token values can't be read, new tokens are minted when a token file is set.
+func (c ServiceAccountContext) copyApiKeys(sourceId, targetId string) error {
+	keys, err := listApiKeys(c.source, c.sourceOrg, c.sourceProject, sourceId)
+	if err != nil {
+		return fmt.Errorf("listing api keys: %w", err)
+	}
+	for _, key := range keys {
+		key.AccountIdentifier = c.target.Account
+		key.OrgIdentifier = c.targetOrg
+		key.ProjectIdentifier = c.targetProject
+		key.ParentIdentifier = targetId
+		// ONLY THE KEYS CREATED NOW GET A TOKEN, RERUNS DON'T MINT NEW ONES
+		err := c.createApiKey(key)
+		if errors.Is(err, ErrEntityExists) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("api key %s: %w", key.Identifier, err)
+		}
+		if c.tokens == nil {
+			continue
+		}
+		token, err := c.mintToken(key)
+		if err != nil {
+			return fmt.Errorf("api key %s token: %w", key.Identifier, err)
+		}
+		c.tokens.add(token)
+	}
+	return nil
+}
+
+func serviceAccountAssignments(assignments []*model.RoleAssignment, identifier string) []*model.RoleAssignment {
+	var result []*model.RoleAssignment
+	for _, ra := range assignments {
+		if isProjectServiceAccount(ra.Principal) && ra.Principal.Identifier == identifier {
+			result = append(result, ra)
+		}
+	}
+	return result
+}
+
+func listServiceAccounts(s *SourceRequest, org, project string) ([]*model.ServiceAccount, error) {
+
+	resp, err := s.Client.R().
+		SetHeader("x-api-key", s.Token).
+		SetHeader("Content-Type", "application/json").
+		SetQueryParams(map[string]string{
+			"accountIdentifier": s.Account,
+			"orgIdentifier":     org,
+			"projectIdentifier": project,
+		}).
+		Get(s.Url + "/ng/api/serviceaccount")
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, handleErrorResponse(resp)
+	}
+
+	result := model.ListServiceAccountResponse{}
+	err = json.Unmarshal(resp.Body(), &result)
+	if err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
+
+func listApiKeys(s *SourceRequest, org, project, serviceAccount string) ([]*model.ApiKey, error) {
+
+	resp, err := s.Client.R().
+		SetHeader("x-api-key", s.Token).
+		SetHeader("Content-Type", "application/json").
+		SetQueryParams(map[string]string{
+			"accountIdentifier": s.Account,
+			"orgIdentifier":     org,
+			"projectIdentifier": project,
+			"apiKeyType":        apiKeyTypeServiceAccount,
+			"parentIdentifier":  serviceAccount,
+		}).
+		Get(s.Url + "/ng/api/apikey")
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, handleErrorResponse(resp)
+	}
+
+	result := model.ListApiKeyResponse{}
+	err = json.Unmarshal(resp.Body(), &result)
+	if err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
+
+func (c ServiceAccountContext) createServiceAccount(sa *model.ServiceAccount) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(sa).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+			"orgIdentifier":     c.targetOrg,
+			"projectIdentifier": c.targetProject,
+		}).
+		Post(api.Url + "/ng/api/serviceaccount")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
+
+func (c ServiceAccountContext) updateServiceAccount(sa *model.ServiceAccount) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(sa).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+			"orgIdentifier":     c.targetOrg,
+			"projectIdentifier": c.targetProject,
+		}).
+		Put(api.Url + "/ng/api/serviceaccount/" + sa.Identifier)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleErrorResponse(resp)
+	}
+
+	return nil
+}
+
+// createApiKey returns ErrEntityExists for the keys already in the target
+func (c ServiceAccountContext) createApiKey(key *model.ApiKey) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(key).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+		}).
+		Post(api.Url + "/ng/api/apikey")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
+
+// tokenSequence keeps the identifiers of the tokens minted in the same second
+// apart
+var tokenSequence atomic.Int64
+
+func (c ServiceAccountContext) mintToken(key *model.ApiKey) (MintedToken, error) {
+
+	identifier := fmt.Sprintf("%s_moved_%d_%d", key.Identifier, time.Now().Unix(), tokenSequence.Add(1))
+	request := &model.CreateTokenRequest{
+		Identifier:        identifier,
+		Name:              identifier,
+		AccountIdentifier: key.AccountIdentifier,
+		OrgIdentifier:     key.OrgIdentifier,
+		ProjectIdentifier: key.ProjectIdentifier,
+		ApiKeyIdentifier:  key.Identifier,
+		ParentIdentifier:  key.ParentIdentifier,
+		ApiKeyType:        apiKeyTypeServiceAccount,
+	}
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(request).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+		}).
+		Post(api.Url + "/ng/api/token")
+	if err != nil {
+		return MintedToken{}, err
+	}
+	if resp.IsError() {
+		return MintedToken{}, handleCreateResponse(resp)
+	}
+
+	result := model.CreateTokenResponse{}
+	if err := json.Unmarshal(resp.Body(), &result); err != nil {
+		return MintedToken{}, err
+	}
+	return MintedToken{
+		ServiceAccount: key.ParentIdentifier,
+		ApiKey:         key.Identifier,
+		Identifier:     identifier,
+		Token:          result.Data,
+	}, nil
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/Fernando-Dourado/harness-move-project/model"
@@ -15,6 +16,11 @@ type ServiceOverrideContext struct {
 	sourceProject string
 	targetOrg     string
 	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
 }
 
 func NewServiceOverrideOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) ServiceOverrideContext {
@@ -25,6 +31,11 @@ func NewServiceOverrideOperation(sourceApi *SourceRequest, targetApi *TargetRequ
 		sourceProject: st.SourceProject,
 		targetOrg:     st.TargetOrg,
 		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
 	}
 }
 
@@ -49,20 +60,48 @@ func (c ServiceOverrideContext) Move() error {
 		bar.ChangeMax(bar.GetMax() + len(overrides))
 
 		for _, o := range overrides {
+			id := o.EnvironmentRef + "/" + o.ServiceRef
+			if !c.filter.Match(EntityOverrideV1, id) {
+				bar.Add(1)
+				continue
+			}
+			result := outcome{status: StatusFailed}
 			if len(o.YAML) == 0 {
+				err = fmt.Errorf("the YAML is empty")
 				failed = append(failed, fmt.Sprintf("The YAML is empty [envId=%s,serviceRef=%s]", o.EnvironmentRef, o.ServiceRef))
 			} else {
-				err := createServiceOverride(c.target, &model.CreateServiceOverrideRequest{
-					OrgIdentifier:     c.targetOrg,
-					ProjectIdentifier: c.targetProject,
-					EnvironmentRef:    o.EnvironmentRef,
-					ServiceRef:        o.ServiceRef,
-					YAML:              o.YAML,
-				})
+				var newYaml string
+				newYaml, err = transformYaml(o.YAML, c.mapping.transform(EntityOverrideV1))
+				if err == nil {
+					req := &model.CreateServiceOverrideRequest{
+						OrgIdentifier:     c.targetOrg,
+						ProjectIdentifier: c.targetProject,
+						EnvironmentRef:    c.mapping.refValue(EntityEnvironment, o.EnvironmentRef),
+						ServiceRef:        c.mapping.refValue(EntityService, o.ServiceRef),
+						YAML:              newYaml,
+					}
+					// THE ENDPOINT UPSERTS, SO THE CONFLICT IS CHECKED BEFORE
+					var existing *model.ServiceOverride
+					result, err = c.state.upsert(c.onConflict, EntityOverrideV1, id, req,
+						func() error {
+							found, err := c.target.findServiceOverride(req)
+							if err != nil {
+								return err
+							}
+							if existing = found; existing != nil {
+								return ErrEntityExists
+							}
+							return createServiceOverride(c.target, req)
+						},
+						func() error { return createServiceOverride(c.target, req) },
+						func() (string, error) { return diffEntities(existing.YAML, req.YAML) },
+					)
+				}
 				if err != nil {
 					failed = append(failed, fmt.Sprintln(e.Name, "/", o.ServiceRef, "-", err.Error()))
 				}
 			}
+			c.report.recordOutcome(EntityOverrideV1, id, o.ServiceRef, result, err)
 			bar.Add(1)
 		}
 		bar.Add(1)
@@ -73,6 +112,126 @@ func (c ServiceOverrideContext) Move() error {
 	return nil
 }
 
+func (c ServiceOverrideContext) Scan(g *DependencyGraph) error {
+
+	envs, err := c.source.listEnvironments(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, env := range envs {
+		e := env.Environment
+		overrides, err := listServiceOverrides(c.source, c.sourceOrg, c.sourceProject, e.Identifier)
+		if err != nil {
+			return err
+		}
+		for _, o := range overrides {
+			if !c.filter.Match(EntityOverrideV1, o.EnvironmentRef+"/"+o.ServiceRef) {
+				continue
+			}
+			refs, err := extractReferences(o.YAML)
+			if err != nil {
+				g.AddUnscanned(EntityOverrideV1, o.EnvironmentRef+"/"+o.ServiceRef, o.ServiceRef, err)
+				continue
+			}
+			if ref, ok := newEntityRef(EntityEnvironment, o.EnvironmentRef); ok {
+				refs = append(refs, ref)
+			}
+			if ref, ok := newEntityRef(EntityService, o.ServiceRef); ok {
+				refs = append(refs, ref)
+			}
+			g.Add(EntityOverrideV1, o.EnvironmentRef+"/"+o.ServiceRef, o.ServiceRef, refs)
+		}
+	}
+	return nil
+}
+
+func (c ServiceOverrideContext) Verify(v *Verification) error {
+
+	envs, err := c.source.listEnvironments(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+
+	count, targetCount := 0, 0
+	for _, env := range envs {
+		e := env.Environment
+		overrides, err := listServiceOverrides(c.source, c.sourceOrg, c.sourceProject, e.Identifier)
+		if err != nil {
+			return err
+		}
+		targetOverrides, err := listServiceOverrides(c.target.asSource(), c.targetOrg, c.targetProject, c.mapping.Identifier(EntityEnvironment, e.Identifier))
+		if err != nil && !errors.Is(err, ErrEntityNotFound) {
+			return err
+		}
+		targetCount += len(targetOverrides)
+
+		for _, o := range overrides {
+			id := o.EnvironmentRef + "/" + o.ServiceRef
+			if !c.filter.Match(EntityOverrideV1, id) {
+				continue
+			}
+			count++
+			newYaml, err := transformYaml(o.YAML, c.mapping.transform(EntityOverrideV1))
+			if err != nil {
+				v.failed(EntityOverrideV1, id, o.ServiceRef, err)
+				continue
+			}
+			req := &model.CreateServiceOverrideRequest{
+				OrgIdentifier:     c.targetOrg,
+				ProjectIdentifier: c.targetProject,
+				EnvironmentRef:    c.mapping.refValue(EntityEnvironment, o.EnvironmentRef),
+				ServiceRef:        c.mapping.refValue(EntityService, o.ServiceRef),
+			}
+			v.check(EntityOverrideV1, id, o.ServiceRef, func() (string, error) {
+				existing, err := c.target.findServiceOverride(req)
+				if err != nil {
+					return "", err
+				}
+				if existing == nil {
+					return "", ErrEntityNotFound
+				}
+				return diffEntities(existing.YAML, newYaml)
+			})
+		}
+	}
+	v.count(EntityOverrideV1, count, targetCount)
+	return nil
+}
+
+// Export describes the overrides without import block, the provider
+// identifies them by a generated id
+func (c ServiceOverrideContext) Export(tf *TerraformExport) error {
+
+	envs, err := c.source.listEnvironments(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, env := range envs {
+		e := env.Environment
+		overrides, err := listServiceOverrides(c.source, c.sourceOrg, c.sourceProject, e.Identifier)
+		if err != nil {
+			return fmt.Errorf("service overrides of environment %s: %w", e.Name, err)
+		}
+		for _, o := range overrides {
+			if !c.filter.Match(EntityOverrideV1, o.EnvironmentRef+"/"+o.ServiceRef) || len(o.YAML) == 0 {
+				continue
+			}
+			newYaml, err := transformYaml(o.YAML, c.mapping.transform(EntityOverrideV1))
+			if err != nil {
+				return fmt.Errorf("service override %s/%s: %w", e.Name, o.ServiceRef, err)
+			}
+			envRef := c.mapping.refValue(EntityEnvironment, o.EnvironmentRef)
+			serviceRef := c.mapping.refValue(EntityService, o.ServiceRef)
+			tf.add(EntityOverrideV1, "", "harness_platform_environment_service_overrides", envRef+"_"+serviceRef, "", tf.scoped(
+				tfReference("env_id", EntityEnvironment, envRef),
+				tfReference("service_id", EntityService, serviceRef),
+				tfYamlDoc("yaml", newYaml),
+			)...)
+		}
+	}
+	return nil
+}
+
 func listServiceOverrides(s *SourceRequest, org, project, envId string) ([]*model.ServiceOverride, error) {
 
 	resp, err := s.Client.R().
@@ -120,3 +279,40 @@ func createServiceOverride(t *TargetRequest, override *model.CreateServiceOverri
 
 	return nil
 }
+
+// findServiceOverride returns the target override of the same environment and
+// service, nil when there is none
+func (t *TargetRequest) findServiceOverride(override *model.CreateServiceOverrideRequest) (*model.ServiceOverride, error) {
+
+	resp, err := t.Client.R().
+		SetHeader("x-api-key", t.Token).
+		SetQueryParams(map[string]string{
+			"accountIdentifier":     t.Account,
+			"orgIdentifier":         override.OrgIdentifier,
+			"projectIdentifier":     override.ProjectIdentifier,
+			"environmentIdentifier": override.EnvironmentRef,
+			"serviceIdentifier":     override.ServiceRef,
+		}).
+		Get(t.Url + "/ng/api/environmentsV2/serviceOverrides")
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		if err := handleErrorResponse(resp); !errors.Is(err, ErrEntityNotFound) {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	result := model.ListServiceOverridesRequest{}
+	err = json.Unmarshal(resp.Body(), &result)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range result.Data.Content {
+		if o.ServiceRef == override.ServiceRef {
+			return o, nil
+		}
+	}
+	return nil, nil
+}
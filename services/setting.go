@@ -0,0 +1,279 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/schollz/progressbar/v3"
+)
+
+// projectSettingsNode identifies the project settings in the dependency graph
+const projectSettingsNode = "Settings"
+
+// projectSettingsRef is referenced by the entities the settings apply to, so
+// the settings are copied first
+var projectSettingsRef = EntityRef{Type: EntitySetting, Scope: ScopeProject, Identifier: projectSettingsNode}
+
+// settingConnectors are the settings whose value is a connector reference
+var settingConnectors = map[string]bool{
+	"default_connector_for_git_experience": true,
+}
+
+const settingSourceProject = "PROJECT"
+
+type SettingContext struct {
+	source        *SourceRequest
+	target        *TargetRequest
+	sourceOrg     string
+	sourceProject string
+	targetOrg     string
+	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
+}
+
+func NewSettingOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) SettingContext {
+	return SettingContext{
+		source:        sourceApi,
+		target:        targetApi,
+		sourceOrg:     st.SourceOrg,
+		sourceProject: st.SourceProject,
+		targetOrg:     st.TargetOrg,
+		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
+	}
+}
+
+// settingView is the part of a setting copied to the target
+type settingView struct {
+	Value          *string `json:"value"`
+	AllowOverrides bool    `json:"allowOverrides"`
+}
+
+func (c SettingContext) Move() error {
+
+	settings, err := listSettings(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	targetSettings, err := c.targetSettings()
+	if err != nil {
+		return err
+	}
+
+	bar := progressbar.Default(int64(len(settings)), "Settings")
+	var failed []string
+
+	for _, s := range settings {
+		if !c.filter.Match(EntitySetting, s.Identifier) {
+			bar.Add(1)
+			continue
+		}
+		req := c.toTarget(s)
+		current := targetSettings[s.Identifier]
+		result, err := c.state.upsert(c.onConflict, EntitySetting, s.Identifier, req,
+			func() error {
+				// EVERY SETTING EXISTS, ONLY A PROJECT VALUE IS A CONFLICT
+				if current != nil && current.SettingSource == settingSourceProject {
+					return ErrEntityExists
+				}
+				return c.updateSetting(req)
+			},
+			func() error { return c.updateSetting(req) },
+			func() (string, error) {
+				if current == nil {
+					return "", ErrEntityNotFound
+				}
+				return diffEntities(newSettingView(current), &settingView{Value: req.Value, AllowOverrides: req.AllowOverrides})
+			},
+		)
+		if err != nil {
+			failed = append(failed, fmt.Sprintln(s.Name, "-", err.Error()))
+		}
+		c.report.recordOutcome(EntitySetting, s.Identifier, s.Name, result, err)
+		bar.Add(1)
+	}
+	bar.Finish()
+
+	reportFailed(failed, "settings:")
+	return nil
+}
+
+func (c SettingContext) Scan(g *DependencyGraph) error {
+
+	settings, err := listSettings(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	var refs []EntityRef
+	for _, s := range settings {
+		if !c.filter.Match(EntitySetting, s.Identifier) || !settingConnectors[s.Identifier] || s.Value == nil {
+			continue
+		}
+		if ref, ok := newEntityRef(EntityConnector, *s.Value); ok {
+			refs = append(refs, ref)
+		}
+	}
+	g.Add(EntitySetting, projectSettingsNode, "Project Settings", refs)
+	return nil
+}
+
+func (c SettingContext) Verify(v *Verification) error {
+
+	settings, err := listSettings(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	targetSettings, err := c.targetSettings()
+	if err != nil {
+		return err
+	}
+
+	count, targetCount := 0, 0
+	for _, t := range targetSettings {
+		if t.SettingSource == settingSourceProject {
+			targetCount++
+		}
+	}
+	for _, s := range settings {
+		if !c.filter.Match(EntitySetting, s.Identifier) {
+			continue
+		}
+		count++
+		req := c.toTarget(s)
+		current := targetSettings[s.Identifier]
+		v.check(EntitySetting, s.Identifier, s.Name, func() (string, error) {
+			if current == nil || current.SettingSource != settingSourceProject {
+				return "", ErrEntityNotFound
+			}
+			return diffEntities(newSettingView(current), &settingView{Value: req.Value, AllowOverrides: req.AllowOverrides})
+		})
+	}
+	v.count(EntitySetting, count, targetCount)
+	return nil
+}
+
+// toTarget returns the update of the setting, with the connector references
+// renamed
+func (c SettingContext) toTarget(s *model.Setting) *model.UpdateSettingRequest {
+	value := s.Value
+	if settingConnectors[s.Identifier] && value != nil {
+		mapped := c.mapping.refValue(EntityConnector, *value)
+		value = &mapped
+	}
+	return &model.UpdateSettingRequest{
+		Identifier:     s.Identifier,
+		Value:          value,
+		AllowOverrides: s.AllowOverrides,
+		UpdateType:     "UPDATE",
+	}
+}
+
+func newSettingView(s *model.Setting) *settingView {
+	return &settingView{Value: s.Value, AllowOverrides: s.AllowOverrides}
+}
+
+// targetSettings returns every setting of the target project, by identifier
+func (c SettingContext) targetSettings() (map[string]*model.Setting, error) {
+	settings, err := listAllSettings(c.target.asSource(), c.targetOrg, c.targetProject)
+	if err != nil {
+		return nil, err
+	}
+	byId := map[string]*model.Setting{}
+	for _, s := range settings {
+		byId[s.Identifier] = s
+	}
+	return byId, nil
+}
+
+// listSettings returns the settings with a value set in the project, the
+// inherited ones are not copied
+func listSettings(s *SourceRequest, org, project string) ([]*model.Setting, error) {
+	all, err := listAllSettings(s, org, project)
+	if err != nil {
+		return nil, err
+	}
+	settings := []*model.Setting{}
+	for _, setting := range all {
+		if setting.SettingSource == settingSourceProject && setting.IsSettingEditable {
+			settings = append(settings, setting)
+		}
+	}
+	return settings, nil
+}
+
+func listAllSettings(s *SourceRequest, org, project string) ([]*model.Setting, error) {
+
+	resp, err := s.Client.R().
+		SetHeader("x-api-key", s.Token).
+		SetHeader("Content-Type", "application/json").
+		SetQueryParams(map[string]string{
+			"accountIdentifier": s.Account,
+			"orgIdentifier":     org,
+			"projectIdentifier": project,
+		}).
+		Get(s.Url + "/ng/api/settings")
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, handleErrorResponse(resp)
+	}
+
+	result := model.ListSettingResponse{}
+	err = json.Unmarshal(resp.Body(), &result)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := []*model.Setting{}
+	for _, item := range result.Data {
+		if item.Setting != nil {
+			settings = append(settings, item.Setting)
+		}
+	}
+	return settings, nil
+}
+
+// updateSetting sets the value in the target project. The API answers every
+// update with its own status.
+func (c SettingContext) updateSetting(req *model.UpdateSettingRequest) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody([]*model.UpdateSettingRequest{req}).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+			"orgIdentifier":     c.targetOrg,
+			"projectIdentifier": c.targetProject,
+		}).
+		Put(api.Url + "/ng/api/settings")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleErrorResponse(resp)
+	}
+
+	result := model.UpdateSettingResponse{}
+	if err := json.Unmarshal(resp.Body(), &result); err != nil {
+		return err
+	}
+	for _, r := range result.Data {
+		if !r.UpdateStatus {
+			return errors.New(r.ErrorMessage)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSettingToTarget_RenamesGitConnector(t *testing.T) {
+	c := SettingContext{mapping: IdentifierMapping{EntityConnector: {Prefix: "legacy_"}}}
+	connector, limit := "github", "10"
+
+	git := c.toTarget(&model.Setting{Identifier: "default_connector_for_git_experience", Value: &connector, AllowOverrides: true})
+	other := c.toTarget(&model.Setting{Identifier: "concurrent_active_pipeline_executions", Value: &limit})
+
+	assert.Equal(t, "legacy_github", *git.Value)
+	assert.True(t, git.AllowOverrides)
+	assert.Equal(t, "10", *other.Value)
+	assert.Equal(t, "github", connector)
+}
+
+func TestTypeLevels_SettingsBeforeTemplates(t *testing.T) {
+	g := NewDependencyGraph()
+	g.Add(EntitySetting, projectSettingsNode, "Project Settings", nil)
+	g.Add(EntityTemplate, "stage", "stage", []EntityRef{projectSettingsRef})
+	g.Add(EntityPipeline, "deploy", "deploy", []EntityRef{projectSettingsRef})
+
+	levels, err := g.TypeLevels([]EntityType{EntityTemplate, EntitySetting, EntityPipeline})
+
+	assert.NoError(t, err)
+	assert.Equal(t, [][]EntityType{{EntitySetting}, {EntityTemplate, EntityPipeline}}, levels)
+}
@@ -0,0 +1,362 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/schollz/progressbar/v3"
+)
+
+type SLOContext struct {
+	source        *SourceRequest
+	target        *TargetRequest
+	sourceOrg     string
+	sourceProject string
+	targetOrg     string
+	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
+}
+
+func NewSLOOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) SLOContext {
+	return SLOContext{
+		source:        sourceApi,
+		target:        targetApi,
+		sourceOrg:     st.SourceOrg,
+		sourceProject: st.SourceProject,
+		targetOrg:     st.TargetOrg,
+		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
+	}
+}
+
+func (c SLOContext) Move() error {
+
+	slos, err := listSLOs(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	// THE SLOS USE THE USER JOURNEYS OF THE PROJECT
+	if err := c.copyUserJourneys(); err != nil {
+		return err
+	}
+
+	bar := progressbar.Default(int64(len(slos)), "SLOs")
+	var failed []string
+
+	for _, slo := range slos {
+		if !c.filter.Match(EntitySLO, slo.SloIdentifier) {
+			bar.Add(1)
+			continue
+		}
+		result := outcome{status: StatusFailed}
+		entity, err := c.getSLO(slo.SloIdentifier)
+		if err == nil {
+			if entity, err = c.toTarget(entity); err == nil {
+				targetId := c.mapping.Identifier(EntitySLO, slo.SloIdentifier)
+				result, err = c.state.upsert(c.onConflict, EntitySLO, slo.SloIdentifier, entity,
+					func() error { return c.createSLO(entity) },
+					func() error { return c.updateSLO(targetId, entity) },
+					c.target.compareWith(EntitySLO, targetId, c.targetOrg, c.targetProject, srmParams(c.target.Account), entity),
+				)
+			}
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintln(slo.Name, "-", err.Error()))
+		}
+		c.report.recordOutcome(EntitySLO, slo.SloIdentifier, slo.Name, result, err)
+		bar.Add(1)
+	}
+	bar.Finish()
+
+	reportFailed(failed, "SLOs:")
+	return nil
+}
+
+func (c SLOContext) Scan(g *DependencyGraph) error {
+
+	slos, err := listSLOs(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, slo := range slos {
+		if !c.filter.Match(EntitySLO, slo.SloIdentifier) {
+			continue
+		}
+		entity, err := c.getSLO(slo.SloIdentifier)
+		if err != nil {
+			g.AddUnscanned(EntitySLO, slo.SloIdentifier, slo.Name, err)
+			continue
+		}
+		refs, err := extractEntityReferences(entity)
+		if err != nil {
+			g.AddUnscanned(EntitySLO, slo.SloIdentifier, slo.Name, err)
+			continue
+		}
+		g.Add(EntitySLO, slo.SloIdentifier, slo.Name, refs)
+	}
+	return nil
+}
+
+func (c SLOContext) Verify(v *Verification) error {
+
+	slos, err := listSLOs(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	targetSLOs, err := listSLOs(c.target.asSource(), c.targetOrg, c.targetProject)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, slo := range slos {
+		if !c.filter.Match(EntitySLO, slo.SloIdentifier) {
+			continue
+		}
+		count++
+		entity, err := c.getSLO(slo.SloIdentifier)
+		if err == nil {
+			entity, err = c.toTarget(entity)
+		}
+		if err != nil {
+			v.failed(EntitySLO, slo.SloIdentifier, slo.Name, err)
+			continue
+		}
+		targetId := c.mapping.Identifier(EntitySLO, slo.SloIdentifier)
+		v.check(EntitySLO, slo.SloIdentifier, slo.Name, c.target.verifyWith(EntitySLO, targetId, c.targetOrg, c.targetProject, srmParams(c.target.Account), entity, false))
+	}
+	v.count(EntitySLO, count, len(targetSLOs))
+	return nil
+}
+
+// toTarget renames the SLO and its monitored service. The composite SLOs
+// point at SLOs of the same project by scope, which moves to the target.
+func (c SLOContext) toTarget(entity map[string]interface{}) (map[string]interface{}, error) {
+	out, err := c.mapping.rewriteEntity(entity)
+	if err != nil {
+		return nil, err
+	}
+	if id, ok := out["identifier"].(string); ok {
+		out["identifier"] = c.mapping.Identifier(EntitySLO, id)
+	}
+	out["orgIdentifier"] = c.targetOrg
+	out["projectIdentifier"] = c.targetProject
+	delete(out, "notificationRuleRefs")
+
+	walkEntity(out, func(m map[string]interface{}) {
+		if _, found := m["serviceLevelObjectiveRef"]; !found {
+			return
+		}
+		if m["orgIdentifier"] == c.sourceOrg && m["projectIdentifier"] == c.sourceProject {
+			m["orgIdentifier"] = c.targetOrg
+			m["projectIdentifier"] = c.targetProject
+			if _, found := m["accountId"]; found {
+				m["accountId"] = c.target.Account
+			}
+		}
+	})
+	return out, nil
+}
+
+// walkEntity calls fn for every object of an entity decoded from JSON
+func walkEntity(v interface{}, fn func(m map[string]interface{})) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		fn(value)
+		for _, item := range value {
+			walkEntity(item, fn)
+		}
+	case []interface{}:
+		for _, item := range value {
+			walkEntity(item, fn)
+		}
+	}
+}
+
+func (c SLOContext) copyUserJourneys() error {
+	journeys, err := listUserJourneys(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return fmt.Errorf("listing user journeys: %w", err)
+	}
+	for _, j := range journeys {
+		if err := c.createUserJourney(j); err != nil {
+			return fmt.Errorf("user journey %s: %w", j.Identifier, err)
+		}
+	}
+	return nil
+}
+
+// listSLOs returns the SLOs of the project, the simple ones before the
+// composite ones using them
+func listSLOs(s *SourceRequest, org, project string) ([]*model.SLOListItem, error) {
+
+	resp, err := s.Client.R().
+		SetHeader("x-api-key", s.Token).
+		SetHeader("Content-Type", "application/json").
+		SetQueryParams(map[string]string{
+			"accountId":         s.Account,
+			"orgIdentifier":     org,
+			"projectIdentifier": project,
+			"offset":            "0",
+			"pageSize":          "1000",
+		}).
+		Get(s.Url + "/cv/api/slo/v2")
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, handleErrorResponse(resp)
+	}
+
+	result := model.ListSLOResponse{}
+	err = json.Unmarshal(resp.Body(), &result)
+	if err != nil {
+		return nil, err
+	}
+
+	slos := result.Data.Content
+	sort.SliceStable(slos, func(i, j int) bool {
+		return slos[i].SloType != "Composite" && slos[j].SloType == "Composite"
+	})
+	return slos, nil
+}
+
+func (c SLOContext) getSLO(identifier string) (map[string]interface{}, error) {
+
+	api := c.source
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetQueryParams(map[string]string{
+			"accountId":         api.Account,
+			"orgIdentifier":     c.sourceOrg,
+			"projectIdentifier": c.sourceProject,
+		}).
+		Get(api.Url + "/cv/api/slo/v2/identifier/" + identifier)
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, handleErrorResponse(resp)
+	}
+
+	result := model.GetSLOResponse{}
+	err = json.Unmarshal(resp.Body(), &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Resource.ServiceLevelObjectiveV2, nil
+}
+
+func listUserJourneys(s *SourceRequest, org, project string) ([]*model.UserJourney, error) {
+
+	resp, err := s.Client.R().
+		SetHeader("x-api-key", s.Token).
+		SetHeader("Content-Type", "application/json").
+		SetQueryParams(map[string]string{
+			"accountId":         s.Account,
+			"orgIdentifier":     org,
+			"projectIdentifier": project,
+			"offset":            "0",
+			"pageSize":          "1000",
+		}).
+		Get(s.Url + "/cv/api/user-journey")
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, handleErrorResponse(resp)
+	}
+
+	result := model.ListUserJourneyResponse{}
+	err = json.Unmarshal(resp.Body(), &result)
+	if err != nil {
+		return nil, err
+	}
+
+	journeys := []*model.UserJourney{}
+	for _, c := range result.Data.Content {
+		journeys = append(journeys, c.UserJourney)
+	}
+	return journeys, nil
+}
+
+// createUserJourney ignores the journeys already in the target
+func (c SLOContext) createUserJourney(journey *model.UserJourney) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(journey).
+		SetQueryParams(map[string]string{
+			"accountId":         api.Account,
+			"orgIdentifier":     c.targetOrg,
+			"projectIdentifier": c.targetProject,
+		}).
+		Post(api.Url + "/cv/api/user-journey/create")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleErrorResponse(resp)
+	}
+
+	return nil
+}
+
+func (c SLOContext) createSLO(entity map[string]interface{}) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(entity).
+		SetQueryParams(map[string]string{
+			"accountId":         api.Account,
+			"orgIdentifier":     c.targetOrg,
+			"projectIdentifier": c.targetProject,
+		}).
+		Post(api.Url + "/cv/api/slo/v2")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
+
+func (c SLOContext) updateSLO(identifier string, entity map[string]interface{}) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(entity).
+		SetQueryParams(map[string]string{
+			"accountId":         api.Account,
+			"orgIdentifier":     c.targetOrg,
+			"projectIdentifier": c.targetProject,
+		}).
+		Put(api.Url + "/cv/api/slo/v2/identifier/" + identifier)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleErrorResponse(resp)
+	}
+
+	return nil
+}
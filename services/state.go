@@ -0,0 +1,96 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// SyncState keeps the content hash of the entities written to each target
+// project by the previous runs, so the unchanged entities are not sent again.
+// It is safe for concurrent use and a nil state syncs every entity.
+type SyncState struct {
+	mu     sync.Mutex
+	target string
+	// Hashes maps "org/project" to the hash of every synced entity, by
+	// "type:source identifier"
+	Hashes map[string]map[string]string `json:"hashes"`
+}
+
+// LoadSyncState reads the state of the target project, a missing file starts
+// an empty state
+func LoadSyncState(path, targetOrg, targetProject string) (*SyncState, error) {
+	s := &SyncState{Hashes: map[string]map[string]string{}}
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(b, s); err != nil {
+			return nil, err
+		}
+	}
+	s.target = targetOrg + "/" + targetProject
+	if s.Hashes == nil {
+		s.Hashes = map[string]map[string]string{}
+	}
+	if s.Hashes[s.target] == nil {
+		s.Hashes[s.target] = map[string]string{}
+	}
+	return s, nil
+}
+
+// Write saves the state as JSON
+func (s *SyncState) Write(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// upsert skips the entity when the content written to the target didn't change
+// since the last successful sync. Otherwise it follows the policy, the new hash
+// is kept when the report records the entity as synced.
+func (s *SyncState) upsert(p ConflictPolicy, t EntityType, sourceId string, content interface{}, create, update func() error, compare func() (string, error)) (outcome, error) {
+	if s == nil {
+		return p.upsert(create, update, compare)
+	}
+	hash, err := contentHash(content)
+	if err != nil {
+		return p.upsert(create, update, compare)
+	}
+
+	key := string(t) + ":" + sourceId
+	s.mu.Lock()
+	unchanged := s.Hashes[s.target][key] == hash
+	s.mu.Unlock()
+	if unchanged {
+		return outcome{status: StatusUnchanged}, nil
+	}
+
+	result, err := p.upsert(create, update, compare)
+	result.commit = func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.Hashes[s.target][key] = hash
+	}
+	return result, err
+}
+
+// contentHash hashes the normalized entity, so formatting and empty values
+// don't change it
+func contentHash(content interface{}) (string, error) {
+	normalized, err := normalizeEntity(content)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncStateUpsert(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	state, err := LoadSyncState(path, "other", "DouradoF")
+	assert.NoError(t, err)
+
+	report := NewReport()
+	creates := 0
+	create := func() error {
+		creates++
+		return nil
+	}
+
+	result, err := state.upsert(ConflictSkip, EntityService, "nginx", "service:\n  identifier: nginx\n", create, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCreated, result.status)
+	report.recordOutcome(EntityService, "nginx", "nginx", result, err)
+	assert.NoError(t, state.Write(path))
+
+	// THE FORMATTING IS NOT PART OF THE HASH
+	state, err = LoadSyncState(path, "other", "DouradoF")
+	assert.NoError(t, err)
+	result, err = state.upsert(ConflictSkip, EntityService, "nginx", "service: {identifier: nginx}", create, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusUnchanged, result.status)
+	assert.Equal(t, 1, creates)
+
+	// OTHER TARGETS DON'T SHARE THE HASHES
+	other, err := LoadSyncState(path, "other", "payments")
+	assert.NoError(t, err)
+	result, _ = other.upsert(ConflictSkip, EntityService, "nginx", "service: {identifier: nginx}", create, nil, nil)
+	assert.Equal(t, StatusCreated, result.status)
+}
+
+func TestSyncStateKeepsFailedEntities(t *testing.T) {
+	state, err := LoadSyncState(filepath.Join(t.TempDir(), "state.json"), "other", "DouradoF")
+	assert.NoError(t, err)
+
+	fail := func() error { return errors.New("boom") }
+	result, err := state.upsert(ConflictSkip, EntityPipeline, "deploy", "pipeline: {identifier: deploy}", fail, nil, nil)
+	NewReport().recordOutcome(EntityPipeline, "deploy", "deploy", result, err)
+	assert.Error(t, err)
+
+	result, err = state.upsert(ConflictSkip, EntityPipeline, "deploy", "pipeline: {identifier: deploy}", func() error { return nil }, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCreated, result.status)
+}
+
+func TestSyncStateNil(t *testing.T) {
+	var state *SyncState
+	result, err := state.upsert(ConflictSkip, EntityRole, "viewer", "role: {}", func() error { return nil }, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCreated, result.status)
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Pruner is implemented by the operations deleting the target entities whose
+// source entity was deleted after they were synced
+type Pruner interface {
+	Prune() error
+}
+
+// unmodified is true when the source entity was last modified before the
+// given time, in epoch milliseconds. Without a time every entity is modified.
+func unmodified(since, modifiedAt int64) bool {
+	return since > 0 && modifiedAt > 0 && modifiedAt < since
+}
+
+// recordUnmodified reports the entity skipped because it was not modified
+// since the previous sync
+func (r *Report) recordUnmodified(t EntityType, identifier, name string) {
+	r.recordOutcome(t, identifier, name, outcome{status: StatusUnchanged, message: "not modified"}, nil)
+}
+
+// synced returns the source identifiers of the type synced to the target,
+// sorted
+func (s *SyncState) synced(t EntityType) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []string
+	prefix := string(t) + ":"
+	for key := range s.Hashes[s.target] {
+		if strings.HasPrefix(key, prefix) {
+			ids = append(ids, strings.TrimPrefix(key, prefix))
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (s *SyncState) forget(t EntityType, sourceId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.Hashes[s.target], string(t)+":"+sourceId)
+}
+
+// prune deletes from the target the entities synced from a source entity that
+// is not listed anymore. Only the entities kept in the state are deleted, the
+// ones created in the target by other means are never touched. The remove
+// function gets the source identifier kept in the state.
+func (s *SyncState) prune(t EntityType, sourceIds []string, report *Report, remove func(sourceId string) error) {
+	if s == nil {
+		return
+	}
+	listed := map[string]bool{}
+	for _, id := range sourceIds {
+		listed[id] = true
+	}
+	var failed []string
+	for _, id := range s.synced(t) {
+		if listed[id] {
+			continue
+		}
+		err := remove(id)
+		if err == nil {
+			s.forget(t, id)
+			report.recordOutcome(t, id, "", outcome{status: StatusDeleted}, nil)
+			continue
+		}
+		failed = append(failed, fmt.Sprintln(id, "-", err.Error()))
+		report.recordOutcome(t, id, "", outcome{status: StatusFailed}, err)
+	}
+	reportFailed(failed, string(t)+" deletions:")
+}
+
+// deleteSynced deletes the target entity of a synced project entity
+func (t *TargetRequest) deleteSynced(mapping IdentifierMapping, entity EntityType, sourceId, org, project string) error {
+	return t.deleteEntity(mapping.Ref(EntityRef{Type: entity, Scope: ScopeProject, Identifier: sourceId}), org, project, nil)
+}
+
+// deleteEntity deletes the entity from the target, the entities already
+// missing are ignored. The params are added to the query.
+func (t *TargetRequest) deleteEntity(ref EntityRef, org, project string, params map[string]string) error {
+	endpoint, found := existsEndpoints[ref.Type]
+	if !found {
+		return fmt.Errorf("no endpoint for %s", ref.Type)
+	}
+
+	query := map[string]string{
+		"accountIdentifier": t.Account,
+	}
+	if ref.Scope != ScopeAccount {
+		query["orgIdentifier"] = org
+	}
+	if ref.Scope == ScopeProject {
+		query["projectIdentifier"] = project
+	}
+	for k, v := range params {
+		query[k] = v
+	}
+
+	identifier := ref.Identifier
+	if ref.Type == EntityInfrastructure {
+		env, infra, _ := strings.Cut(ref.Identifier, "/")
+		query["environmentIdentifier"] = env
+		identifier = infra
+	}
+
+	resp, err := t.Client.R().
+		SetHeader("x-api-key", t.Token).
+		SetHeader("Content-Type", "application/json").
+		SetPathParam("identifier", identifier).
+		SetQueryParams(query).
+		Delete(t.Url + endpoint)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() == http.StatusNotFound {
+		return nil
+	}
+	if resp.IsError() {
+		if err := handleErrorResponse(resp); err != nil && !errors.Is(err, ErrEntityNotFound) {
+			return err
+		}
+	}
+	return nil
+}
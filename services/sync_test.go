@@ -0,0 +1,64 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmodified(t *testing.T) {
+	assert.False(t, unmodified(0, 1000))
+	assert.False(t, unmodified(1000, 0))
+	assert.False(t, unmodified(1000, 1000))
+	assert.True(t, unmodified(1000, 999))
+}
+
+func TestSyncStatePrune(t *testing.T) {
+	state, err := LoadSyncState(filepath.Join(t.TempDir(), "state.json"), "other", "DouradoF")
+	assert.NoError(t, err)
+	state.Hashes["other/DouradoF"] = map[string]string{
+		"pipeline:deploy":  "a",
+		"pipeline:release": "b",
+		"pipeline:broken":  "c",
+		"service:nginx":    "d",
+	}
+
+	mapping := IdentifierMapping{EntityPipeline: {Prefix: "new_"}}
+	report := NewReport()
+	var deleted []string
+	state.prune(EntityPipeline, []string{"deploy", "manual"}, report, func(sourceId string) error {
+		targetId := mapping.Identifier(EntityPipeline, sourceId)
+		if targetId == "new_broken" {
+			return errors.New("boom")
+		}
+		deleted = append(deleted, targetId)
+		return nil
+	})
+
+	// ONLY THE SYNCED ENTITIES MISSING IN THE SOURCE ARE DELETED
+	assert.Equal(t, []string{"new_release"}, deleted)
+	assert.Equal(t, []string{"broken", "deploy"}, state.synced(EntityPipeline))
+	assert.Equal(t, []string{"nginx"}, state.synced(EntityService))
+	assert.Equal(t, map[ReportStatus]int{StatusDeleted: 1, StatusFailed: 1}, report.Summary()[EntityPipeline])
+}
+
+func TestDeleteSynced_Infrastructure(t *testing.T) {
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		deleted = append(deleted, r.URL.Path+"?env="+r.URL.Query().Get("environmentIdentifier")+"&project="+r.URL.Query().Get("projectIdentifier"))
+		w.Write([]byte(`{"status":"SUCCESS"}`))
+	}))
+	defer server.Close()
+
+	target := &TargetRequest{Client: resty.New(), Url: server.URL}
+	mapping := IdentifierMapping{EntityEnvironment: {Prefix: "new_"}}
+
+	assert.NoError(t, target.deleteSynced(mapping, EntityInfrastructure, "prod/k8s", "default", "DouradoF"))
+	assert.Equal(t, []string{"/ng/api/infrastructures/k8s?env=new_prod&project=DouradoF"}, deleted)
+}
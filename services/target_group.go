@@ -0,0 +1,385 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/schollz/progressbar/v3"
+)
+
+// ffTargetsNode identifies the targets of an environment in the dependency
+// graph, as "<env>/Targets"
+const ffTargetsNode = "Targets"
+
+// TargetGroupContext copies the Feature Flags targets and target groups of the
+// environments copied by EnvironmentContext
+type TargetGroupContext struct {
+	source        *SourceRequest
+	target        *TargetRequest
+	sourceOrg     string
+	sourceProject string
+	targetOrg     string
+	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
+}
+
+func NewTargetGroupOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) TargetGroupContext {
+	return TargetGroupContext{
+		source:        sourceApi,
+		target:        targetApi,
+		sourceOrg:     st.SourceOrg,
+		sourceProject: st.SourceProject,
+		targetOrg:     st.TargetOrg,
+		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
+	}
+}
+
+// segmentView is the part of a target group compared with the target
+type segmentView struct {
+	Identifier string          `json:"identifier"`
+	Name       string          `json:"name"`
+	Included   []*targetRef    `json:"included,omitempty"`
+	Excluded   []*targetRef    `json:"excluded,omitempty"`
+	Rules      []*model.Clause `json:"rules,omitempty"`
+}
+
+type targetRef struct {
+	Identifier string `json:"identifier"`
+}
+
+func (c TargetGroupContext) Move() error {
+
+	envs, err := ffEnvironments(c.source, c.sourceOrg, c.sourceProject, c.filter)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, env := range envs {
+		targetEnv := c.mapping.Identifier(EntityEnvironment, env)
+
+		// THE TARGET GROUPS INCLUDE THE TARGETS BY IDENTIFIER
+		if err := c.copyTargets(env, targetEnv); err != nil {
+			failed = append(failed, fmt.Sprintln(env, "targets -", err.Error()))
+			continue
+		}
+
+		segments, err := listSegments(c.source, c.sourceOrg, c.sourceProject, env)
+		if err != nil {
+			failed = append(failed, fmt.Sprintln(env, "target groups -", err.Error()))
+			continue
+		}
+
+		bar := progressbar.Default(int64(len(segments)), "Target Groups "+env)
+		for _, s := range segments {
+			if !c.filter.Match(EntityTargetGroup, s.Identifier) {
+				bar.Add(1)
+				continue
+			}
+			req := c.toTarget(s, targetEnv)
+			targetId := targetEnv + "/" + req.Identifier
+			result, err := c.state.upsert(c.onConflict, EntityTargetGroup, env+"/"+s.Identifier, req,
+				func() error { return c.createSegment(req) },
+				nil,
+				c.target.compareWith(EntityTargetGroup, targetId, c.targetOrg, c.targetProject, nil, newSegmentView(req)),
+			)
+			if err != nil {
+				failed = append(failed, fmt.Sprintln(s.Name, "-", err.Error()))
+			}
+			c.report.recordOutcome(EntityTargetGroup, env+"/"+s.Identifier, s.Name, result, err)
+			bar.Add(1)
+		}
+		bar.Finish()
+	}
+
+	reportFailed(failed, "target groups:")
+	return nil
+}
+
+func (c TargetGroupContext) Scan(g *DependencyGraph) error {
+
+	envs, err := ffEnvironments(c.source, c.sourceOrg, c.sourceProject, c.filter)
+	if err != nil {
+		return err
+	}
+	for _, env := range envs {
+		envRef := EntityRef{Type: EntityEnvironment, Scope: ScopeProject, Identifier: env}
+		g.Add(EntityTargetGroup, env+"/"+ffTargetsNode, "Targets "+env, []EntityRef{envRef})
+
+		segments, err := listSegments(c.source, c.sourceOrg, c.sourceProject, env)
+		if err != nil {
+			return fmt.Errorf("target groups of %s: %w", env, err)
+		}
+		for _, s := range segments {
+			if !c.filter.Match(EntityTargetGroup, s.Identifier) {
+				continue
+			}
+			refs := []EntityRef{envRef, {Type: EntityTargetGroup, Scope: ScopeProject, Identifier: env + "/" + ffTargetsNode}}
+			refs = append(refs, clauseSegmentRefs(env, s.Rules)...)
+			g.Add(EntityTargetGroup, env+"/"+s.Identifier, s.Name, refs)
+		}
+	}
+	return nil
+}
+
+func (c TargetGroupContext) Verify(v *Verification) error {
+
+	envs, err := ffEnvironments(c.source, c.sourceOrg, c.sourceProject, c.filter)
+	if err != nil {
+		return err
+	}
+
+	count, targetCount := 0, 0
+	for _, env := range envs {
+		targetEnv := c.mapping.Identifier(EntityEnvironment, env)
+		segments, err := listSegments(c.source, c.sourceOrg, c.sourceProject, env)
+		if err != nil {
+			return err
+		}
+		targetSegments, err := listSegments(c.target.asSource(), c.targetOrg, c.targetProject, targetEnv)
+		if err != nil {
+			return err
+		}
+		targetCount += len(targetSegments)
+
+		for _, s := range segments {
+			if !c.filter.Match(EntityTargetGroup, s.Identifier) {
+				continue
+			}
+			count++
+			req := c.toTarget(s, targetEnv)
+			targetId := targetEnv + "/" + req.Identifier
+			v.check(EntityTargetGroup, env+"/"+s.Identifier, s.Name, c.target.verifyWith(EntityTargetGroup, targetId, c.targetOrg, c.targetProject, nil, newSegmentView(req), false))
+		}
+	}
+	v.count(EntityTargetGroup, count, targetCount)
+	return nil
+}
+
+// toTarget renames the target group and the groups its rules match
+func (c TargetGroupContext) toTarget(s *model.Segment, targetEnv string) *model.CreateSegmentRequest {
+	req := &model.CreateSegmentRequest{
+		Identifier:  c.mapping.Identifier(EntityTargetGroup, s.Identifier),
+		Name:        s.Name,
+		Environment: targetEnv,
+		Project:     c.targetProject,
+		Rules:       mapClauses(c.mapping, s.Rules),
+	}
+	for _, t := range s.Included {
+		req.Included = append(req.Included, t.Identifier)
+	}
+	for _, t := range s.Excluded {
+		req.Excluded = append(req.Excluded, t.Identifier)
+	}
+	return req
+}
+
+func newSegmentView(req *model.CreateSegmentRequest) *segmentView {
+	view := &segmentView{Identifier: req.Identifier, Name: req.Name, Rules: req.Rules}
+	for _, id := range req.Included {
+		view.Included = append(view.Included, &targetRef{Identifier: id})
+	}
+	for _, id := range req.Excluded {
+		view.Excluded = append(view.Excluded, &targetRef{Identifier: id})
+	}
+	return view
+}
+
+// mapClauses returns a copy of the clauses with the target groups of the
+// segmentMatch clauses renamed
+func mapClauses(mapping IdentifierMapping, clauses []*model.Clause) []*model.Clause {
+	var out []*model.Clause
+	for _, cl := range clauses {
+		mapped := *cl
+		if cl.Op == "segmentMatch" {
+			mapped.Values = make([]string, len(cl.Values))
+			for i, id := range cl.Values {
+				mapped.Values[i] = mapping.Identifier(EntityTargetGroup, id)
+			}
+		}
+		out = append(out, &mapped)
+	}
+	return out
+}
+
+// clauseSegmentRefs returns the target groups matched by the clauses
+func clauseSegmentRefs(env string, clauses []*model.Clause) []EntityRef {
+	var refs []EntityRef
+	for _, cl := range clauses {
+		if cl.Op != "segmentMatch" {
+			continue
+		}
+		for _, id := range cl.Values {
+			refs = append(refs, EntityRef{Type: EntityTargetGroup, Scope: ScopeProject, Identifier: env + "/" + id})
+		}
+	}
+	return refs
+}
+
+// ffEnvironments returns the identifiers of the source environments copied
+// by EnvironmentContext
+func ffEnvironments(s *SourceRequest, org, project string, filter EntityFilter) ([]string, error) {
+	envs, err := s.listEnvironments(org, project)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range envs {
+		if filter.Match(EntityEnvironment, e.Environment.Identifier) {
+			ids = append(ids, e.Environment.Identifier)
+		}
+	}
+	return ids, nil
+}
+
+// ffParams are the query parameters of the Feature Flags admin API. The
+// environment is omitted when empty.
+func ffParams(account, org, project, env string) map[string]string {
+	params := map[string]string{
+		"accountIdentifier": account,
+		"orgIdentifier":     org,
+		"projectIdentifier": project,
+	}
+	if len(env) > 0 {
+		params["environmentIdentifier"] = env
+	}
+	return params
+}
+
+// copyTargets creates the targets of the environment, the ones already in
+// the target are kept
+func (c TargetGroupContext) copyTargets(env, targetEnv string) error {
+	targets, err := listTargets(c.source, c.sourceOrg, c.sourceProject, env)
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		t.Account = c.target.Account
+		t.Org = c.targetOrg
+		t.Project = c.targetProject
+		t.Environment = targetEnv
+		if err := createTarget(c.target, t); err != nil {
+			return fmt.Errorf("target %s: %w", t.Identifier, err)
+		}
+	}
+	return nil
+}
+
+func listSegments(s *SourceRequest, org, project, env string) ([]*model.Segment, error) {
+
+	segments := []*model.Segment{}
+	for page := 0; ; page++ {
+		params := ffParams(s.Account, org, project, env)
+		params["pageNumber"] = strconv.Itoa(page)
+		params["pageSize"] = "100"
+
+		resp, err := s.Client.R().
+			SetHeader("x-api-key", s.Token).
+			SetHeader("Content-Type", "application/json").
+			SetQueryParams(params).
+			Get(s.Url + "/cf/admin/segments")
+		if err != nil {
+			return nil, err
+		}
+		if resp.IsError() {
+			return nil, handleErrorResponse(resp)
+		}
+
+		result := model.ListSegmentResponse{}
+		err = json.Unmarshal(resp.Body(), &result)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, result.Segments...)
+		if int64(page+1) >= result.PageCount {
+			return segments, nil
+		}
+	}
+}
+
+func listTargets(s *SourceRequest, org, project, env string) ([]*model.Target, error) {
+
+	targets := []*model.Target{}
+	for page := 0; ; page++ {
+		params := ffParams(s.Account, org, project, env)
+		params["pageNumber"] = strconv.Itoa(page)
+		params["pageSize"] = "100"
+
+		resp, err := s.Client.R().
+			SetHeader("x-api-key", s.Token).
+			SetHeader("Content-Type", "application/json").
+			SetQueryParams(params).
+			Get(s.Url + "/cf/admin/targets")
+		if err != nil {
+			return nil, err
+		}
+		if resp.IsError() {
+			return nil, handleErrorResponse(resp)
+		}
+
+		result := model.ListTargetResponse{}
+		err = json.Unmarshal(resp.Body(), &result)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, result.Targets...)
+		if int64(page+1) >= result.PageCount {
+			return targets, nil
+		}
+	}
+}
+
+// createTarget ignores the targets already in the environment
+func createTarget(t *TargetRequest, target *model.Target) error {
+
+	resp, err := t.Client.R().
+		SetHeader("x-api-key", t.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(target).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": t.Account,
+			"orgIdentifier":     target.Org,
+		}).
+		Post(t.Url + "/cf/admin/targets")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleErrorResponse(resp)
+	}
+
+	return nil
+}
+
+func (c TargetGroupContext) createSegment(req *model.CreateSegmentRequest) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(req).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+			"orgIdentifier":     c.targetOrg,
+		}).
+		Post(api.Url + "/cf/admin/segments")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
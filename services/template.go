@@ -2,7 +2,10 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 
 	"github.com/Fernando-Dourado/harness-move-project/model"
 	"github.com/schollz/progressbar/v3"
@@ -11,6 +14,8 @@ import (
 const LIST_TEMPLATES_ENDPOINT = "/v1/orgs/{org}/projects/{project}/templates"
 const GET_TEMPLATE_ENDPOINT = "/template/api/templates/{templateIdentifier}"
 const CREATE_TEMPLATE_ENDPOINT = "/template/api/templates"
+const UPDATE_TEMPLATE_ENDPOINT = "/template/api/templates/update/{templateIdentifier}/{versionLabel}"
+const DELETE_TEMPLATE_ENDPOINT = "/template/api/templates/{templateIdentifier}/{versionLabel}"
 
 type TemplateContext struct {
 	source        *SourceRequest
@@ -19,6 +24,11 @@ type TemplateContext struct {
 	sourceProject string
 	targetOrg     string
 	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
 }
 
 func NewTemplateOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) TemplateContext {
@@ -29,6 +39,11 @@ func NewTemplateOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st
 		sourceProject: st.SourceProject,
 		targetOrg:     st.TargetOrg,
 		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
 	}
 }
 
@@ -42,23 +57,241 @@ func (c TemplateContext) Move() error {
 	bar := progressbar.Default(int64(len(templates)), "Templates   ")
 	var failed []string
 
+	// FETCH EVERY VERSION FIRST, SO TEMPLATES USED BY OTHER TEMPLATES ARE CREATED BEFORE THEM
+	g := NewDependencyGraph()
+	versions := map[string][]*model.TemplateGetData{}
 	for _, template := range templates {
+		if !c.filter.Match(EntityTemplate, template.Identifier) {
+			bar.Add(1)
+			continue
+		}
+		if reused, found := c.mapping.Reused(EntityTemplate, template.Identifier); found {
+			c.report.recordReused(EntityTemplate, template.Identifier, template.Name, reused)
+			bar.Add(1)
+			continue
+		}
 		t, err := c.getTemplate(c.sourceOrg, c.sourceProject, template.Identifier, template.VersionLabel)
 		if err == nil {
-			newYaml := createYaml(t.Yaml, c.sourceOrg, c.sourceProject, c.targetOrg, c.targetProject)
-			err = c.createTemplate(c.targetOrg, c.targetProject, newYaml)
-		}
-		if err != nil {
-			failed = append(failed, fmt.Sprintln(template.Name, "-", err.Error()))
+			var refs []EntityRef
+			if refs, err = extractReferences(t.Yaml); err == nil {
+				g.Add(EntityTemplate, template.Identifier, template.Name, refs)
+				versions[template.Identifier] = append(versions[template.Identifier], t)
+				continue
+			}
 		}
+		failed = append(failed, fmt.Sprintln(template.Name, "-", err.Error()))
+		c.report.record(EntityTemplate, template.Identifier, template.Name, err)
 		bar.Add(1)
 	}
+
+	order, err := g.EntityOrder(EntityTemplate)
+	if err != nil {
+		failed = append(failed, fmt.Sprintln("Unable to sort templates -", err.Error()))
+	}
+
+	for _, id := range order {
+		for _, t := range versions[id] {
+			result := outcome{status: StatusFailed}
+			ref, org, project := c.targetRef(t.Identifier)
+			newYaml, err := c.templateYaml(t.Yaml, ref, org, project)
+			if err == nil {
+				result, err = c.state.upsert(c.onConflict, EntityTemplate, t.Identifier+"/"+t.VersionLabel, newYaml,
+					func() error { return c.createTemplate(org, project, newYaml) },
+					func() error {
+						return c.updateTemplate(org, project, ref.Identifier, t.VersionLabel, newYaml)
+					},
+					c.target.compareRef(ref, c.targetOrg, c.targetProject, map[string]string{
+						"versionLabel": t.VersionLabel,
+					}, newYaml),
+				)
+			}
+			if err != nil {
+				failed = append(failed, fmt.Sprintln(t.Identifier, t.VersionLabel, "-", err.Error()))
+			}
+			c.report.recordOutcome(EntityTemplate, t.Identifier, t.Identifier+" ("+t.VersionLabel+")", result, err)
+			bar.Add(1)
+		}
+	}
 	bar.Finish()
 
 	reportFailed(failed, "templates:")
 	return nil
 }
 
+func (c TemplateContext) Scan(g *DependencyGraph) error {
+
+	templates, err := listTemplates(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, template := range templates {
+		if !c.filter.Match(EntityTemplate, template.Identifier) || c.mapping.reused(EntityTemplate, template.Identifier) {
+			continue
+		}
+		t, err := c.getTemplate(c.sourceOrg, c.sourceProject, template.Identifier, template.VersionLabel)
+		if err != nil {
+			g.AddUnscanned(EntityTemplate, template.Identifier, template.Name, err)
+			continue
+		}
+		refs, err := extractReferences(t.Yaml)
+		if err != nil {
+			g.AddUnscanned(EntityTemplate, template.Identifier, template.Name, err)
+			continue
+		}
+		g.Add(EntityTemplate, template.Identifier, template.Name, append(refs, projectSettingsRef))
+	}
+	return nil
+}
+
+func (c TemplateContext) Verify(v *Verification) error {
+
+	templates, err := listTemplates(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	targetTemplates, err := listTemplates(c.target.asSource(), c.targetOrg, c.targetProject)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, template := range templates {
+		if !c.filter.Match(EntityTemplate, template.Identifier) || c.mapping.reused(EntityTemplate, template.Identifier) {
+			continue
+		}
+		ref, org, project := c.targetRef(template.Identifier)
+		// THE PROMOTED TEMPLATES ARE NOT LISTED IN THE TARGET PROJECT
+		if ref.Scope == ScopeProject {
+			count++
+		}
+		name := template.Identifier + " (" + template.VersionLabel + ")"
+		t, err := c.getTemplate(c.sourceOrg, c.sourceProject, template.Identifier, template.VersionLabel)
+		if err != nil {
+			v.failed(EntityTemplate, template.Identifier, name, err)
+			continue
+		}
+		newYaml, err := createYaml(t.Yaml, org, project, c.mapping.transform(EntityTemplate))
+		if err != nil {
+			v.failed(EntityTemplate, template.Identifier, name, err)
+			continue
+		}
+		v.check(EntityTemplate, template.Identifier, name, c.target.verifyRef(ref, c.targetOrg, c.targetProject, map[string]string{
+			"versionLabel": template.VersionLabel,
+		}, newYaml, false))
+	}
+	v.count(EntityTemplate, count, len(targetTemplates))
+	return nil
+}
+
+// Export describes every version, references to the template point at its
+// stable version
+func (c TemplateContext) Export(tf *TerraformExport) error {
+
+	templates, err := listTemplates(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, template := range templates {
+		if !c.filter.Match(EntityTemplate, template.Identifier) || c.mapping.reused(EntityTemplate, template.Identifier) {
+			continue
+		}
+		if scope := c.mapping.Scope(EntityTemplate, template.Identifier); scope != ScopeProject {
+			tf.skip(EntityTemplate, template.Name+" "+template.VersionLabel, fmt.Sprintf("promoted to the %s scope", scope))
+			continue
+		}
+		t, err := c.getTemplate(c.sourceOrg, c.sourceProject, template.Identifier, template.VersionLabel)
+		if err != nil {
+			return fmt.Errorf("template %s %s: %w", template.Name, template.VersionLabel, err)
+		}
+		newYaml, err := createYaml(t.Yaml, c.targetOrg, c.targetProject, c.mapping.transform(EntityTemplate))
+		if err != nil {
+			return fmt.Errorf("template %s %s: %w", template.Name, template.VersionLabel, err)
+		}
+		var description string
+		if template.Description != nil {
+			description = *template.Description
+		}
+		id := c.mapping.Identifier(EntityTemplate, template.Identifier)
+		// ONLY THE STABLE VERSION IS REGISTERED, THE OTHERS DON'T OWN THE IDENTIFIER
+		targetId := ""
+		if template.StableTemplate {
+			targetId = id
+		}
+		tf.add(EntityTemplate, targetId, "harness_platform_template", id+"_"+template.VersionLabel, tf.importId(id, template.VersionLabel), tf.entity(id, template.Name,
+			tfString("description", description),
+			tfString("version", template.VersionLabel),
+			tfBool("is_stable", template.StableTemplate),
+			tfYamlDoc("template_yaml", newYaml),
+		)...)
+	}
+	return nil
+}
+
+// targetRef returns the template in the target, with the org and project of
+// its scope. They are empty above the scope of the promoted templates.
+func (c TemplateContext) targetRef(identifier string) (EntityRef, string, string) {
+	ref := c.mapping.Ref(EntityRef{Type: EntityTemplate, Scope: ScopeProject, Identifier: identifier})
+	switch ref.Scope {
+	case ScopeAccount:
+		return ref, "", ""
+	case ScopeOrg:
+		return ref, c.targetOrg, ""
+	}
+	return ref, c.targetOrg, c.targetProject
+}
+
+// templateYaml maps the template to its target scope. A promoted template
+// can't use the project entities, they are not visible from its scope.
+func (c TemplateContext) templateYaml(doc string, ref EntityRef, org, project string) (string, error) {
+	newYaml, err := createYaml(doc, org, project, c.mapping.transform(EntityTemplate))
+	if err != nil || ref.Scope == ScopeProject {
+		return newYaml, err
+	}
+	refs, err := extractReferences(newYaml)
+	if err != nil {
+		return "", err
+	}
+	for _, r := range refs {
+		if r.Scope == ScopeProject || (ref.Scope == ScopeAccount && r.Scope == ScopeOrg) {
+			return "", fmt.Errorf("promoted to the %s scope, but uses %s", ref.Scope, r)
+		}
+	}
+	return newYaml, nil
+}
+
+// scopeParams omits the org and project above the scope of the entity
+func scopeParams(account, org, project string) map[string]string {
+	params := map[string]string{
+		"accountIdentifier": account,
+	}
+	if len(org) > 0 {
+		params["orgIdentifier"] = org
+	}
+	if len(project) > 0 {
+		params["projectIdentifier"] = project
+	}
+	return params
+}
+
+// Prune deletes the synced template versions missing in the source
+func (c TemplateContext) Prune() error {
+
+	templates, err := listTemplates(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for _, template := range templates {
+		ids = append(ids, template.Identifier+"/"+template.VersionLabel)
+	}
+	c.state.prune(EntityTemplate, ids, c.report, func(sourceId string) error {
+		identifier, versionLabel, _ := strings.Cut(sourceId, "/")
+		ref, org, project := c.targetRef(identifier)
+		return c.deleteTemplate(org, project, ref.Identifier, versionLabel)
+	})
+	return nil
+}
+
 func listTemplates(s *SourceRequest, org, project string) (model.TemplateListResult, error) {
 
 	resp, err := s.Client.R().
@@ -125,18 +358,66 @@ func (c TemplateContext) createTemplate(org, project, yaml string) error {
 		SetHeader("x-api-key", api.Token).
 		SetHeader("Content-Type", "application/json").
 		SetBody(yaml).
-		SetQueryParams(map[string]string{
-			"accountIdentifier": api.Account,
-			"orgIdentifier":     org,
-			"projectIdentifier": project,
-		}).
+		SetQueryParams(scopeParams(api.Account, org, project)).
 		Post(api.Url + CREATE_TEMPLATE_ENDPOINT)
 	if err != nil {
 		return err
 	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
+
+func (c TemplateContext) updateTemplate(org, project, templateIdentifier, versionLabel, yaml string) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(yaml).
+		SetPathParams(map[string]string{
+			"templateIdentifier": templateIdentifier,
+			"versionLabel":       versionLabel,
+		}).
+		SetQueryParams(scopeParams(api.Account, org, project)).
+		Put(api.Url + UPDATE_TEMPLATE_ENDPOINT)
+	if err != nil {
+		return err
+	}
 	if resp.IsError() {
 		return handleErrorResponse(resp)
 	}
 
 	return nil
 }
+
+// deleteTemplate deletes the template version, the versions already missing
+// are ignored
+func (c TemplateContext) deleteTemplate(org, project, templateIdentifier, versionLabel string) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetPathParams(map[string]string{
+			"templateIdentifier": templateIdentifier,
+			"versionLabel":       versionLabel,
+		}).
+		SetQueryParams(scopeParams(api.Account, org, project)).
+		Delete(api.Url + DELETE_TEMPLATE_ENDPOINT)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() == http.StatusNotFound {
+		return nil
+	}
+	if resp.IsError() {
+		if err := handleErrorResponse(resp); err != nil && !errors.Is(err, ErrEntityNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
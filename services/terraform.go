@@ -0,0 +1,466 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Exporter is implemented by the operations able to describe the source
+// entities as resources of the Harness Terraform provider.
+type Exporter interface {
+	Export(tf *TerraformExport) error
+}
+
+// tfProject is the file and pseudo type of the project resource, which is
+// not an entity copied by the tool
+const tfProject EntityType = "project"
+
+// tfProjectAddress is the project every exported resource belongs to
+const tfProjectAddress = "harness_platform_project.this"
+
+// TerraformExport collects the resources of the target project. References
+// between them are resolved when the files are written, so the resources can
+// be added in any order.
+type TerraformExport struct {
+	org       string
+	project   string
+	resources []*tfResource
+	// addresses maps the target identifier of the exported entities to their
+	// resource address
+	addresses map[EntityRef]string
+	names     map[string]bool
+	variables []tfVariable
+	skipped   []tfSkipped
+}
+
+type tfResource struct {
+	file     EntityType
+	kind     string
+	name     string
+	importId string
+	attrs    []tfAttr
+}
+
+func (r *tfResource) address() string {
+	return r.kind + "." + r.name
+}
+
+type tfKind int
+
+const (
+	// tfText is a string, the secret expressions become references
+	tfText tfKind = iota
+	// tfExpr is written as is
+	tfExpr
+	// tfRef is the identifier of the resource exporting the entity, or the
+	// reference value when the entity is not exported
+	tfRef
+	// tfYaml is a heredoc, the entity references become interpolations
+	tfYaml
+	tfBlock
+)
+
+type tfAttr struct {
+	name  string
+	kind  tfKind
+	value string
+	ref   EntityRef
+	attrs []tfAttr
+}
+
+type tfSkipped struct {
+	file        EntityType
+	name        string
+	reason      string
+	unsupported bool
+}
+
+type tfVariable struct {
+	name        string
+	description string
+	sensitive   bool
+}
+
+func NewTerraformExport(targetOrg, targetProject string) *TerraformExport {
+	return &TerraformExport{
+		org:       targetOrg,
+		project:   targetProject,
+		addresses: map[EntityRef]string{},
+		names:     map[string]bool{},
+	}
+}
+
+// Resources returns the number of resources exported so far
+func (tf *TerraformExport) Resources() int {
+	return len(tf.resources)
+}
+
+// add registers the resource of an entity, identified by its target
+// identifier. Resources without import id get no import block.
+func (tf *TerraformExport) add(t EntityType, targetId, kind, name, importId string, attrs ...tfAttr) *tfResource {
+	r := &tfResource{
+		file:     t,
+		kind:     kind,
+		name:     tf.uniqueName(kind, name),
+		importId: importId,
+		attrs:    attrs,
+	}
+	tf.resources = append(tf.resources, r)
+	if t != tfProject && len(targetId) > 0 {
+		tf.addresses[EntityRef{Type: t, Scope: ScopeProject, Identifier: targetId}] = r.address()
+	}
+	return r
+}
+
+// skip leaves a comment in the file of the entity type, for the entities the
+// provider can't describe
+func (tf *TerraformExport) skip(t EntityType, name, reason string) {
+	tf.skipped = append(tf.skipped, tfSkipped{file: t, name: name, reason: reason})
+}
+
+// unsupported is like skip, for the project entities missing from the export
+func (tf *TerraformExport) unsupported(t EntityType, name, reason string) {
+	tf.skipped = append(tf.skipped, tfSkipped{file: t, name: name, reason: reason, unsupported: true})
+}
+
+// NotExported returns the project entities the provider can't describe
+func (tf *TerraformExport) NotExported() []string {
+	var names []string
+	for _, s := range tf.skipped {
+		if s.unsupported {
+			names = append(names, fmt.Sprintf("%s %s - %s", s.file, s.name, s.reason))
+		}
+	}
+	return names
+}
+
+// variable declares an input of the configuration and returns its reference
+func (tf *TerraformExport) variable(name, description string, sensitive bool) string {
+	name = tf.uniqueName("var", name)
+	tf.variables = append(tf.variables, tfVariable{name: name, description: description, sensitive: sensitive})
+	return "var." + name
+}
+
+// importId returns the id of a project entity, as expected by terraform import
+func (tf *TerraformExport) importId(ids ...string) string {
+	return strings.Join(append([]string{tf.org, tf.project}, ids...), "/")
+}
+
+// entity returns the identifier, name and scope of a project entity, followed
+// by the attributes with value
+func (tf *TerraformExport) entity(id, name string, attrs ...tfAttr) []tfAttr {
+	return append([]tfAttr{
+		tfString("identifier", id),
+		tfString("name", name),
+	}, tf.scoped(attrs...)...)
+}
+
+// scoped returns the scope of a project entity, followed by the attributes
+// with value
+func (tf *TerraformExport) scoped(attrs ...tfAttr) []tfAttr {
+	return append([]tfAttr{
+		tfExpression("org_id", tfProjectAddress+".org_id"),
+		tfExpression("project_id", tfProjectAddress+".id"),
+	}, tfOptional(attrs...)...)
+}
+
+var tfNameChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// uniqueName turns the identifier into a valid resource name, not used yet by
+// the resource kind
+func (tf *TerraformExport) uniqueName(kind, id string) string {
+	name := tfNameChars.ReplaceAllString(id, "_")
+	if len(name) == 0 || (name[0] >= '0' && name[0] <= '9') || name[0] == '-' {
+		name = "_" + name
+	}
+	unique := name
+	for i := 2; tf.names[kind+"."+unique]; i++ {
+		unique = fmt.Sprintf("%s_%d", name, i)
+	}
+	tf.names[kind+"."+unique] = true
+	return unique
+}
+
+func tfString(name, value string) tfAttr {
+	return tfAttr{name: name, kind: tfText, value: value}
+}
+
+func tfExpression(name, expr string) tfAttr {
+	return tfAttr{name: name, kind: tfExpr, value: expr}
+}
+
+func tfBool(name string, value bool) tfAttr {
+	return tfExpression(name, fmt.Sprint(value))
+}
+
+func tfStrings(name string, values []string) tfAttr {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = hclQuote(hclEscapeTemplates(v))
+	}
+	return tfExpression(name, "["+strings.Join(quoted, ", ")+"]")
+}
+
+// tfTags writes the tags as "key:value", the format used by the provider
+func tfTags(tags map[string]string) tfAttr {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var values []string
+	for _, k := range keys {
+		if len(tags[k]) == 0 {
+			values = append(values, k)
+		} else {
+			values = append(values, k+":"+tags[k])
+		}
+	}
+	return tfStrings("tags", values)
+}
+
+func tfReference(name string, t EntityType, value string) tfAttr {
+	ref, _ := newEntityRef(t, value)
+	return tfAttr{name: name, kind: tfRef, ref: ref, value: value}
+}
+
+// tfInfrastructure references an infrastructure, only unique inside its
+// environment
+func tfInfrastructure(name, envRef, infraId string) tfAttr {
+	env, _ := newEntityRef(EntityEnvironment, envRef)
+	return tfAttr{name: name, kind: tfRef, ref: EntityRef{
+		Type:       EntityInfrastructure,
+		Scope:      env.Scope,
+		Identifier: env.Identifier + "/" + infraId,
+	}, value: infraId}
+}
+
+func tfYamlDoc(name, doc string) tfAttr {
+	return tfAttr{name: name, kind: tfYaml, value: doc}
+}
+
+func tfNested(name string, attrs ...tfAttr) tfAttr {
+	return tfAttr{name: name, kind: tfBlock, attrs: attrs}
+}
+
+// tfOptional drops the attributes without value
+func tfOptional(attrs ...tfAttr) []tfAttr {
+	var out []tfAttr
+	for _, a := range attrs {
+		if (a.kind == tfText || a.kind == tfRef) && len(a.value) == 0 {
+			continue
+		}
+		if a.kind == tfExpr && (a.value == "[]" || len(a.value) == 0) {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// Write renders one file by entity type, the import blocks and the inputs
+func (tf *TerraformExport) Write(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	files := map[EntityType]*bytes.Buffer{}
+	var order []EntityType
+	buffer := func(t EntityType) *bytes.Buffer {
+		if b, found := files[t]; found {
+			b.WriteString("\n")
+			return b
+		}
+		files[t] = &bytes.Buffer{}
+		order = append(order, t)
+		return files[t]
+	}
+
+	for _, r := range tf.resources {
+		b := buffer(r.file)
+		fmt.Fprintf(b, "resource %q %q {\n", r.kind, r.name)
+		if err := tf.writeAttrs(b, r.attrs, "  "); err != nil {
+			return fmt.Errorf("%s: %w", r.address(), err)
+		}
+		b.WriteString("}\n")
+	}
+	for _, s := range tf.skipped {
+		fmt.Fprintf(buffer(s.file), "# Not exported %s: %s\n", s.name, s.reason)
+	}
+
+	main := &bytes.Buffer{}
+	main.WriteString("terraform {\n  required_providers {\n    harness = {\n      source = \"harness/harness\"\n    }\n  }\n}\n")
+	if err := os.WriteFile(filepath.Join(dir, "main.tf"), main.Bytes(), 0644); err != nil {
+		return err
+	}
+	for _, t := range order {
+		if err := os.WriteFile(filepath.Join(dir, string(t)+".tf"), files[t].Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+
+	imports := &bytes.Buffer{}
+	for _, r := range tf.resources {
+		if len(r.importId) == 0 {
+			continue
+		}
+		if imports.Len() > 0 {
+			imports.WriteString("\n")
+		}
+		fmt.Fprintf(imports, "import {\n  to = %s\n  id = %s\n}\n", r.address(), hclQuote(hclEscapeTemplates(r.importId)))
+	}
+	if err := os.WriteFile(filepath.Join(dir, "imports.tf"), imports.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	if len(tf.variables) == 0 {
+		return nil
+	}
+	inputs := &bytes.Buffer{}
+	for i, v := range tf.variables {
+		if i > 0 {
+			inputs.WriteString("\n")
+		}
+		fmt.Fprintf(inputs, "variable %q {\n", v.name)
+		tf.writeAttrs(inputs, tfOptional(
+			tfString("description", v.description),
+			tfExpression("type", "string"),
+			tfBool("sensitive", v.sensitive),
+		), "  ")
+		inputs.WriteString("}\n")
+	}
+	return os.WriteFile(filepath.Join(dir, "inputs.tf"), inputs.Bytes(), 0644)
+}
+
+// writeAttrs aligns the equal signs of consecutive single line attributes, as
+// terraform fmt does
+func (tf *TerraformExport) writeAttrs(b *bytes.Buffer, attrs []tfAttr, indent string) error {
+	values := make([]string, len(attrs))
+	for i, a := range attrs {
+		var err error
+		if values[i], err = tf.render(a); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < len(attrs); {
+		if attrs[i].kind == tfBlock {
+			fmt.Fprintf(b, "%s%s {\n", indent, attrs[i].name)
+			if err := tf.writeAttrs(b, attrs[i].attrs, indent+"  "); err != nil {
+				return err
+			}
+			fmt.Fprintf(b, "%s}\n", indent)
+			i++
+			continue
+		}
+		end, width := i, 0
+		for ; end < len(attrs) && attrs[end].kind != tfBlock; end++ {
+			if len(attrs[end].name) > width {
+				width = len(attrs[end].name)
+			}
+			if attrs[end].kind == tfYaml {
+				end++
+				break
+			}
+		}
+		for ; i < end; i++ {
+			fmt.Fprintf(b, "%s%-*s = %s\n", indent, width, attrs[i].name, values[i])
+		}
+	}
+	return nil
+}
+
+func (tf *TerraformExport) render(a tfAttr) (string, error) {
+	switch a.kind {
+	case tfText:
+		return hclQuote(tf.secretExpressions(hclEscapeTemplates(a.value))), nil
+	case tfRef:
+		if addr, found := tf.addresses[a.ref]; found {
+			return addr + ".id", nil
+		}
+		return hclQuote(hclEscapeTemplates(a.value)), nil
+	case tfYaml:
+		doc, err := tf.hclYaml(a.value)
+		if err != nil {
+			return "", err
+		}
+		return "<<EOT\n" + doc + "EOT", nil
+	}
+	return a.value, nil
+}
+
+// hclYaml prepares the YAML document for a heredoc. The template sequences are
+// escaped, the exported entities and the project become interpolations.
+func (tf *TerraformExport) hclYaml(doc string) (string, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(doc), &root); err != nil {
+		return "", err
+	}
+
+	refs := map[*yaml.Node]string{}
+	visitReferences(&root, func(ref EntityRef, value *yaml.Node) {
+		if addr, found := tf.addresses[ref]; found && value.Kind == yaml.ScalarNode {
+			refs[value] = "${" + addr + ".id}"
+		}
+	})
+	walkMappings(&root, func(key, value *yaml.Node) {
+		if key.Value == "orgIdentifier" && value.Value == tf.org {
+			refs[value] = "${" + tfProjectAddress + ".org_id}"
+		}
+		if key.Value == "projectIdentifier" && value.Value == tf.project {
+			refs[value] = "${" + tfProjectAddress + ".id}"
+		}
+	})
+	walkScalars(&root, func(n *yaml.Node) {
+		if expr, found := refs[n]; found {
+			n.Value = expr
+			return
+		}
+		n.Value = tf.secretExpressions(hclEscapeTemplates(n.Value))
+	})
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&root); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// secretExpressions points the <+secrets.getValue()> expressions to the
+// exported secrets
+func (tf *TerraformExport) secretExpressions(value string) string {
+	return secretExpression.ReplaceAllStringFunc(value, func(expr string) string {
+		match := secretExpression.FindStringSubmatch(expr)
+		ref, ok := newEntityRef(EntitySecret, match[1])
+		addr, found := tf.addresses[ref]
+		if !ok || !found {
+			return expr
+		}
+		return strings.Replace(expr, match[1], "${"+addr+".id}", 1)
+	})
+}
+
+var hclTemplateEscaper = strings.NewReplacer("${", "$${", "%{", "%%{")
+
+// hclEscapeTemplates keeps ${ and %{ as text, HCL reads them as templates
+func hclEscapeTemplates(s string) string {
+	return hclTemplateEscaper.Replace(s)
+}
+
+var hclQuoteEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+func hclQuote(s string) string {
+	return `"` + hclQuoteEscaper.Replace(s) + `"`
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestTerraformExportWrite(t *testing.T) {
+	tf := NewTerraformExport("other", "DouradoF")
+	tf.add(EntityService, "nginx", "harness_platform_service", "nginx", tf.importId("nginx"), tf.entity("nginx", "Nginx")...)
+	tf.add(EntitySecret, "token", "harness_platform_secret_text", "token", tf.importId("token"), tf.entity("token", "Token",
+		tfExpression("value", tf.variable("secret_token", "Value of the secret Token", true)),
+	)...)
+	tf.add(EntityPipeline, "deploy", "harness_platform_pipeline", "deploy", tf.importId("deploy"), tf.entity("deploy", "Deploy",
+		tfString("description", `Uses "${HOME}"`),
+		tfYamlDoc("yaml", `pipeline:
+  identifier: deploy
+  orgIdentifier: other
+  projectIdentifier: DouradoF
+  stages:
+    - stage:
+        spec:
+          service:
+            serviceRef: nginx
+          script: echo ${TOKEN} <+secrets.getValue("token")> <+secrets.getValue("org.shared")>
+          connectorRef: account.docker
+`),
+	)...)
+
+	dir := t.TempDir()
+	assert.NoError(t, tf.Write(dir))
+
+	pipeline, err := os.ReadFile(filepath.Join(dir, "pipeline.tf"))
+	assert.NoError(t, err)
+	assert.Equal(t, `resource "harness_platform_pipeline" "deploy" {
+  identifier  = "deploy"
+  name        = "Deploy"
+  org_id      = harness_platform_project.this.org_id
+  project_id  = harness_platform_project.this.id
+  description = "Uses \"$${HOME}\""
+  yaml        = <<EOT
+pipeline:
+  identifier: deploy
+  orgIdentifier: ${harness_platform_project.this.org_id}
+  projectIdentifier: ${harness_platform_project.this.id}
+  stages:
+    - stage:
+        spec:
+          service:
+            serviceRef: ${harness_platform_service.nginx.id}
+          script: echo $${TOKEN} <+secrets.getValue("${harness_platform_secret_text.token.id}")> <+secrets.getValue("org.shared")>
+          connectorRef: account.docker
+EOT
+}
+`, string(pipeline))
+
+	imports, err := os.ReadFile(filepath.Join(dir, "imports.tf"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(imports), "import {\n  to = harness_platform_pipeline.deploy\n  id = \"other/DouradoF/deploy\"\n}\n")
+
+	inputs, err := os.ReadFile(filepath.Join(dir, "inputs.tf"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(inputs), "variable \"secret_token\" {\n  description = \"Value of the secret Token\"\n  type        = string\n  sensitive   = true\n}\n")
+}
+
+func TestTerraformExportUniqueNames(t *testing.T) {
+	tf := NewTerraformExport("other", "DouradoF")
+	assert.Equal(t, "dev_k8s", tf.uniqueName("harness_platform_infrastructure", "dev_k8s"))
+	assert.Equal(t, "dev_k8s_2", tf.uniqueName("harness_platform_infrastructure", "dev_k8s"))
+	assert.Equal(t, "_1_0", tf.uniqueName("harness_platform_template", "1.0"))
+}
+
+func TestTerraformExportAwsConnector(t *testing.T) {
+	var root yaml.Node
+	assert.NoError(t, yaml.Unmarshal([]byte(`
+credential:
+  type: ManualConfig
+  region: us-east-1
+  spec:
+    accessKey: AKIA
+    secretKeyRef: aws_secret
+delegateSelectors: [primary]
+executeOnDelegate: true
+`), &root))
+
+	tf := NewTerraformExport("other", "DouradoF")
+	tf.add(EntitySecret, "aws_secret", "harness_platform_secret_text", "aws_secret", tf.importId("aws_secret"), tf.entity("aws_secret", "AWS Secret")...)
+	tf.add(EntityConnector, "aws", "harness_platform_connector_aws", "aws", tf.importId("aws"), tf.entity("aws", "AWS", awsConnectorAttrs(root.Content[0])...)...)
+	tf.unsupported(EntityConnector, "Vault", "connector type Vault or its authentication is not supported")
+
+	dir := t.TempDir()
+	assert.NoError(t, tf.Write(dir))
+
+	connectors, err := os.ReadFile(filepath.Join(dir, "connector.tf"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(connectors), `  execute_on_delegate = true
+  manual {
+    access_key         = "AKIA"
+    secret_key_ref     = harness_platform_secret_text.aws_secret.id
+    delegate_selectors = ["primary"]
+    region             = "us-east-1"
+  }
+}
+`)
+	assert.Contains(t, string(connectors), "# Not exported Vault: connector type Vault or its authentication is not supported\n")
+	assert.Equal(t, []string{"connector Vault - connector type Vault or its authentication is not supported"}, tf.NotExported())
+}
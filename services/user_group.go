@@ -0,0 +1,241 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Fernando-Dourado/harness-move-project/model"
+	"github.com/schollz/progressbar/v3"
+)
+
+type UserGroupContext struct {
+	source        *SourceRequest
+	target        *TargetRequest
+	sourceOrg     string
+	sourceProject string
+	targetOrg     string
+	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
+	principals    *principalResolver
+}
+
+func NewUserGroupOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) UserGroupContext {
+	return UserGroupContext{
+		source:        sourceApi,
+		target:        targetApi,
+		sourceOrg:     st.SourceOrg,
+		sourceProject: st.SourceProject,
+		targetOrg:     st.TargetOrg,
+		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
+		principals:    st.principalResolver(sourceApi, targetApi),
+	}
+}
+
+func (c UserGroupContext) Move() error {
+
+	groups, err := listUserGroups(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+
+	bar := progressbar.Default(int64(len(groups)), "User Groups")
+	var failed []string
+
+	for _, ug := range groups {
+		if !c.filter.Match(EntityUserGroup, ug.Identifier) {
+			bar.Add(1)
+			continue
+		}
+		sourceId := ug.Identifier
+		unresolved, err := c.toTarget(ug)
+		if err != nil {
+			failed = append(failed, fmt.Sprintln(ug.Name, "-", err.Error()))
+			c.report.record(EntityUserGroup, sourceId, ug.Name, err)
+			bar.Add(1)
+			continue
+		}
+
+		result, err := c.state.upsert(c.onConflict, EntityUserGroup, sourceId, ug,
+			func() error { return c.createUserGroup(ug) },
+			func() error { return c.updateUserGroup(ug) },
+			c.target.compareWith(EntityUserGroup, ug.Identifier, c.targetOrg, c.targetProject, nil, ug),
+		)
+		if err != nil {
+			failed = append(failed, fmt.Sprintln(ug.Name, "-", err.Error()))
+		} else if len(unresolved) > 0 {
+			message := "users not found in target: " + strings.Join(unresolved, ", ")
+			if len(result.message) > 0 {
+				message = result.message + "; " + message
+			}
+			result.message = message
+		}
+		c.report.recordOutcome(EntityUserGroup, sourceId, ug.Name, result, err)
+		bar.Add(1)
+	}
+	bar.Finish()
+
+	reportFailed(failed, "user groups:")
+	return nil
+}
+
+func (c UserGroupContext) Scan(g *DependencyGraph) error {
+
+	groups, err := listUserGroups(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, ug := range groups {
+		if !c.filter.Match(EntityUserGroup, ug.Identifier) {
+			continue
+		}
+		g.Add(EntityUserGroup, ug.Identifier, ug.Name, nil)
+	}
+	return nil
+}
+
+func (c UserGroupContext) Verify(v *Verification) error {
+
+	groups, err := listUserGroups(c.source, c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	targetGroups, err := listUserGroups(c.target.asSource(), c.targetOrg, c.targetProject)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, ug := range groups {
+		if !c.filter.Match(EntityUserGroup, ug.Identifier) {
+			continue
+		}
+		count++
+		sourceId := ug.Identifier
+		if _, err := c.toTarget(ug); err != nil {
+			v.failed(EntityUserGroup, sourceId, ug.Name, err)
+			continue
+		}
+		v.check(EntityUserGroup, sourceId, ug.Name, c.target.verifyWith(EntityUserGroup, ug.Identifier, c.targetOrg, c.targetProject, nil, ug, false))
+	}
+	v.count(EntityUserGroup, count, len(targetGroups))
+	return nil
+}
+
+// toTarget moves the user group to the target project and maps its users.
+// The users not found in the target are dropped and returned.
+func (c UserGroupContext) toTarget(ug *model.UserGroup) ([]string, error) {
+	ug.Identifier = c.mapping.Identifier(EntityUserGroup, ug.Identifier)
+	ug.AccountIdentifier = c.target.Account
+	ug.OrgIdentifier = c.targetOrg
+	ug.ProjectIdentifier = c.targetProject
+	ug.ExternallyManaged = false
+	ug.SsoLinked = false
+
+	users := []string{}
+	var unresolved []string
+	for _, u := range ug.Users {
+		id, err := c.principals.user(u)
+		if errors.Is(err, ErrUnresolvedPrincipal) {
+			unresolved = append(unresolved, u)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, id)
+	}
+	ug.Users = users
+	return unresolved, nil
+}
+
+// listUserGroups returns the user groups created in the project
+func listUserGroups(s *SourceRequest, org, project string) ([]*model.UserGroup, error) {
+
+	resp, err := s.Client.R().
+		SetHeader("x-api-key", s.Token).
+		SetHeader("Content-Type", "application/json").
+		SetQueryParams(map[string]string{
+			"accountIdentifier": s.Account,
+			"orgIdentifier":     org,
+			"projectIdentifier": project,
+			"pageSize":          "1000",
+		}).
+		Get(s.Url + "/ng/api/user-groups")
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, handleErrorResponse(resp)
+	}
+
+	result := model.ListUserGroupResponse{}
+	err = json.Unmarshal(resp.Body(), &result)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []*model.UserGroup{}
+	for _, ug := range result.Data.Content {
+		if !ug.HarnessManaged && !isBuiltIn(ug.Identifier) {
+			groups = append(groups, ug)
+		}
+	}
+	return groups, nil
+}
+
+func (c UserGroupContext) createUserGroup(ug *model.UserGroup) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(ug).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+			"orgIdentifier":     c.targetOrg,
+			"projectIdentifier": c.targetProject,
+		}).
+		Post(api.Url + "/ng/api/user-groups")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
+
+func (c UserGroupContext) updateUserGroup(ug *model.UserGroup) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(ug).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+			"orgIdentifier":     c.targetOrg,
+			"projectIdentifier": c.targetProject,
+		}).
+		Put(api.Url + "/ng/api/user-groups")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return handleErrorResponse(resp)
+	}
+
+	return nil
+}
@@ -15,6 +15,12 @@ type VariableContext struct {
 	sourceProject string
 	targetOrg     string
 	targetProject string
+	mapping       IdentifierMapping
+	filter        EntityFilter
+	report        *Report
+	onConflict    ConflictPolicy
+	state         *SyncState
+	since         int64
 }
 
 func NewVariableOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st *SourceTarget) VariableContext {
@@ -25,29 +31,57 @@ func NewVariableOperation(sourceApi *SourceRequest, targetApi *TargetRequest, st
 		sourceProject: st.SourceProject,
 		targetOrg:     st.TargetOrg,
 		targetProject: st.TargetProject,
+		mapping:       st.Mapping,
+		filter:        st.Filter,
+		report:        st.Report,
+		onConflict:    st.OnConflict,
+		state:         st.State,
+		since:         st.ModifiedSince,
 	}
 }
 
 func (c VariableContext) Move() error {
 
-	variables, err := c.listVariables(c.sourceOrg, c.sourceProject)
+	contents, err := c.listVariableContents(c.sourceOrg, c.sourceProject)
 	if err != nil {
 		return err
 	}
 
-	bar := progressbar.Default(int64(len(variables)), "Variables")
+	bar := progressbar.Default(int64(len(contents)), "Variables")
 	var failed = []string{}
 
-	for _, v := range variables {
+	for _, content := range contents {
+		v := content.Variable
+		if !c.filter.Match(EntityVariable, v.Identifier) {
+			bar.Add(1)
+			continue
+		}
+		if unmodified(c.since, content.LastModifiedAt) {
+			c.report.recordUnmodified(EntityVariable, v.Identifier, v.Name)
+			bar.Add(1)
+			continue
+		}
+		sourceId := v.Identifier
+		v.Identifier = c.mapping.Identifier(EntityVariable, v.Identifier)
 		v.OrgIdentifier = c.targetOrg
 		v.ProjectIdentifier = c.targetProject
+		if v.Spec.Value != nil {
+			value := c.mapping.rewriteExpressions(*v.Spec.Value)
+			v.Spec.Value = &value
+		}
 
-		err = c.createVariable(&model.CreateVariableRequest{
+		request := &model.CreateVariableRequest{
 			Variable: v,
-		})
+		}
+		result, err := c.state.upsert(c.onConflict, EntityVariable, sourceId, request,
+			func() error { return c.createVariable(request) },
+			func() error { return c.updateVariable(request) },
+			c.target.compareWith(EntityVariable, v.Identifier, c.targetOrg, c.targetProject, nil, v),
+		)
 		if err != nil {
 			failed = append(failed, fmt.Sprintln(v.Name, "-", err.Error()))
 		}
+		c.report.recordOutcome(EntityVariable, sourceId, v.Name, result, err)
 		bar.Add(1)
 	}
 	bar.Finish()
@@ -56,8 +90,123 @@ func (c VariableContext) Move() error {
 	return nil
 }
 
+func (c VariableContext) Scan(g *DependencyGraph) error {
+
+	contents, err := c.listVariableContents(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, content := range contents {
+		v := content.Variable
+		if !c.filter.Match(EntityVariable, v.Identifier) || unmodified(c.since, content.LastModifiedAt) {
+			continue
+		}
+		var refs []EntityRef
+		if v.Spec.Value != nil {
+			refs = extractExpressionReferences(*v.Spec.Value)
+		}
+		g.Add(EntityVariable, v.Identifier, v.Name, refs)
+	}
+	return nil
+}
+
+func (c VariableContext) Verify(v *Verification) error {
+
+	variables, err := c.listVariables(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	target := c
+	target.source = c.target.asSource()
+	targetVariables, err := target.listVariables(c.targetOrg, c.targetProject)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, variable := range variables {
+		if !c.filter.Match(EntityVariable, variable.Identifier) {
+			continue
+		}
+		count++
+		sourceId := variable.Identifier
+		variable.Identifier = c.mapping.Identifier(EntityVariable, variable.Identifier)
+		variable.OrgIdentifier = c.targetOrg
+		variable.ProjectIdentifier = c.targetProject
+		if variable.Spec.Value != nil {
+			value := c.mapping.rewriteExpressions(*variable.Spec.Value)
+			variable.Spec.Value = &value
+		}
+		v.check(EntityVariable, sourceId, variable.Name, c.target.verifyWith(EntityVariable, variable.Identifier, c.targetOrg, c.targetProject, nil, variable, false))
+	}
+	v.count(EntityVariable, count, len(targetVariables))
+	return nil
+}
+
+func (c VariableContext) Export(tf *TerraformExport) error {
+
+	variables, err := c.listVariables(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	for _, v := range variables {
+		if !c.filter.Match(EntityVariable, v.Identifier) {
+			continue
+		}
+		id := c.mapping.Identifier(EntityVariable, v.Identifier)
+		var value, description string
+		if v.Spec.Value != nil {
+			value = c.mapping.rewriteExpressions(*v.Spec.Value)
+		}
+		if v.Description != nil {
+			description = *v.Description
+		}
+		tf.add(EntityVariable, id, "harness_platform_variables", id, tf.importId(id), tf.entity(id, v.Name,
+			tfString("description", description),
+			tfString("type", v.Type),
+			tfNested("spec",
+				tfString("value_type", v.Spec.ValueType),
+				tfString("fixed_value", value),
+			),
+		)...)
+	}
+	return nil
+}
+
+func (c VariableContext) Prune() error {
+
+	variables, err := c.listVariables(c.sourceOrg, c.sourceProject)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for _, v := range variables {
+		ids = append(ids, v.Identifier)
+	}
+	c.state.prune(EntityVariable, ids, c.report, func(sourceId string) error {
+		return c.target.deleteSynced(c.mapping, EntityVariable, sourceId, c.targetOrg, c.targetProject)
+	})
+	return nil
+}
+
 func (c VariableContext) listVariables(org, project string) ([]*model.Variable, error) {
 
+	contents, err := c.listVariableContents(org, project)
+	if err != nil {
+		return nil, err
+	}
+
+	variables := []*model.Variable{}
+	for _, c := range contents {
+		variables = append(variables, c.Variable)
+	}
+
+	return variables, nil
+}
+
+// listVariableContents lists the variables with their modification time
+func (c VariableContext) listVariableContents(org, project string) ([]*model.GetVariablesContent, error) {
+
 	api := c.source
 	resp, err := api.Client.R().
 		SetHeader("x-api-key", api.Token).
@@ -82,12 +231,7 @@ func (c VariableContext) listVariables(org, project string) ([]*model.Variable,
 		return nil, err
 	}
 
-	variables := []*model.Variable{}
-	for _, c := range result.Data.Content {
-		variables = append(variables, c.Variable)
-	}
-
-	return variables, nil
+	return result.Data.Content, nil
 }
 
 func (c VariableContext) createVariable(variable *model.CreateVariableRequest) error {
@@ -104,6 +248,27 @@ func (c VariableContext) createVariable(variable *model.CreateVariableRequest) e
 	if err != nil {
 		return err
 	}
+	if resp.IsError() {
+		return handleCreateResponse(resp)
+	}
+
+	return nil
+}
+
+func (c VariableContext) updateVariable(variable *model.CreateVariableRequest) error {
+
+	api := c.target
+	resp, err := api.Client.R().
+		SetHeader("x-api-key", api.Token).
+		SetHeader("Content-Type", "application/json").
+		SetBody(variable).
+		SetQueryParams(map[string]string{
+			"accountIdentifier": api.Account,
+		}).
+		Put(api.Url + "/ng/api/variables")
+	if err != nil {
+		return err
+	}
 	if resp.IsError() {
 		return handleErrorResponse(resp)
 	}
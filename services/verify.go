@@ -0,0 +1,222 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+// Verifier is implemented by the operations able to check, after the move,
+// that the source entities exist in the target with the same content.
+type Verifier interface {
+	Verify(v *Verification) error
+}
+
+type VerifyStatus string
+
+const (
+	VerifyOk        VerifyStatus = "ok"
+	VerifyMissing   VerifyStatus = "missing"
+	VerifyDiffers   VerifyStatus = "differs"
+	VerifyInvalid   VerifyStatus = "invalid"
+	VerifyUnchecked VerifyStatus = "unchecked"
+	VerifyFailed    VerifyStatus = "failed"
+)
+
+type VerifyEntry struct {
+	Type       EntityType   `json:"type"`
+	Identifier string       `json:"identifier"`
+	Name       string       `json:"name,omitempty"`
+	Status     VerifyStatus `json:"status"`
+	Message    string       `json:"message,omitempty"`
+	Diff       string       `json:"diff,omitempty"`
+}
+
+// VerifyCount is the number of entities of one type in the source and the
+// target
+type VerifyCount struct {
+	Source int `json:"source"`
+	Target int `json:"target"`
+}
+
+// Verification collects the checks of every source entity. It is safe for
+// concurrent use.
+type Verification struct {
+	mu      sync.Mutex
+	Entries []VerifyEntry               `json:"entries"`
+	Counts  map[EntityType]*VerifyCount `json:"counts"`
+}
+
+func NewVerification() *Verification {
+	return &Verification{
+		Entries: []VerifyEntry{},
+		Counts:  map[EntityType]*VerifyCount{},
+	}
+}
+
+func (v *Verification) add(entry VerifyEntry) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.Entries = append(v.Entries, entry)
+}
+
+// count sets the number of entities of the type in the source and the target
+func (v *Verification) count(t EntityType, source, target int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.Counts[t] = &VerifyCount{Source: source, Target: target}
+}
+
+// check adds the result of verifying one entity. The verify function returns
+// the diff between the target and the source, ErrEntityNotFound when the
+// entity is missing and errInvalidEntity when the target marks it invalid.
+func (v *Verification) check(t EntityType, identifier, name string, verify func() (string, error)) {
+	entry := VerifyEntry{
+		Type:       t,
+		Identifier: identifier,
+		Name:       name,
+		Status:     VerifyOk,
+	}
+	diff, err := verify()
+	switch {
+	case errors.Is(err, ErrEntityNotFound):
+		entry.Status = VerifyMissing
+	case errors.Is(err, errInvalidEntity):
+		entry.Status = VerifyInvalid
+		entry.Diff = diff
+	case err != nil:
+		entry.Status = VerifyFailed
+		entry.Message = removeNewLine(err.Error())
+	case len(diff) > 0:
+		entry.Status = VerifyDiffers
+		entry.Diff = diff
+	}
+	v.add(entry)
+}
+
+// failed adds an entity whose source couldn't be prepared for the check
+func (v *Verification) failed(t EntityType, identifier, name string, err error) {
+	v.add(VerifyEntry{
+		Type:       t,
+		Identifier: identifier,
+		Name:       name,
+		Status:     VerifyFailed,
+		Message:    removeNewLine(err.Error()),
+	})
+}
+
+// unchecked adds an entity that can't be verified
+func (v *Verification) unchecked(t EntityType, identifier, name, reason string) {
+	v.add(VerifyEntry{
+		Type:       t,
+		Identifier: identifier,
+		Name:       name,
+		Status:     VerifyUnchecked,
+		Message:    reason,
+	})
+}
+
+// Failed counts the entities not verified, plus the types with fewer entities
+// in the target than in the source
+func (v *Verification) Failed() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	failed := 0
+	for _, e := range v.Entries {
+		if e.Status != VerifyOk && e.Status != VerifyUnchecked {
+			failed++
+		}
+	}
+	for _, c := range v.Counts {
+		if c.Target < c.Source {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Print writes the result by entity type to the console
+func (v *Verification) Print() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	summary := map[EntityType]map[VerifyStatus]int{}
+	for _, e := range v.Entries {
+		if summary[e.Type] == nil {
+			summary[e.Type] = map[VerifyStatus]int{}
+		}
+		summary[e.Type][e.Status]++
+	}
+	var types []string
+	for t := range v.Counts {
+		types = append(types, string(t))
+	}
+	sort.Strings(types)
+
+	fmt.Println("Verification:")
+	for _, t := range types {
+		c := v.Counts[EntityType(t)]
+		var statuses []string
+		for s := range summary[EntityType(t)] {
+			statuses = append(statuses, string(s))
+		}
+		sort.Strings(statuses)
+		counts := []string{fmt.Sprintf("source %d, target %d", c.Source, c.Target)}
+		for _, s := range statuses {
+			counts = append(counts, fmt.Sprintf("%s %d", s, summary[EntityType(t)][VerifyStatus(s)]))
+		}
+		line := fmt.Sprintf("%-16s %s", t, strings.Join(counts, ", "))
+		if len(statuses) > 1 || (len(statuses) == 1 && statuses[0] != string(VerifyOk)) || c.Target < c.Source {
+			fmt.Println(color.RedString(line))
+		} else {
+			fmt.Println(line)
+		}
+	}
+	for _, e := range v.Entries {
+		if e.Status != VerifyOk {
+			fmt.Println(color.RedString("%s %s %s %s", e.Type, e.Identifier, e.Status, e.Message))
+		}
+	}
+}
+
+var errInvalidEntity = errors.New("entity is invalid in the target")
+
+// verifyWith returns the verify function of a project entity. With
+// checkValid the target response must have a valid entityValidityDetails.
+func (t *TargetRequest) verifyWith(entityType EntityType, identifier, org, project string, params map[string]string, source interface{}, checkValid bool) func() (string, error) {
+	return t.verifyRef(EntityRef{Type: entityType, Scope: ScopeProject, Identifier: identifier}, org, project, params, source, checkValid)
+}
+
+// verifyRef is verifyWith for an entity of any scope
+func (t *TargetRequest) verifyRef(ref EntityRef, org, project string, params map[string]string, source interface{}, checkValid bool) func() (string, error) {
+	return func() (string, error) {
+		body, err := t.getEntity(ref, org, project, params)
+		if err != nil {
+			return "", err
+		}
+		diff, err := diffResponse(ref.Type, body, source)
+		if err != nil || !checkValid {
+			return diff, err
+		}
+
+		result := struct {
+			Data struct {
+				EntityValidityDetails *struct {
+					Valid bool `json:"valid"`
+				} `json:"entityValidityDetails"`
+			} `json:"data"`
+		}{}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return "", err
+		}
+		if details := result.Data.EntityValidityDetails; details != nil && !details.Valid {
+			return diff, errInvalidEntity
+		}
+		return diff, nil
+	}
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerificationCheck(t *testing.T) {
+	v := NewVerification()
+	v.check(EntityService, "ok", "ok", func() (string, error) { return "", nil })
+	v.check(EntityService, "missing", "missing", func() (string, error) { return "", ErrEntityNotFound })
+	v.check(EntityService, "differs", "differs", func() (string, error) { return "-a\n+b\n", nil })
+	v.check(EntityPipeline, "invalid", "invalid", func() (string, error) { return "", errInvalidEntity })
+	v.check(EntityPipeline, "failed", "failed", func() (string, error) { return "", errors.New("boom") })
+	v.unchecked(EntityOverrideV2, "unchecked", "unchecked", "no identifier")
+	v.count(EntityService, 3, 3)
+	v.count(EntityPipeline, 2, 1)
+
+	var statuses []VerifyStatus
+	for _, e := range v.Entries {
+		statuses = append(statuses, e.Status)
+	}
+	assert.Equal(t, []VerifyStatus{VerifyOk, VerifyMissing, VerifyDiffers, VerifyInvalid, VerifyFailed, VerifyUnchecked}, statuses)
+	assert.Equal(t, "boom", v.Entries[4].Message)
+	// FOUR ENTITIES PLUS THE PIPELINE COUNT
+	assert.Equal(t, 5, v.Failed())
+}
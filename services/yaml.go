@@ -0,0 +1,97 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlTransform changes the parsed document in place
+type yamlTransform func(doc *yaml.Node) error
+
+// transformYaml parses the document, applies the transformations in order and
+// encodes it back
+func transformYaml(doc string, transforms ...yamlTransform) (string, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(doc), &root); err != nil {
+		return "", err
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return "", fmt.Errorf("empty yaml document")
+	}
+
+	for _, t := range transforms {
+		if err := t(&root); err != nil {
+			return "", err
+		}
+	}
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&root); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// entityNode returns the mapping holding the entity fields. Harness wraps the
+// entity in a single root key, like "pipeline:" or "service:".
+func entityNode(doc *yaml.Node) (*yaml.Node, error) {
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("yaml root is not a mapping")
+	}
+	if len(root.Content) == 2 && root.Content[1].Kind == yaml.MappingNode {
+		return root.Content[1], nil
+	}
+	return root, nil
+}
+
+// setScope sets the org and project identifiers of the root entity. An empty
+// value removes the field, as expected for org and account scoped entities.
+func setScope(org, project string) yamlTransform {
+	return func(doc *yaml.Node) error {
+		entity, err := entityNode(doc)
+		if err != nil {
+			return err
+		}
+		setMappingValue(entity, "orgIdentifier", org)
+		setMappingValue(entity, "projectIdentifier", project)
+		return nil
+	}
+}
+
+func setMappingValue(m *yaml.Node, key, value string) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value != key {
+			continue
+		}
+		if len(value) == 0 {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return
+		}
+		setScalar(m.Content[i+1], value)
+		return
+	}
+	if len(value) > 0 {
+		v := &yaml.Node{}
+		setScalar(v, value)
+		m.Content = append(m.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+			v,
+		)
+	}
+}
+
+func setScalar(n *yaml.Node, value string) {
+	n.Kind = yaml.ScalarNode
+	n.Tag = "!!str"
+	n.Style = 0
+	n.Value = value
+	n.Content = nil
+}